@@ -0,0 +1,108 @@
+package account
+
+import (
+	"cached_proxy/repo"
+	"cached_proxy/utils"
+	path2 "path"
+	"sync"
+)
+
+// FeedService 定义了订阅日历密钥的服务，订阅地址中携带密钥，日历应用无需发送 Authorization 头即可订阅
+type FeedService interface {
+	// GetFeedSecret 获取账户的订阅密钥，如果不存在则创建一个新的密钥
+	GetFeedSecret(accountID string) (string, error)
+	// RegenerateFeedSecret 重新生成账户的订阅密钥，旧的订阅地址将失效
+	RegenerateFeedSecret(accountID string) (string, error)
+	// RevokeFeedSecret 停用账户的订阅密钥
+	RevokeFeedSecret(accountID string) error
+	// GetAccountByFeedSecret 根据订阅密钥获取账户信息
+	GetAccountByFeedSecret(secret string) (Account, error)
+}
+
+// FeedRepository 是订阅密钥的数据仓库
+type FeedRepository struct {
+	idRepo     repo.KVRepo[string, string] // 用于根据账户ID查找密钥
+	secretRepo repo.KVRepo[string, string] // 用于根据密钥查找账户ID
+}
+
+func NewFeedMemRepository() *FeedRepository {
+	return &FeedRepository{
+		idRepo:     repo.NewMemRepo[string, string](),
+		secretRepo: repo.NewMemRepo[string, string](),
+	}
+}
+
+func NewFeedFileRepository(path string) *FeedRepository {
+	return &FeedRepository{
+		idRepo:     repo.NewFileRepos[string, string](path2.Join(path, "feed_id.gob")),
+		secretRepo: repo.NewFileRepos[string, string](path2.Join(path, "feed_secret.gob")),
+	}
+}
+
+type FeedServiceImpl struct {
+	feedRepo    *FeedRepository
+	accountRepo repository
+	mu          sync.Mutex
+}
+
+func NewFeedServiceImpl(feedRepo *FeedRepository, accountRepo repository) *FeedServiceImpl {
+	return &FeedServiceImpl{feedRepo: feedRepo, accountRepo: accountRepo}
+}
+
+func (s *FeedServiceImpl) GetFeedSecret(accountID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if secret, found := s.feedRepo.idRepo.Get(accountID); found {
+		return secret, nil
+	}
+	return s.newSecret(accountID)
+}
+
+func (s *FeedServiceImpl) RegenerateFeedSecret(accountID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoke(accountID)
+	return s.newSecret(accountID)
+}
+
+func (s *FeedServiceImpl) RevokeFeedSecret(accountID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoke(accountID)
+	return nil
+}
+
+func (s *FeedServiceImpl) GetAccountByFeedSecret(secret string) (Account, error) {
+	accountID, found := s.feedRepo.secretRepo.Get(secret)
+	if !found {
+		return nil, ErrAccountNotFound
+	}
+	return s.accountRepo.GetAccountByAccountID(accountID)
+}
+
+// newSecret 为账户生成新的密钥，调用前需要持有锁
+func (s *FeedServiceImpl) newSecret(accountID string) (string, error) {
+	if _, err := s.accountRepo.GetAccountByAccountID(accountID); err != nil {
+		return "", err
+	}
+	for {
+		secret, err := utils.GenerateUUID()
+		if err != nil {
+			return "", err
+		}
+		if _, found := s.feedRepo.secretRepo.Get(secret); found {
+			continue
+		}
+		s.feedRepo.secretRepo.Set(secret, accountID)
+		s.feedRepo.idRepo.Set(accountID, secret)
+		return secret, nil
+	}
+}
+
+// revoke 删除账户当前的密钥，调用前需要持有锁
+func (s *FeedServiceImpl) revoke(accountID string) {
+	if secret, found := s.feedRepo.idRepo.Get(accountID); found {
+		s.feedRepo.secretRepo.Delete(secret)
+		s.feedRepo.idRepo.Delete(accountID)
+	}
+}
@@ -0,0 +1,40 @@
+package account
+
+import "testing"
+
+func TestFeedServiceImpl(t *testing.T) {
+	accountRepo := NewMockRepository()
+	_ = accountRepo.SaveOrUpdateAccount(&SimpleAccountImpl{Username: "user1", StaticToken: "token1", status: Normal})
+	service := NewFeedServiceImpl(NewFeedMemRepository(), accountRepo)
+
+	secret, err := service.GetFeedSecret("user1")
+	if err != nil || secret == "" {
+		t.Fatalf("expected a secret, got %q, %v", secret, err)
+	}
+	if again, _ := service.GetFeedSecret("user1"); again != secret {
+		t.Errorf("GetFeedSecret() should return the same secret, got %q and %q", secret, again)
+	}
+	account, err := service.GetAccountByFeedSecret(secret)
+	if err != nil || account.AccountID() != "user1" {
+		t.Errorf("GetAccountByFeedSecret() = %v, %v", account, err)
+	}
+
+	regenerated, err := service.RegenerateFeedSecret("user1")
+	if err != nil || regenerated == secret {
+		t.Fatalf("RegenerateFeedSecret() = %q, %v", regenerated, err)
+	}
+	if _, err := service.GetAccountByFeedSecret(secret); err == nil {
+		t.Errorf("the old secret should be invalid after regeneration")
+	}
+
+	if err := service.RevokeFeedSecret("user1"); err != nil {
+		t.Fatalf("RevokeFeedSecret() error = %v", err)
+	}
+	if _, err := service.GetAccountByFeedSecret(regenerated); err == nil {
+		t.Errorf("the secret should be invalid after revocation")
+	}
+
+	if _, err := service.GetFeedSecret("unknown"); err == nil {
+		t.Errorf("GetFeedSecret() should fail for unknown account")
+	}
+}
@@ -3,10 +3,17 @@ package account
 import (
 	"cached_proxy/repo"
 	"encoding/gob"
-	"fmt"
+	"errors"
 	path2 "path"
 )
 
+var (
+	// ErrAccountNotFound 账户不存在
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrTokenCollision 生成的 token 已经被其他账户使用
+	ErrTokenCollision = errors.New("StaticToken has been occupied")
+)
+
 type repository interface {
 	// GetAccountByAccountID 获取账户信息
 	GetAccountByAccountID(accountID string) (Account, error)
@@ -14,6 +21,8 @@ type repository interface {
 	GetAccountByToken(token string) (Account, error)
 	// SaveOrUpdateAccount 保存或更新账户信息
 	SaveOrUpdateAccount(account Account) error
+	// CountByStatus 统计每种状态的账户数量
+	CountByStatus() map[Status]int
 }
 
 type Repository struct {
@@ -42,7 +51,7 @@ func NewFileRepository(path string) *Repository {
 func (m *Repository) GetAccountByAccountID(accountID string) (Account, error) {
 	account, found := m.idRepo.Get(accountID)
 	if !found {
-		return nil, fmt.Errorf("account not found")
+		return nil, ErrAccountNotFound
 	}
 	return account, nil
 }
@@ -50,7 +59,7 @@ func (m *Repository) GetAccountByAccountID(accountID string) (Account, error) {
 func (m *Repository) GetAccountByToken(token string) (Account, error) {
 	account, found := m.tokenRepo.Get(token)
 	if !found {
-		return nil, fmt.Errorf("account not found")
+		return nil, ErrAccountNotFound
 	}
 	return account, nil
 }
@@ -62,7 +71,7 @@ func (m *Repository) SaveOrUpdateAccount(account Account) error {
 	// if the StaticToken has been used by other account, we should reject the request
 	tokenAccount, found := m.tokenRepo.Get(token)
 	if found && tokenAccount.AccountID() != accountId {
-		return fmt.Errorf("StaticToken has been occupied")
+		return ErrTokenCollision
 	}
 
 	// if the account has other StaticToken, we should delete the old StaticToken
@@ -76,3 +85,12 @@ func (m *Repository) SaveOrUpdateAccount(account Account) error {
 	m.idRepo.Set(accountId, account)
 	return nil
 }
+
+func (m *Repository) CountByStatus() map[Status]int {
+	counts := map[Status]int{}
+	m.idRepo.Range(func(_ string, account Account) bool {
+		counts[account.Status()]++
+		return true
+	})
+	return counts
+}
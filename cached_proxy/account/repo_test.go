@@ -138,3 +138,21 @@ func TestSaveOrUpdateAccountWithUpdatingToken(t *testing.T) {
 		t.Fatalf("expected StaticToken to be 'newToken1', got %v", acc.Token())
 	}
 }
+
+func TestCountByStatus(t *testing.T) {
+	memRepo := setup()
+	for i, status := range []Status{Normal, Normal, Banned} {
+		account := &SimpleAccountImpl{
+			Username:    "user" + string(rune('1'+i)),
+			StaticToken: "token" + string(rune('1'+i)),
+			status:      status,
+		}
+		if err := memRepo.SaveOrUpdateAccount(account); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	counts := memRepo.CountByStatus()
+	if len(counts) != 2 || counts[Normal] != 2 || counts[Banned] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
@@ -2,7 +2,8 @@ package account
 
 import (
 	"cached_proxy/utils"
-	"log"
+	"errors"
+	"log/slog"
 )
 
 // Service 定义了账户服务的接口。
@@ -15,6 +16,8 @@ type Service interface {
 	Login(username string, password string) (string, error)
 	// LockAccount 锁定账户，锁定后账户返回的状态将会是锁定状态，如果需要恢复账户，需要重新登陆
 	LockAccount(accountID string) error
+	// CountByStatus 统计每种状态的账户数量
+	CountByStatus() map[Status]int
 }
 
 type ServiceImpl struct {
@@ -28,7 +31,7 @@ func NewServiceImpl(accountRepo repository) *ServiceImpl {
 func (s *ServiceImpl) GetAccountByAccountID(accountID string) (Account, error) {
 	account, err := s.accountRepo.GetAccountByAccountID(accountID)
 	if err != nil {
-		log.Print(err)
+		slog.Debug("failed to get account", "account", accountID, "error", err)
 		return nil, err
 	}
 	return account, nil
@@ -37,7 +40,7 @@ func (s *ServiceImpl) GetAccountByAccountID(accountID string) (Account, error) {
 func (s *ServiceImpl) GetAccountByToken(token string) (Account, error) {
 	account, err := s.accountRepo.GetAccountByToken(token)
 	if err != nil {
-		log.Print(err)
+		slog.Debug("failed to get account by token", "error", err)
 		return nil, err
 	}
 	return account, nil
@@ -47,7 +50,7 @@ func (s *ServiceImpl) GetAccountByToken(token string) (Account, error) {
 func (s *ServiceImpl) newAccount(username string, password string) (Account, error) {
 	token, err := utils.GenerateUUID()
 	if err != nil {
-		log.Print(err)
+		slog.Error("failed to generate token", "error", err)
 		return nil, err
 	}
 	account := &SimpleAccountImpl{
@@ -67,7 +70,7 @@ func (s *ServiceImpl) Login(username string, password string) (string, error) {
 		}
 		err = s.accountRepo.SaveOrUpdateAccount(account)
 		if err != nil {
-			if err.Error() == "StaticToken has been occupied" {
+			if errors.Is(err, ErrTokenCollision) {
 				continue
 			}
 			return "", err
@@ -84,3 +87,7 @@ func (s *ServiceImpl) LockAccount(accountID string) error {
 	account.setStatus(Banned)
 	return s.accountRepo.SaveOrUpdateAccount(account)
 }
+
+func (s *ServiceImpl) CountByStatus() map[Status]int {
+	return s.accountRepo.CountByStatus()
+}
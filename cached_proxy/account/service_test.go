@@ -1,7 +1,6 @@
 package account
 
 import (
-	"errors"
 	"testing"
 )
 
@@ -21,7 +20,7 @@ func NewMockRepository() *MockRepository {
 func (m *MockRepository) GetAccountByAccountID(accountID string) (Account, error) {
 	account, found := m.accountsByID[accountID]
 	if !found {
-		return nil, errors.New("account not found")
+		return nil, ErrAccountNotFound
 	}
 	return account, nil
 }
@@ -29,7 +28,7 @@ func (m *MockRepository) GetAccountByAccountID(accountID string) (Account, error
 func (m *MockRepository) GetAccountByToken(token string) (Account, error) {
 	account, found := m.accountsByToken[token]
 	if !found {
-		return nil, errors.New("account not found")
+		return nil, ErrAccountNotFound
 	}
 	return account, nil
 }
@@ -40,6 +39,14 @@ func (m *MockRepository) SaveOrUpdateAccount(account Account) error {
 	return nil
 }
 
+func (m *MockRepository) CountByStatus() map[Status]int {
+	counts := map[Status]int{}
+	for _, account := range m.accountsByID {
+		counts[account.Status()]++
+	}
+	return counts
+}
+
 func TestServiceImpl_GetAccountByAccountID(t *testing.T) {
 	mockRepo := NewMockRepository()
 	service := &ServiceImpl{accountRepo: mockRepo}
@@ -7,4 +7,13 @@ type cacheItem[V any] struct {
 	data     V         // 缓存的数据
 	updateAt time.Time // 缓存更新时间
 	submitAt time.Time // 提交更新时间
+	lastErr  error     // 最近一次更新失败的原因，更新成功后清空
+}
+
+// Meta 是缓存条目的新鲜度信息
+type Meta struct {
+	Status      ItemStatus // 获取数据时的缓存状态
+	UpdatedAt   time.Time  // 缓存更新时间，从未更新时为零值
+	LastError   error      // 最近一次更新失败的原因，包含上游的内部信息，不能直接返回给客户端
+	NextRefresh time.Time  // 缓存过期的时间，过期后的第一次请求会触发更新
 }
@@ -3,22 +3,34 @@ package cache
 import (
 	"cached_proxy/executor"
 	"cached_proxy/repo"
+	"cached_proxy/tracing"
+	"context"
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 )
 
 type InformationService[V any] interface {
 	// GetInfo 获取信息
 	GetInfo(studentID string) (*V, error)
+	// GetInfoContext 与 GetInfo 相同，触发的更新任务使用 ctx 中的值
+	GetInfoContext(ctx context.Context, studentID string) (*V, error)
+	// GetInfoWithMeta 获取信息和缓存的新鲜度信息
+	GetInfoWithMeta(studentID string) (*V, Meta, error)
+	// GetInfoWithMetaContext 与 GetInfoWithMeta 相同，触发的更新任务使用 ctx 中的值，例如请求 ID
+	GetInfoWithMetaContext(ctx context.Context, studentID string) (*V, Meta, error)
 	// 触发更新
-	submitUpdateTask(studentID string)
+	submitUpdateTask(ctx context.Context, studentID string, priority executor.Priority)
 }
 
 type AbsInfoService[V any] struct {
+	name      string // 服务名称，用于监控指标
 	checker   StatusChecker[V]
-	onUpdater func(studentID string) (value *V, update bool)
+	onUpdater func(ctx context.Context, studentID string) (*V, error)
 	exec      executor.Executor
 	repo      repo.KVRepo[string, cacheItem[V]]
+	mu        sync.Mutex // 串行化缓存条目的读改写，避免并发的刷新互相覆盖
 }
 
 func (p *AbsInfoService[V]) getData(key string) *cacheItem[V] {
@@ -33,51 +45,143 @@ func (p *AbsInfoService[V]) setData(key string, item *cacheItem[V]) {
 	p.repo.Set(key, *item)
 }
 
-func (p *AbsInfoService[V]) submitUpdateTask(studentID string) {
+// modifyData 在锁内读取、修改并保存缓存条目，条目不存在时 f 收到 nil，f 返回 nil 时不保存
+func (p *AbsInfoService[V]) modifyData(key string, f func(item *cacheItem[V]) *cacheItem[V]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if item := f(p.getData(key)); item != nil {
+		p.setData(key, item)
+	}
+}
+
+// submitUpdateTask 提交更新任务，更新在请求结束后继续进行，因此任务使用不会被取消的 ctx，只保留其中的值
+//
+// 已经有更新任务在进行时不重复提交。协程池队列已满、任务被拒绝或者被丢弃时取消更新标记，下一次查询会重新提交。
+func (p *AbsInfoService[V]) submitUpdateTask(ctx context.Context, studentID string, priority executor.Priority) {
 	// 标记为更新
-	item := p.getData(studentID)
-	if item == nil {
-		item = &cacheItem[V]{}
+	submitted := false
+	p.modifyData(studentID, func(item *cacheItem[V]) *cacheItem[V] {
+		if item != nil && p.checker.StatusOf(item) == Updating {
+			return nil
+		}
+		if item == nil {
+			item = &cacheItem[V]{}
+		}
+		item.submitAt = time.Now()
+		submitted = true
+		return item
+	})
+	if !submitted {
+		return
 	}
-	item.submitAt = time.Now()
-	p.setData(studentID, item)
 	// 提交更新任务
-	p.exec.Submit(func() {
-		value, succeed := p.onUpdater(studentID)
-		if succeed {
-			formerItem := p.getData(studentID)
-			formerItem.data = *value
-			formerItem.updateAt = time.Now()
-			p.setData(studentID, formerItem)
+	ctx = context.WithoutCancel(ctx)
+	err := executor.SubmitContext(p.exec, ctx, executor.Task{
+		Name:     "cache.refresh " + p.name,
+		Priority: priority,
+		Run: func(taskCtx context.Context) {
+			p.update(taskCtx, studentID)
+		},
+		OnShed: func(err error) {
+			p.abandonUpdate(ctx, studentID, err)
+		},
+	})
+	if err != nil {
+		p.abandonUpdate(ctx, studentID, err)
+	}
+}
+
+// update 执行更新并保存结果，失败时保留原来的数据
+func (p *AbsInfoService[V]) update(ctx context.Context, studentID string) {
+	value, err := p.onUpdater(ctx, studentID)
+	if err == nil && value == nil {
+		err = fmt.Errorf("empty value")
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "cache refresh failed", "service", p.name, "student", studentID, "error", err)
+		tracing.SpanFromContext(ctx).RecordError(err)
+		refreshesMetric.Inc(p.name, "error")
+	} else {
+		refreshesMetric.Inc(p.name, "success")
+	}
+	p.modifyData(studentID, func(item *cacheItem[V]) *cacheItem[V] {
+		if item == nil {
+			item = &cacheItem[V]{}
 		}
+		item.lastErr = err
+		if err == nil {
+			item.data = *value
+			item.updateAt = time.Now()
+		}
+		return item
+	})
+}
+
+// abandonUpdate 在更新任务没有执行时取消更新标记
+func (p *AbsInfoService[V]) abandonUpdate(ctx context.Context, studentID string, err error) {
+	slog.WarnContext(ctx, "cache refresh dropped", "service", p.name, "student", studentID, "error", err)
+	refreshesMetric.Inc(p.name, "rejected")
+	p.modifyData(studentID, func(item *cacheItem[V]) *cacheItem[V] {
+		if item == nil {
+			return nil
+		}
+		item.submitAt = time.Time{}
+		item.lastErr = err
+		return item
 	})
 }
 
 func (p *AbsInfoService[V]) GetInfo(studentID string) (*V, error) {
+	return p.GetInfoContext(context.Background(), studentID)
+}
+
+func (p *AbsInfoService[V]) GetInfoContext(ctx context.Context, studentID string) (*V, error) {
+	value, _, err := p.GetInfoWithMetaContext(ctx, studentID)
+	return value, err
+}
+
+func (p *AbsInfoService[V]) GetInfoWithMeta(studentID string) (*V, Meta, error) {
+	return p.GetInfoWithMetaContext(context.Background(), studentID)
+}
+
+func (p *AbsInfoService[V]) GetInfoWithMetaContext(ctx context.Context, studentID string) (*V, Meta, error) {
+	ctx, span := tracing.Start(ctx, "cache.lookup", tracing.WithAttributes(tracing.Attribute{Key: "cache.service", Value: p.name}))
+	defer span.End()
 	item := p.getData(studentID)
+	meta := Meta{Status: p.checker.StatusOf(item), NextRefresh: p.checker.NextRefresh(item)}
+	span.SetAttribute("cache.status", lookupResult(meta.Status))
+	lookupsMetric.Inc(p.name, lookupResult(meta.Status))
+	slog.DebugContext(ctx, "cache lookup", "service", p.name, "student", studentID, "status", meta.Status)
+	if item != nil {
+		meta.UpdatedAt = item.updateAt
+		meta.LastError = item.lastErr
+	}
 	var err error
-	switch p.checker.StatusOf(item) {
+	switch meta.Status {
 	case Valid:
-		return &item.data, nil
+		return &item.data, meta, nil
 	case Expired:
 		err = fmt.Errorf("cache expired")
-		p.submitUpdateTask(studentID)
+		// 客户端仍然可以使用旧数据，作为后台任务刷新
+		p.submitUpdateTask(ctx, studentID, executor.PriorityBackground)
 	case NotFound:
 		err = fmt.Errorf("cache not found")
-		p.submitUpdateTask(studentID)
-		return nil, err
+		p.submitUpdateTask(ctx, studentID, executor.PriorityInteractive)
+		return nil, meta, err
 	case Updating:
 		err = fmt.Errorf("cache updating")
 	}
-	return &item.data, err
+	return &item.data, meta, err
 }
 
 func NewPublicInformationService[V any](
+	name string,
 	executor2 executor.Executor,
 	checker StatusChecker[V],
-	onUpdater func(studentID string) (value *V, update bool),
+	onUpdater func(ctx context.Context, studentID string) (*V, error),
 ) InformationService[V] {
 	return &AbsInfoService[V]{
+		name:      name,
 		exec:      executor2,
 		checker:   checker,
 		onUpdater: onUpdater,
@@ -86,11 +190,13 @@ func NewPublicInformationService[V any](
 }
 
 func NewPersonalInformationService[V any](
+	name string,
 	executor2 executor.Executor,
 	checker StatusChecker[V],
-	onUpdater func(studentID string) (value *V, update bool),
+	onUpdater func(ctx context.Context, studentID string) (*V, error),
 ) InformationService[V] {
 	return &AbsInfoService[V]{
+		name:      name,
 		exec:      executor2,
 		checker:   checker,
 		onUpdater: onUpdater,
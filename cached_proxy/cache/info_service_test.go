@@ -2,6 +2,14 @@ package cache
 
 import (
 	"cached_proxy/executor"
+	"cached_proxy/logging"
+	"cached_proxy/metrics"
+	"cached_proxy/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -10,11 +18,11 @@ func TestPersonalInformationService_GetInfo(t *testing.T) {
 	exec := executor.NewWorkerPool(1)
 	exec.Run()
 	checker := NewIntervalStatusChecker[string](2*time.Second, 3*time.Second)
-	onUpdater := func(studentID string) (value *string, update bool) {
+	onUpdater := func(_ context.Context, studentID string) (*string, error) {
 		v := "updated info"
-		return &v, true
+		return &v, nil
 	}
-	service := NewPersonalInformationService(exec, checker, onUpdater)
+	service := NewPersonalInformationService("test", exec, checker, onUpdater)
 
 	// Test cache not found
 	t.Run("Cache not found", func(t *testing.T) {
@@ -71,11 +79,11 @@ func TestPublicInformationService_GetInfo(t *testing.T) {
 	exec := executor.NewWorkerPool(1)
 	exec.Run()
 	checker := NewDailyStatusChecker[string](2 * time.Second)
-	onUpdater := func(studentID string) (value *string, update bool) {
+	onUpdater := func(_ context.Context, studentID string) (*string, error) {
 		v := "updated info"
-		return &v, true
+		return &v, nil
 	}
-	service := NewPublicInformationService(exec, checker, onUpdater)
+	service := NewPublicInformationService("test", exec, checker, onUpdater)
 
 	// Test cache not found
 	t.Run("Cache not found", func(t *testing.T) {
@@ -127,3 +135,201 @@ func TestPublicInformationService_GetInfo(t *testing.T) {
 		}
 	})
 }
+
+// syncExecutor 在提交时立即执行任务
+type syncExecutor struct{}
+
+func (syncExecutor) Run()               {}
+func (syncExecutor) Submit(task func()) { task() }
+func (syncExecutor) Wait()              {}
+
+func TestAbsInfoService_GetInfoWithMeta(t *testing.T) {
+	var updateErr error
+	onUpdater := func(_ context.Context, studentID string) (*string, error) {
+		if updateErr != nil {
+			return nil, updateErr
+		}
+		v := "updated info"
+		return &v, nil
+	}
+	service := NewPersonalInformationService[string]("test", syncExecutor{}, NewIntervalStatusChecker[string](time.Hour, time.Minute), onUpdater)
+
+	updateErr = fmt.Errorf("service unavailable")
+	data, meta, err := service.GetInfoWithMeta("student1")
+	if data != nil || err == nil || meta.Status != NotFound || !meta.UpdatedAt.IsZero() || !meta.NextRefresh.IsZero() {
+		t.Errorf("unexpected result without cache: %v %+v %v", data, meta, err)
+	}
+	_, meta, _ = service.GetInfoWithMeta("student1")
+	if meta.Status != Updating || meta.LastError == nil || meta.LastError.Error() != "service unavailable" || !meta.UpdatedAt.IsZero() {
+		t.Errorf("failed update should be recorded, got %+v", meta)
+	}
+
+	updateErr = nil
+	service.(*AbsInfoService[string]).setData("student1", &cacheItem[string]{lastErr: fmt.Errorf("service unavailable")})
+	service.(*AbsInfoService[string]).submitUpdateTask(context.Background(), "student1", executor.PriorityInteractive)
+	data, meta, err = service.GetInfoWithMeta("student1")
+	if err != nil || *data != "updated info" || meta.Status != Valid || meta.LastError != nil {
+		t.Errorf("unexpected result after update: %v %+v %v", data, meta, err)
+	}
+	if !meta.NextRefresh.Equal(meta.UpdatedAt.Add(time.Hour)) {
+		t.Errorf("unexpected next refresh %v, updated at %v", meta.NextRefresh, meta.UpdatedAt)
+	}
+}
+
+func TestItemStatus_MarshalText(t *testing.T) {
+	for status, want := range map[ItemStatus]string{Valid: "valid", Expired: "expired", NotFound: "not_found", Updating: "updating", 9: "unknown"} {
+		if got, _ := status.MarshalText(); string(got) != want {
+			t.Errorf("MarshalText(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
+
+func TestAbsInfoService_Metrics(t *testing.T) {
+	onUpdater := func(_ context.Context, studentID string) (*string, error) {
+		if studentID == "broken" {
+			return nil, fmt.Errorf("service unavailable")
+		}
+		v := "updated info"
+		return &v, nil
+	}
+	service := NewPersonalInformationService[string]("metrics_test", syncExecutor{}, NewIntervalStatusChecker[string](time.Hour, time.Minute), onUpdater)
+
+	_, _, _ = service.GetInfoWithMeta("student1")
+	_, _, _ = service.GetInfoWithMeta("student1")
+	_, _, _ = service.GetInfoWithMeta("broken")
+	tests := []struct {
+		metric *metrics.CounterVec
+		result string
+		want   float64
+	}{
+		{lookupsMetric, "not_found", 2},
+		{lookupsMetric, "hit", 1},
+		{refreshesMetric, "success", 1},
+		{refreshesMetric, "error", 1},
+	}
+	for _, tt := range tests {
+		if got := tt.metric.Get("metrics_test", tt.result); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestAbsInfoService_UpdateContext(t *testing.T) {
+	var updateCtx context.Context
+	onUpdater := func(ctx context.Context, studentID string) (*string, error) {
+		updateCtx = ctx
+		v := "updated info"
+		return &v, nil
+	}
+	service := NewPersonalInformationService[string]("test", syncExecutor{}, NewIntervalStatusChecker[string](time.Hour, time.Minute), onUpdater)
+
+	// 请求结束后更新任务仍然继续，但保留请求 ID
+	ctx, cancel := context.WithCancel(logging.WithRequestID(context.Background(), "req-1"))
+	cancel()
+	_, _, _ = service.GetInfoWithMetaContext(ctx, "student1")
+	if updateCtx == nil || logging.RequestID(updateCtx) != "req-1" || updateCtx.Err() != nil {
+		t.Errorf("update task should keep the request ID without being canceled, got %v", updateCtx)
+	}
+}
+
+func TestAbsInfoService_Tracing(t *testing.T) {
+	recorder := &tracing.Recorder{}
+	old := tracing.Default()
+	tracing.SetDefault(tracing.NewTracer(recorder))
+	defer tracing.SetDefault(old)
+	onUpdater := func(_ context.Context, studentID string) (*string, error) {
+		return nil, fmt.Errorf("service unavailable")
+	}
+	service := NewPersonalInformationService[string]("tracing_test", syncExecutor{}, NewIntervalStatusChecker[string](time.Hour, time.Minute), onUpdater)
+
+	_, _, _ = service.GetInfoWithMeta("student1")
+	spans := recorder.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("expected queue, run and lookup spans, got %v", spans)
+	}
+	run, lookup := spans[1], spans[2]
+	if lookup.Name != "cache.lookup" || lookup.Attribute("cache.service") != "tracing_test" || lookup.Attribute("cache.status") != "not_found" {
+		t.Errorf("unexpected lookup span %+v", lookup)
+	}
+	// 刷新任务属于触发它的查询
+	if run.Parent != lookup.SpanContext.SpanID || run.Status != tracing.StatusError || run.StatusMessage != "service unavailable" {
+		t.Errorf("unexpected refresh span %+v", run)
+	}
+}
+
+// rejectingExecutor 记录提交的任务的优先级并拒绝所有任务
+type rejectingExecutor struct {
+	syncExecutor
+	priorities []executor.Priority
+}
+
+func (e *rejectingExecutor) SubmitTask(_ context.Context, task executor.Task) error {
+	e.priorities = append(e.priorities, task.Priority)
+	return executor.ErrQueueFull
+}
+
+func TestAbsInfoService_Rejected(t *testing.T) {
+	exec := &rejectingExecutor{}
+	onUpdater := func(_ context.Context, studentID string) (*string, error) {
+		t.Error("rejected task should not run")
+		return nil, nil
+	}
+	service := NewPersonalInformationService[string]("rejected_test", exec, NewIntervalStatusChecker[string](time.Hour, time.Minute), onUpdater)
+
+	_, _, _ = service.GetInfoWithMeta("student1")
+	// 被拒绝后不再处于更新中，下一次查询重新提交
+	_, meta, _ := service.GetInfoWithMeta("student1")
+	if meta.Status != Expired || !errors.Is(meta.LastError, executor.ErrQueueFull) {
+		t.Errorf("rejected update should be retried, got %+v", meta)
+	}
+	want := []executor.Priority{executor.PriorityInteractive, executor.PriorityBackground}
+	if fmt.Sprint(exec.priorities) != fmt.Sprint(want) {
+		t.Errorf("priorities = %v, want %v", exec.priorities, want)
+	}
+	if got := refreshesMetric.Get("rejected_test", "rejected"); got != 2 {
+		t.Errorf("rejected refreshes = %v, want 2", got)
+	}
+}
+
+func TestAbsInfoService_ConcurrentRefresh(t *testing.T) {
+	type constructor func(string, executor.Executor, StatusChecker[string], func(context.Context, string) (*string, error)) InformationService[string]
+	tests := []struct {
+		name string
+		new  constructor
+	}{
+		{"public", NewPublicInformationService[string]},
+		{"personal", NewPersonalInformationService[string]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := executor.NewWorkerPool(4)
+			var calls atomic.Int32
+			onUpdater := func(_ context.Context, studentID string) (*string, error) {
+				calls.Add(1)
+				time.Sleep(10 * time.Millisecond)
+				v := "info of " + studentID
+				return &v, nil
+			}
+			service := tt.new("concurrent_test", exec, NewIntervalStatusChecker[string](time.Hour, time.Minute), onUpdater)
+
+			// 同一个 ID 的并发查询只提交一次更新，更新结果不会被其他查询的标记覆盖
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, _, _ = service.GetInfoWithMeta("student1")
+				}()
+			}
+			wg.Wait()
+			exec.Wait()
+			if got := calls.Load(); got != 1 {
+				t.Errorf("updater calls = %d, want 1", got)
+			}
+			data, meta, err := service.GetInfoWithMeta("student1")
+			if err != nil || meta.Status != Valid || *data != "info of student1" {
+				t.Errorf("got %v, %+v, %v, want valid data", data, meta, err)
+			}
+		})
+	}
+}
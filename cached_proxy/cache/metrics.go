@@ -0,0 +1,24 @@
+package cache
+
+import "cached_proxy/metrics"
+
+var (
+	// lookupsMetric 按缓存项的状态统计查询次数，有效的缓存项记为 hit
+	lookupsMetric = metrics.NewCounterVec("proxy_cache_lookups_total",
+		"Cache lookups by service and result (hit, expired, not_found, updating).", "service", "result")
+	// refreshesMetric 统计后台更新的结果
+	refreshesMetric = metrics.NewCounterVec("proxy_cache_refreshes_total",
+		"Background cache refreshes by service and result (success, error).", "service", "result")
+)
+
+func init() {
+	metrics.Default.Register(lookupsMetric, refreshesMetric)
+}
+
+// lookupResult 返回查询结果在指标中的名称
+func lookupResult(status ItemStatus) string {
+	if status == Valid {
+		return "hit"
+	}
+	return status.String()
+}
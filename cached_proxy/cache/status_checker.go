@@ -5,15 +5,36 @@ import "time"
 type ItemStatus int
 
 const (
-	Valid    = iota // 缓存有效
-	Expired         // 缓存过期
-	NotFound        // 缓存未找到
-	Updating        // 缓存正在更新
+	Valid    ItemStatus = iota // 缓存有效
+	Expired                    // 缓存过期
+	NotFound                   // 缓存未找到
+	Updating                   // 缓存正在更新
 )
 
+// String 返回缓存状态的名称
+func (s ItemStatus) String() string {
+	switch s {
+	case Valid:
+		return "valid"
+	case Expired:
+		return "expired"
+	case NotFound:
+		return "not_found"
+	case Updating:
+		return "updating"
+	}
+	return "unknown"
+}
+
+// MarshalText 在 JSON 中使用缓存状态的名称
+func (s ItemStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // StatusChecker  缓存校验器
 type StatusChecker[V any] interface {
-	StatusOf(item *cacheItem[V]) ItemStatus // 校验缓存状态
+	StatusOf(item *cacheItem[V]) ItemStatus   // 校验缓存状态
+	NextRefresh(item *cacheItem[V]) time.Time // 缓存过期的时间，没有数据时返回零值
 }
 
 type DailyStatusChecker[V any] struct {
@@ -35,6 +56,15 @@ func (d *DailyStatusChecker[V]) StatusOf(item *cacheItem[V]) ItemStatus {
 
 }
 
+// NextRefresh 返回更新时间的第二天零点
+func (d *DailyStatusChecker[V]) NextRefresh(item *cacheItem[V]) time.Time {
+	if item == nil || item.updateAt.IsZero() {
+		return time.Time{}
+	}
+	year, month, day := item.updateAt.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, item.updateAt.Location())
+}
+
 // NewDailyStatusChecker 创建默认缓存校验器
 func NewDailyStatusChecker[V any](submitExpireAt time.Duration) *DailyStatusChecker[V] {
 	return &DailyStatusChecker[V]{
@@ -62,6 +92,14 @@ func (d *IntervalStatusChecker[V]) StatusOf(item *cacheItem[V]) ItemStatus {
 
 }
 
+// NextRefresh 返回更新时间加上过期时间间隔
+func (d *IntervalStatusChecker[V]) NextRefresh(item *cacheItem[V]) time.Time {
+	if item == nil || item.updateAt.IsZero() {
+		return time.Time{}
+	}
+	return item.updateAt.Add(d.updateExpireInterval)
+}
+
 // NewIntervalStatusChecker 创建默认缓存校验器
 func NewIntervalStatusChecker[V any](updateExpireAt, submitExpireAt time.Duration) *IntervalStatusChecker[V] {
 	return &IntervalStatusChecker[V]{
@@ -96,3 +96,20 @@ func TestDailyStatusChecker_StatusOf(t *testing.T) {
 		}
 	})
 }
+
+func TestStatusChecker_NextRefresh(t *testing.T) {
+	zone := time.FixedZone("Asia/Shanghai", 8*60*60)
+	updateAt := time.Date(2025, 2, 28, 23, 30, 0, 0, zone)
+	item := &cacheItem[string]{updateAt: updateAt}
+	if got := NewDailyStatusChecker[string](time.Second).NextRefresh(item); !got.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, zone)) {
+		t.Errorf("unexpected next refresh of daily checker %v", got)
+	}
+	if got := NewIntervalStatusChecker[string](2*time.Hour, time.Second).NextRefresh(item); !got.Equal(updateAt.Add(2 * time.Hour)) {
+		t.Errorf("unexpected next refresh of interval checker %v", got)
+	}
+	for _, item := range []*cacheItem[string]{nil, {submitAt: updateAt}} {
+		if got := NewDailyStatusChecker[string](time.Second).NextRefresh(item); !got.IsZero() {
+			t.Errorf("expected zero time without data, got %v", got)
+		}
+	}
+}
@@ -0,0 +1,619 @@
+package main
+
+import (
+	account2 "cached_proxy/account"
+	"cached_proxy/icalendar"
+	"context"
+	"crypto/sha256"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CalDAVRoot 是 CalDAV 服务的根路径
+const CalDAVRoot = "/caldav/"
+
+// CalDAV 使用的 XML 命名空间
+const (
+	davNS       = "DAV:"
+	calDAVNS    = "urn:ietf:params:xml:ns:caldav"
+	calServerNS = "http://calendarserver.org/ns/"
+)
+
+// davPrefixes 是响应中命名空间使用的前缀，在 multistatus 上统一声明
+var davPrefixes = map[string]string{davNS: "d", calDAVNS: "c", calServerNS: "cs"}
+
+// davHiddenProps 是 allprop 时不返回的属性，calendar-data 只在明确请求时返回
+var davHiddenProps = map[xml.Name]bool{{Space: calDAVNS, Local: "calendar-data"}: true}
+
+// calDAVCollection 是 CalDAV 中的一个只读日历
+type calDAVCollection struct {
+	id       string // 日历在地址中的名称
+	name     string // 日历的显示名称
+	renderer calendarRenderer
+}
+
+// CalDAVServer 提供只读的 CalDAV 服务，使用 HTTP Basic 认证，密码为账户的 token，用户名不做校验。
+// 地址结构如下：
+//
+//	/caldav/principals/{account}/                用户主体
+//	/caldav/calendars/{account}/                 日历主集合
+//	/caldav/calendars/{account}/{calendar}/      日历，calendar 为 courses、exams 或 all
+//	/caldav/calendars/{account}/{calendar}/{uid}.ics  单个事件
+type CalDAVServer struct {
+	TokenService
+	collections []calDAVCollection
+}
+
+var (
+	CalDAVHandler = &CalDAVServer{
+		TokenService: TokenService{acc: AccountService},
+		collections: []calDAVCollection{
+			{id: "courses", name: CoursesCalendarName, renderer: &CoursesCalendarHandler},
+			{id: "exams", name: ExamsCalendarName, renderer: &ExamCalendarHandler},
+			{id: "all", name: AllCalendarName, renderer: AllCalendarHandler},
+		},
+	}
+)
+
+// davTarget 是请求路径对应的资源
+type davTarget struct {
+	kind       string // root、principal、home、calendar 或 object
+	accountID  string
+	collection *calDAVCollection
+	object     string // 事件的资源名，例如 {uid}.ics
+}
+
+// calDAVObject 是日历中的一个事件，每个事件是一个单独的日历对象
+type calDAVObject struct {
+	name  string
+	ics   string
+	etag  string
+	event icalendar.Event
+}
+
+// calDAVData 是某个日历当前的全部事件
+type calDAVData struct {
+	ics      string // 整个日历的内容
+	objects  []calDAVObject
+	ctag     string
+	modified time.Time
+}
+
+// davResource 是 multistatus 中的一个资源，props 是属性名到 XML 内容的映射
+type davResource struct {
+	href   string
+	props  map[xml.Name]string
+	status int // 不为 0 时表示资源本身的状态，例如 multiget 中不存在的事件
+}
+
+// davPropNames 是请求中的 prop 元素
+type davPropNames struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+type davPropfind struct {
+	XMLName xml.Name      `xml:"DAV: propfind"`
+	AllProp *struct{}     `xml:"DAV: allprop"`
+	Prop    *davPropNames `xml:"DAV: prop"`
+}
+
+// calDAVReport 是 calendar-query 和 calendar-multiget 请求
+type calDAVReport struct {
+	XMLName xml.Name
+	Prop    *davPropNames `xml:"DAV: prop"`
+	Hrefs   []string      `xml:"DAV: href"`
+	Filter  *struct {
+		Comp calDAVCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type calDAVCompFilter struct {
+	Name         string    `xml:"name,attr"`
+	IsNotDefined *struct{} `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+	TimeRange    *struct {
+		Start string `xml:"start,attr"`
+		End   string `xml:"end,attr"`
+	} `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	Comps []calDAVCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// WellKnown 按照 RFC 6764 将 /.well-known/caldav 重定向到 CalDAV 的根路径
+func (c *CalDAVServer) WellKnown(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, CalDAVRoot, http.StatusMovedPermanently)
+}
+
+// ServeDAV 处理 /caldav/ 下的所有请求，只支持 OPTIONS、GET、HEAD、PROPFIND 和 REPORT
+func (c *CalDAVServer) ServeDAV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		return
+	}
+	account := c.authenticate(w, r)
+	if account == nil {
+		return
+	}
+	target, found := c.resolve(r.URL.Path)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	if target.kind != "root" && target.accountID != account.AccountID() {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	target.accountID = account.AccountID()
+	switch r.Method {
+	case "PROPFIND":
+		c.propfind(w, r, target)
+	case "REPORT":
+		c.report(w, r, target)
+	case http.MethodGet, http.MethodHead:
+		c.get(w, r, target)
+	default:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authenticate 使用 HTTP Basic 认证中的密码作为 token 查找账户
+func (c *CalDAVServer) authenticate(w http.ResponseWriter, r *http.Request) account2.Account {
+	unauthorized := func() {
+		w.Header().Set("WWW-Authenticate", `Basic realm="cached_proxy", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+	_, token, ok := r.BasicAuth()
+	if !ok || token == "" {
+		unauthorized()
+		return nil
+	}
+	account, err := c.acc.GetAccountByToken(token)
+	if err != nil {
+		if errors.Is(err, account2.ErrAccountNotFound) {
+			unauthorized()
+		} else {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return nil
+	}
+	if account == nil || account.Status() != account2.Normal {
+		unauthorized()
+		return nil
+	}
+	return account
+}
+
+// resolve 解析请求路径
+func (c *CalDAVServer) resolve(path string) (*davTarget, bool) {
+	path = strings.Trim(strings.TrimPrefix(path, strings.TrimSuffix(CalDAVRoot, "/")), "/")
+	if path == "" {
+		return &davTarget{kind: "root"}, true
+	}
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "principals":
+		return &davTarget{kind: "principal", accountID: parts[1]}, true
+	case len(parts) == 2 && parts[0] == "calendars":
+		return &davTarget{kind: "home", accountID: parts[1]}, true
+	case len(parts) == 3 || len(parts) == 4:
+		if parts[0] != "calendars" {
+			return nil, false
+		}
+		collection := c.collection(parts[2])
+		if collection == nil {
+			return nil, false
+		}
+		target := &davTarget{kind: "calendar", accountID: parts[1], collection: collection}
+		if len(parts) == 4 {
+			target.kind = "object"
+			target.object = parts[3]
+		}
+		return target, true
+	}
+	return nil, false
+}
+
+func (c *CalDAVServer) collection(id string) *calDAVCollection {
+	for i := range c.collections {
+		if c.collections[i].id == id {
+			return &c.collections[i]
+		}
+	}
+	return nil
+}
+
+// load 生成日历并按事件拆分为单独的日历对象
+func (c *CalDAVServer) load(ctx context.Context, accountID string, collection *calDAVCollection) (*calDAVData, error) {
+	rendered, err := collection.renderer.render(ctx, accountID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rendered.calendar == nil {
+		return nil, fmt.Errorf("calendar %s of %s is not available", collection.id, accountID)
+	}
+	data := &calDAVData{ics: rendered.ics, modified: rendered.modified}
+	tags := strings.Builder{}
+	for _, event := range rendered.calendar.GetEvents() {
+		ical := &icalendar.IcsCalendar{}
+		ical.SetProductID(ProdID)
+		ical.SetTimezone(icalendar.GetDefaultTimezone())
+		ical.AddEvent(event)
+		object := calDAVObject{name: event.GetUID() + ".ics", ics: ical.ToIcs(nil), event: event}
+		object.etag = calDAVETag(object.ics)
+		tags.WriteString(object.etag)
+		data.objects = append(data.objects, object)
+	}
+	data.ctag = calDAVETag(tags.String())
+	return data, nil
+}
+
+// calDAVETag 根据内容生成 ETag，DTSTAMP 在每次生成时都不同，因此不参与计算
+func calDAVETag(content string) string {
+	hash := sha256.New()
+	for _, line := range strings.SplitAfter(content, "\r\n") {
+		if !strings.HasPrefix(line, "DTSTAMP") {
+			hash.Write([]byte(line))
+		}
+	}
+	return fmt.Sprintf(`"%x"`, hash.Sum(nil)[:16])
+}
+
+// loadOrFail 加载日历，失败时输出错误，数据未就绪时让客户端稍后重试
+func (c *CalDAVServer) loadOrFail(w http.ResponseWriter, r *http.Request, target *davTarget) *calDAVData {
+	data, err := c.load(r.Context(), target.accountID, target.collection)
+	if errors.Is(err, errCalendarUpdating) {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Data Updating", http.StatusServiceUnavailable)
+		return nil
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to load calendar", "calendar", target.collection.id, "account", target.accountID, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil
+	}
+	return data
+}
+
+func (c *CalDAVServer) principalHref(accountID string) string {
+	return CalDAVRoot + "principals/" + url.PathEscape(accountID) + "/"
+}
+
+func (c *CalDAVServer) homeHref(accountID string) string {
+	return CalDAVRoot + "calendars/" + url.PathEscape(accountID) + "/"
+}
+
+func (c *CalDAVServer) collectionHref(accountID string, collection *calDAVCollection) string {
+	return c.homeHref(accountID) + collection.id + "/"
+}
+
+// accountProps 是所有资源共有的属性
+func (c *CalDAVServer) accountProps(accountID string, resourceType string) map[xml.Name]string {
+	return map[xml.Name]string{
+		{Space: davNS, Local: "resourcetype"}:               resourceType,
+		{Space: davNS, Local: "current-user-principal"}:     davHref(c.principalHref(accountID)),
+		{Space: davNS, Local: "current-user-privilege-set"}: "<d:privilege><d:read/></d:privilege>",
+	}
+}
+
+func (c *CalDAVServer) principalResource(accountID string) davResource {
+	props := c.accountProps(accountID, "<d:principal/>")
+	props[xml.Name{Space: davNS, Local: "displayname"}] = davText(accountID)
+	props[xml.Name{Space: davNS, Local: "principal-URL"}] = davHref(c.principalHref(accountID))
+	props[xml.Name{Space: calDAVNS, Local: "calendar-home-set"}] = davHref(c.homeHref(accountID))
+	return davResource{href: c.principalHref(accountID), props: props}
+}
+
+func (c *CalDAVServer) homeResource(accountID string) davResource {
+	props := c.accountProps(accountID, "<d:collection/>")
+	props[xml.Name{Space: davNS, Local: "displayname"}] = davText(accountID)
+	return davResource{href: c.homeHref(accountID), props: props}
+}
+
+func (c *CalDAVServer) collectionResource(accountID string, collection *calDAVCollection, data *calDAVData) davResource {
+	props := c.accountProps(accountID, "<d:collection/><c:calendar/>")
+	props[xml.Name{Space: davNS, Local: "displayname"}] = davText(collection.name)
+	props[xml.Name{Space: calDAVNS, Local: "supported-calendar-component-set"}] = `<c:comp name="VEVENT"/>`
+	props[xml.Name{Space: davNS, Local: "supported-report-set"}] = "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+		"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>"
+	if data != nil {
+		props[xml.Name{Space: calServerNS, Local: "getctag"}] = davText(data.ctag)
+		if !data.modified.IsZero() {
+			props[xml.Name{Space: davNS, Local: "getlastmodified"}] = data.modified.UTC().Format(http.TimeFormat)
+		}
+	}
+	return davResource{href: c.collectionHref(accountID, collection), props: props}
+}
+
+func (c *CalDAVServer) objectResource(href string, object *calDAVObject, modified time.Time) davResource {
+	props := map[xml.Name]string{
+		{Space: davNS, Local: "resourcetype"}:     "",
+		{Space: davNS, Local: "getetag"}:          davText(object.etag),
+		{Space: davNS, Local: "getcontenttype"}:   "text/calendar; charset=utf-8; component=VEVENT",
+		{Space: davNS, Local: "getcontentlength"}: fmt.Sprint(len(object.ics)),
+		{Space: calDAVNS, Local: "calendar-data"}: davText(object.ics),
+	}
+	if !modified.IsZero() {
+		props[xml.Name{Space: davNS, Local: "getlastmodified"}] = modified.UTC().Format(http.TimeFormat)
+	}
+	return davResource{href: href + url.PathEscape(object.name), props: props}
+}
+
+// propfind 按照 RFC 4918 9.1 返回资源的属性，Depth 为 infinity 时按照 1 处理
+func (c *CalDAVServer) propfind(w http.ResponseWriter, r *http.Request, target *davTarget) {
+	names, err := parsePropfind(r.Body)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	children := r.Header.Get("Depth") != "0"
+	var resources []davResource
+	switch target.kind {
+	case "root":
+		resources = append(resources, davResource{href: CalDAVRoot, props: c.accountProps(target.accountID, "<d:collection/>")})
+		if children {
+			resources = append(resources, c.principalResource(target.accountID))
+		}
+	case "principal":
+		resources = append(resources, c.principalResource(target.accountID))
+	case "home":
+		resources = append(resources, c.homeResource(target.accountID))
+		if children {
+			for i := range c.collections {
+				// 日历的 ctag 需要生成日历，列出主集合时不计算
+				resources = append(resources, c.collectionResource(target.accountID, &c.collections[i], nil))
+			}
+		}
+	case "calendar":
+		data := c.loadOrFail(w, r, target)
+		if data == nil {
+			return
+		}
+		href := c.collectionHref(target.accountID, target.collection)
+		resources = append(resources, c.collectionResource(target.accountID, target.collection, data))
+		if children {
+			for i := range data.objects {
+				resources = append(resources, c.objectResource(href, &data.objects[i], data.modified))
+			}
+		}
+	case "object":
+		data := c.loadOrFail(w, r, target)
+		if data == nil {
+			return
+		}
+		object := data.find(target.object)
+		if object == nil {
+			http.NotFound(w, r)
+			return
+		}
+		resources = append(resources, c.objectResource(c.collectionHref(target.accountID, target.collection), object, data.modified))
+	}
+	writeMultistatus(w, r, resources, names)
+}
+
+// report 支持 RFC 4791 中的 calendar-query 和 calendar-multiget
+func (c *CalDAVServer) report(w http.ResponseWriter, r *http.Request, target *davTarget) {
+	if target.collection == nil {
+		writeDAVError(w, http.StatusForbidden, "<d:supported-report/>")
+		return
+	}
+	report := calDAVReport{}
+	if err := xml.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&report); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if report.XMLName.Space != calDAVNS || (report.XMLName.Local != "calendar-query" && report.XMLName.Local != "calendar-multiget") {
+		writeDAVError(w, http.StatusForbidden, "<d:supported-report/>")
+		return
+	}
+	data := c.loadOrFail(w, r, target)
+	if data == nil {
+		return
+	}
+	href := c.collectionHref(target.accountID, target.collection)
+	var resources []davResource
+	if report.XMLName.Local == "calendar-multiget" {
+		for _, h := range report.Hrefs {
+			name := strings.TrimPrefix(strings.TrimSpace(h), href)
+			name, _ = url.PathUnescape(name)
+			if object := data.find(name); object != nil {
+				resources = append(resources, c.objectResource(href, object, data.modified))
+			} else {
+				resources = append(resources, davResource{href: h, status: http.StatusNotFound})
+			}
+		}
+	} else {
+		for i := range data.objects {
+			object := &data.objects[i]
+			if target.kind == "object" && object.name != target.object {
+				continue
+			}
+			if report.Filter == nil || matchCompFilter(&report.Filter.Comp, object.event) {
+				resources = append(resources, c.objectResource(href, object, data.modified))
+			}
+		}
+	}
+	var names []xml.Name
+	if report.Prop != nil {
+		names = report.Prop.names()
+	}
+	writeMultistatus(w, r, resources, names)
+}
+
+// get 输出单个事件，或者整个日历
+func (c *CalDAVServer) get(w http.ResponseWriter, r *http.Request, target *davTarget) {
+	if target.collection == nil {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data := c.loadOrFail(w, r, target)
+	if data == nil {
+		return
+	}
+	content, etag, name := data.ics, data.ctag, target.collection.id+".ics"
+	if target.kind == "object" {
+		object := data.find(target.object)
+		if object == nil {
+			http.NotFound(w, r)
+			return
+		}
+		content, etag, name = object.ics, object.etag, object.name
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, name, data.modified, strings.NewReader(content))
+}
+
+func (d *calDAVData) find(name string) *calDAVObject {
+	for i := range d.objects {
+		if d.objects[i].name == name {
+			return &d.objects[i]
+		}
+	}
+	return nil
+}
+
+// matchCompFilter 判断事件是否满足 calendar-query 的过滤条件，只支持 VEVENT 的 time-range，
+// 其他属性过滤条件不做处理，返回的事件可能多于请求的范围，由客户端自行过滤
+func matchCompFilter(filter *calDAVCompFilter, event icalendar.Event) bool {
+	if filter.Name != "VCALENDAR" {
+		return false
+	}
+	for i := range filter.Comps {
+		comp := &filter.Comps[i]
+		if comp.Name != "VEVENT" || comp.IsNotDefined != nil {
+			return false
+		}
+		if comp.TimeRange == nil {
+			continue
+		}
+		start, end := time.Time{}, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+		if comp.TimeRange.Start != "" {
+			start, _ = icalendar.ParseIcsTime(comp.TimeRange.Start, time.UTC)
+		}
+		if comp.TimeRange.End != "" {
+			end, _ = icalendar.ParseIcsTime(comp.TimeRange.End, time.UTC)
+		}
+		if !event.Overlaps(start, end) {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePropfind 解析 PROPFIND 请求体，请求体为空或为 allprop 时返回 nil
+func parsePropfind(body io.Reader) ([]xml.Name, error) {
+	content, err := io.ReadAll(io.LimitReader(body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(content))) == 0 {
+		return nil, nil
+	}
+	propfind := davPropfind{}
+	if err := xml.Unmarshal(content, &propfind); err != nil {
+		return nil, err
+	}
+	if propfind.Prop == nil {
+		return nil, nil
+	}
+	return propfind.Prop.names(), nil
+}
+
+func (p *davPropNames) names() []xml.Name {
+	names := make([]xml.Name, len(p.Names))
+	for i, name := range p.Names {
+		names[i] = name.XMLName
+	}
+	return names
+}
+
+// writeMultistatus 输出 207 Multi-Status，names 为 nil 时返回除 davHiddenProps 以外的全部属性
+func writeMultistatus(w http.ResponseWriter, r *http.Request, resources []davResource, names []xml.Name) {
+	b := strings.Builder{}
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + calDAVNS + `" xmlns:cs="` + calServerNS + `">`)
+	for _, resource := range resources {
+		b.WriteString("<d:response>" + davHref(resource.href))
+		if resource.status != 0 {
+			b.WriteString(davStatus(resource.status) + "</d:response>")
+			continue
+		}
+		requested := names
+		if requested == nil {
+			for name := range resource.props {
+				if !davHiddenProps[name] {
+					requested = append(requested, name)
+				}
+			}
+			sort.Slice(requested, func(i, j int) bool {
+				return requested[i].Space+requested[i].Local < requested[j].Space+requested[j].Local
+			})
+		}
+		found, missing := strings.Builder{}, strings.Builder{}
+		for _, name := range requested {
+			if value, ok := resource.props[name]; ok {
+				found.WriteString(davElement(name, value))
+			} else {
+				missing.WriteString(davElement(name, ""))
+			}
+		}
+		if found.Len() > 0 {
+			b.WriteString("<d:propstat><d:prop>" + found.String() + "</d:prop>" + davStatus(http.StatusOK) + "</d:propstat>")
+		}
+		if missing.Len() > 0 {
+			b.WriteString("<d:propstat><d:prop>" + missing.String() + "</d:prop>" + davStatus(http.StatusNotFound) + "</d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>\n")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		slog.WarnContext(r.Context(), "failed to write multistatus", "error", err)
+	}
+}
+
+// writeDAVError 按照 RFC 4918 16 输出带有前置条件的错误
+func writeDAVError(w http.ResponseWriter, status int, condition string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>`+"\n"+`<d:error xmlns:d="DAV:">`+condition+"</d:error>\n")
+}
+
+// davElement 生成属性元素，已知命名空间使用 multistatus 上声明的前缀
+func davElement(name xml.Name, inner string) string {
+	prefix, ok := davPrefixes[name.Space]
+	if !ok {
+		return "<" + name.Local + ` xmlns="` + davText(name.Space) + `"/>`
+	}
+	tag := prefix + ":" + name.Local
+	if inner == "" {
+		return "<" + tag + "/>"
+	}
+	return "<" + tag + ">" + inner + "</" + tag + ">"
+}
+
+func davHref(href string) string {
+	return "<d:href>" + davText(href) + "</d:href>"
+}
+
+func davStatus(status int) string {
+	return fmt.Sprintf("<d:status>HTTP/1.1 %d %s</d:status>", status, http.StatusText(status))
+}
+
+// davText 转义 XML 文本
+func davText(text string) string {
+	b := strings.Builder{}
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	account2 "cached_proxy/account"
+	"cached_proxy/feign"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAccountService 只有一个固定 token 的账户服务
+type fakeAccountService struct {
+	account2.Service
+	token   string
+	account account2.Account
+}
+
+func (f *fakeAccountService) GetAccountByToken(token string) (account2.Account, error) {
+	if token != f.token {
+		return nil, account2.ErrAccountNotFound
+	}
+	return f.account, nil
+}
+
+func newTestCalDAVServer() *CalDAVServer {
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	list := &feign.CourseList{Courses: []feign.Course{
+		{Name: "Early", Teacher: "Test", Classroom: "A101", Weeks: "1-4", StartTime: 1, Duration: 2, Day: "Monday"},
+		{Name: "Late", Teacher: "Test", Classroom: "A102", Weeks: "10-12", StartTime: 3, Duration: 2, Day: "Tuesday"},
+	}}
+	ical := coursesConvertCalendar("user1", list, calendar, nil, &CalendarOptions{})
+	rendered := &renderedCalendar{ics: ical.ToIcs(nil), calendar: ical, modified: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}
+	return &CalDAVServer{
+		TokenService: TokenService{acc: &fakeAccountService{token: "token", account: &account2.SimpleAccountImpl{Username: "user1"}}},
+		collections: []calDAVCollection{
+			{id: "courses", name: CoursesCalendarName, renderer: &fakeRenderer{rendered: rendered}},
+			{id: "exams", name: ExamsCalendarName, renderer: &fakeRenderer{err: errCalendarUpdating}},
+		},
+	}
+}
+
+func TestCalDAVServer_ServeDAV(t *testing.T) {
+	server := newTestCalDAVServer()
+	serve := func(method, path, depth, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.SetBasicAuth("user1", "token")
+		if depth != "" {
+			r.Header.Set("Depth", depth)
+		}
+		w := httptest.NewRecorder()
+		server.ServeDAV(w, r)
+		return w
+	}
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		depth   string
+		body    string
+		status  int
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "Current user principal",
+			method: "PROPFIND", path: "/caldav/", depth: "0",
+			body:   `<d:propfind xmlns:d="DAV:"><d:prop><d:current-user-principal/><d:unknown/></d:prop></d:propfind>`,
+			status: http.StatusMultiStatus,
+			want: []string{
+				"<d:current-user-principal><d:href>/caldav/principals/user1/</d:href></d:current-user-principal>",
+				"<d:unknown/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status>",
+			},
+		},
+		{
+			name:   "Calendar home set",
+			method: "PROPFIND", path: "/caldav/principals/user1/", depth: "0",
+			body:   `<propfind xmlns="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><prop><C:calendar-home-set/></prop></propfind>`,
+			status: http.StatusMultiStatus,
+			want:   []string{"<c:calendar-home-set><d:href>/caldav/calendars/user1/</d:href></c:calendar-home-set>"},
+		},
+		{
+			name:   "List calendars",
+			method: "PROPFIND", path: "/caldav/calendars/user1/", depth: "1",
+			status: http.StatusMultiStatus,
+			want: []string{
+				"<d:href>/caldav/calendars/user1/courses/</d:href>", "<d:href>/caldav/calendars/user1/exams/</d:href>",
+				"<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>", "<d:displayname>" + CoursesCalendarName + "</d:displayname>",
+			},
+		},
+		{
+			name:   "List events",
+			method: "PROPFIND", path: "/caldav/calendars/user1/courses/", depth: "1",
+			status:  http.StatusMultiStatus,
+			want:    []string{"<cs:getctag>", "<d:getetag>", ".ics</d:href>"},
+			notWant: []string{"<c:calendar-data>"},
+		},
+		{
+			name:   "Calendar query with time range",
+			method: "REPORT", path: "/caldav/calendars/user1/courses/", depth: "1",
+			body: `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+				<d:prop><d:getetag/><c:calendar-data/></d:prop>
+				<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT">
+					<c:time-range start="20250401T000000Z" end="20250601T000000Z"/>
+				</c:comp-filter></c:comp-filter></c:filter>
+			</c:calendar-query>`,
+			status:  http.StatusMultiStatus,
+			want:    []string{"<c:calendar-data>BEGIN:VCALENDAR", "】 Late"},
+			notWant: []string{"】 Early"},
+		},
+		{
+			name:   "Calendar multiget",
+			method: "REPORT", path: "/caldav/calendars/user1/courses/",
+			body: `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+				<d:prop><d:getetag/></d:prop><d:href>/caldav/calendars/user1/courses/missing.ics</d:href>
+			</c:calendar-multiget>`,
+			status: http.StatusMultiStatus,
+			want:   []string{"<d:href>/caldav/calendars/user1/courses/missing.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>"},
+		},
+		{
+			name:   "Unsupported report",
+			method: "REPORT", path: "/caldav/calendars/user1/courses/",
+			body:   `<d:sync-collection xmlns:d="DAV:"/>`,
+			status: http.StatusForbidden,
+			want:   []string{"<d:supported-report/>"},
+		},
+		{
+			name:   "Get calendar",
+			method: http.MethodGet, path: "/caldav/calendars/user1/courses/",
+			status: http.StatusOK,
+			want:   []string{"BEGIN:VCALENDAR", "】 Early", "】 Late"},
+		},
+		{name: "Calendar updating", method: "PROPFIND", path: "/caldav/calendars/user1/exams/", depth: "1", status: http.StatusServiceUnavailable},
+		{name: "Other account", method: "PROPFIND", path: "/caldav/calendars/user2/", status: http.StatusForbidden},
+		{name: "Unknown calendar", method: "PROPFIND", path: "/caldav/calendars/user1/unknown/", status: http.StatusNotFound},
+		{name: "Read only", method: http.MethodPut, path: "/caldav/calendars/user1/courses/a.ics", status: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.path, tt.depth, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("response missing %q in\n%s", want, w.Body.String())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(w.Body.String(), notWant) {
+					t.Errorf("response should not contain %q in\n%s", notWant, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCalDAVServer_GetObject(t *testing.T) {
+	server := newTestCalDAVServer()
+	data, err := server.load(context.Background(), "user1", &server.collections[0])
+	if err != nil || len(data.objects) != 2 {
+		t.Fatalf("load() = %v, %v", data, err)
+	}
+	object := data.objects[0]
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/caldav/calendars/user1/courses/"+object.name, nil)
+		r.SetBasicAuth("", "token")
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		server.ServeDAV(w, r)
+		return w
+	}
+	w := get(nil)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != object.etag || strings.Count(w.Body.String(), "BEGIN:VEVENT") != 1 {
+		t.Fatalf("expected a single event with ETag %s, got %d %v\n%s", object.etag, w.Code, w.Header(), w.Body.String())
+	}
+	if w := get(http.Header{"If-None-Match": {object.etag}}); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 with If-None-Match, got %d", w.Code)
+	}
+	// DTSTAMP 变化时 ETag 保持不变
+	again, _ := server.load(context.Background(), "user1", &server.collections[0])
+	if again.objects[0].etag != object.etag || again.ctag != data.ctag {
+		t.Errorf("ETag should be stable across renders")
+	}
+}
+
+func TestCalDAVServer_Authentication(t *testing.T) {
+	server := newTestCalDAVServer()
+	r := httptest.NewRequest("PROPFIND", "/caldav/", nil)
+	w := httptest.NewRecorder()
+	server.ServeDAV(w, r)
+	if w.Code != http.StatusUnauthorized || !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Basic") {
+		t.Errorf("expected 401 with a Basic challenge, got %d %v", w.Code, w.Header())
+	}
+	r = httptest.NewRequest("PROPFIND", "/caldav/", nil)
+	r.SetBasicAuth("user1", "wrong")
+	w = httptest.NewRecorder()
+	server.ServeDAV(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong token, got %d", w.Code)
+	}
+	r = httptest.NewRequest(http.MethodOptions, "/caldav/", nil)
+	w = httptest.NewRecorder()
+	server.ServeDAV(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("DAV"), "calendar-access") {
+		t.Errorf("expected OPTIONS to advertise calendar-access, got %d %v", w.Code, w.Header())
+	}
+	r = httptest.NewRequest(http.MethodGet, "/.well-known/caldav", nil)
+	w = httptest.NewRecorder()
+	server.WellKnown(w, r)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != CalDAVRoot {
+		t.Errorf("expected redirect to %s, got %d %v", CalDAVRoot, w.Code, w.Header())
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"cached_proxy/cache"
+	"cached_proxy/feign"
+	"cached_proxy/icalendar"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// AllCalendarGetter 输出课程和考试合并后的日历
+type AllCalendarGetter struct {
+	TokenService
+	courseService   cache.InformationService[feign.CourseList]
+	examService     cache.InformationService[feign.ExamList]
+	calendarService cache.InformationService[feign.TeachingCalendar]
+}
+
+var (
+	AllCalendarHandler = &AllCalendarGetter{
+		courseService:   StudentCourseService,
+		examService:     StudentExamService,
+		calendarService: CalendarService,
+	}
+)
+
+// render 生成合并后的日历，课程或考试的数据过期时标记为正在更新
+func (c *AllCalendarGetter) render(ctx context.Context, accountID string, query url.Values) (*renderedCalendar, error) {
+	options, err := getCalendarOptions(accountID).ApplyQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCalendarOptions, err)
+	}
+	result := &renderedCalendar{}
+	courses, err := c.courseService.GetInfoContext(ctx, accountID)
+	if err != nil {
+		result.updating = true
+	}
+	exams, err := c.examService.GetInfoContext(ctx, accountID)
+	if err != nil {
+		result.updating = true
+	}
+	calendar, err := c.calendarService.GetInfoContext(ctx, accountID)
+	if err != nil {
+		result.updating = true
+	}
+	if calendar == nil || courses == nil || exams == nil {
+		return nil, errCalendarUpdating
+	}
+	result.weekErrors = len(weekErrors(courses, calendar))
+	return result, result.publish("all", accountID, AllConvertCalendar(accountID, courses, exams, calendar, &options))
+}
+
+func (c *AllCalendarGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
+	serveCalendar(w, r, &c.TokenService, c)
+}
+
+// AllConvertCalendar 将课程和考试合并为一个日历，事件通过 CATEGORIES 和 COLOR 区分，可选地添加教学周的全天事件
+func AllConvertCalendar(studentID string, courses *feign.CourseList, exams *feign.ExamList, calendar *feign.TeachingCalendar, options *CalendarOptions) icalendar.Calendar {
+	if calendar == nil {
+		return nil
+	}
+	ical := &icalendar.IcsCalendar{}
+	ical.SetProductID(ProdID)
+	ical.SetName(AllCalendarName)
+	ical.SetTimezone(icalendar.GetDefaultTimezone())
+	if c := CoursesConvertCalendar(studentID, courses, calendar, options); c != nil {
+		ical.Merge(c)
+	}
+	if c := ExamsConvertCalendar(studentID, exams, calendar, options); c != nil {
+		ical.Merge(c)
+	}
+	if options.WeekMarkers {
+		for _, event := range weekMarkerEvents(studentID, calendar) {
+			ical.AddEvent(event)
+		}
+	}
+	return ical
+}
+
+// weekMarkerEvents 为每个教学周生成一个从周一到周日的全天事件，例如 "第3周"
+func weekMarkerEvents(studentID string, calendar *feign.TeachingCalendar) []icalendar.Event {
+	var events []icalendar.Event
+	for week := 1; week <= calendar.Weeks; week++ {
+		event := &icalendar.IcsEvent{}
+		event.SetSummary(fmt.Sprintf("第%d周", week))
+		event.SetAllDay(true)
+		event.SetStart(calendar.DateOf(week, 1))
+		// 全天事件的 DTEND 不包含在事件内，因此是下一周的周一
+		event.SetEnd(calendar.DateOf(week+1, 1))
+		event.SetCategories(WeekCategory)
+		event.SetColor(WeekMarkerColor)
+		// 教学周标记不占用时间，不影响忙闲状态
+		event.SetTransparency(icalendar.TRANSPARENT)
+		event.SetUID(eventUID(studentID, "week", calendar.TermId, strconv.Itoa(week)))
+		events = append(events, event)
+	}
+	return events
+}
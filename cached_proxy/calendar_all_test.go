@@ -0,0 +1,65 @@
+package main
+
+import (
+	"cached_proxy/feign"
+	"strings"
+	"testing"
+)
+
+func TestAllConvertCalendar(t *testing.T) {
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	courses := &feign.CourseList{Courses: []feign.Course{
+		{Name: "高等数学", Teacher: "张三", Classroom: "A101", Weeks: "1-8", StartTime: 1, Duration: 2, Day: "Monday"},
+	}}
+	exams := &feign.ExamList{Exams: []feign.Examination{
+		{Name: "高等数学", Type: "期末", Location: "A101", StartTime: "2025-06-20 09:00:00", EndTime: "2025-06-20 11:00:00"},
+	}}
+	tests := []struct {
+		name        string
+		options     CalendarOptions
+		events      int
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:   "courses and exams",
+			events: 2,
+			contains: []string{
+				"X-WR-CALNAME:" + AllCalendarName, "X-WR-TIMEZONE:Asia/Shanghai",
+				"CATEGORIES:" + CourseCategory, "COLOR:" + CourseColor,
+				"CATEGORIES:" + ExamCategory, "COLOR:" + ExamColor,
+				"DTSTART;TZID=Asia/Shanghai:20250620T090000",
+			},
+			notContains: []string{"CATEGORIES:" + WeekCategory},
+		},
+		{
+			name:    "week markers",
+			options: CalendarOptions{WeekMarkers: true},
+			events:  2 + 18,
+			contains: []string{
+				"SUMMARY:第3周\r\nCATEGORIES:" + WeekCategory,
+				"TRANSP:TRANSPARENT",
+				"DTSTART;VALUE=DATE:20250303\r\nDTEND;VALUE=DATE:20250310",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AllConvertCalendar("student", courses, exams, calendar, &tt.options)
+			if len(got.GetEvents()) != tt.events {
+				t.Errorf("AllConvertCalendar() has %d events, want %d", len(got.GetEvents()), tt.events)
+			}
+			ics := got.ToIcs(nil)
+			for _, want := range tt.contains {
+				if !strings.Contains(ics, want) {
+					t.Errorf("AllConvertCalendar() missing %q", want)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if strings.Contains(ics, unwanted) {
+					t.Errorf("AllConvertCalendar() should not contain %q", unwanted)
+				}
+			}
+		})
+	}
+}
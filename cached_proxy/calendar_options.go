@@ -0,0 +1,254 @@
+package main
+
+import (
+	"cached_proxy/feign"
+	"cached_proxy/icalendar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CalendarOptions 是日历导出的选项，可以按账户保存，也可以通过请求参数临时覆盖
+type CalendarOptions struct {
+	CourseAlarms []int    `json:"course_alarms"` // 课程提醒，上课前多少分钟，为 nil 时使用默认值
+	ExamAlarms   []int    `json:"exam_alarms"`   // 考试提醒，考试前多少分钟，为 nil 时使用默认值
+	NoAlarms     bool     `json:"no_alarms"`     // 是否关闭所有提醒
+	CourseTitle  string   `json:"course_title"`  // 课程标题模板，支持 {name} {teacher} {classroom}
+	ExamTitle    string   `json:"exam_title"`    // 考试标题模板，支持 {name} {type} {location}
+	HideTeacher  bool     `json:"hide_teacher"`  // 描述中是否隐藏授课教师
+	HideWeeks    bool     `json:"hide_weeks"`    // 描述中是否隐藏周次
+	Exclude      []string `json:"exclude"`       // 不导出的课程或考试名称
+	WeekMarkers  bool     `json:"week_markers"`  // 是否添加标记教学周的全天事件，仅用于合并的日历
+}
+
+// validate 校验选项是否合法，选项会保存下来，因此限制提醒和排除的数量以及标题的长度
+func (o *CalendarOptions) validate() error {
+	for _, alarms := range [][]int{o.CourseAlarms, o.ExamAlarms} {
+		if len(alarms) > MaxAlarms {
+			return fmt.Errorf("at most %d alarms are allowed", MaxAlarms)
+		}
+		for _, minutes := range alarms {
+			if minutes < 0 || minutes > MaxAlarmMinutes {
+				return fmt.Errorf("alarm offset must be between 0 and %d minutes", MaxAlarmMinutes)
+			}
+		}
+	}
+	if len(o.Exclude) > MaxExcludes {
+		return fmt.Errorf("at most %d excluded names are allowed", MaxExcludes)
+	}
+	for _, text := range append([]string{o.CourseTitle, o.ExamTitle}, o.Exclude...) {
+		if utf8.RuneCountInString(text) > MaxTitleLength {
+			return fmt.Errorf("titles and excluded names must be at most %d characters", MaxTitleLength)
+		}
+	}
+	return nil
+}
+
+// courseAlarms 返回课程事件的提醒
+func (o *CalendarOptions) courseAlarms() []icalendar.Alarm {
+	if o.NoAlarms {
+		return nil
+	}
+	if o.CourseAlarms == nil {
+		return newAlarms(DefaultCourseAlarms, "距离上课仅剩")
+	}
+	return newAlarms(o.CourseAlarms, "距离上课仅剩")
+}
+
+// examAlarms 返回考试事件的提醒
+func (o *CalendarOptions) examAlarms() []icalendar.Alarm {
+	if o.NoAlarms {
+		return nil
+	}
+	if o.ExamAlarms == nil {
+		return newAlarms(DefaultExamAlarms, "距离考试仅剩")
+	}
+	return newAlarms(o.ExamAlarms, "距离考试仅剩")
+}
+
+// courseTitle 根据模板生成课程事件的标题
+func (o *CalendarOptions) courseTitle(course feign.Course) string {
+	title := o.CourseTitle
+	if title == "" {
+		title = DefaultCourseTitle
+	}
+	return strings.NewReplacer(
+		"{name}", course.Name,
+		"{teacher}", course.Teacher,
+		"{classroom}", course.Classroom,
+	).Replace(title)
+}
+
+// examTitle 根据模板生成考试事件的标题
+func (o *CalendarOptions) examTitle(exam feign.Examination) string {
+	title := o.ExamTitle
+	if title == "" {
+		title = DefaultExamTitle
+	}
+	return strings.NewReplacer(
+		"{name}", exam.Name,
+		"{type}", exam.Type,
+		"{location}", exam.Location,
+	).Replace(title)
+}
+
+// excluded 判断课程或考试是否不需要导出
+func (o *CalendarOptions) excluded(name string) bool {
+	for _, exclude := range o.Exclude {
+		if strings.TrimSpace(exclude) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newAlarms 根据提前的分钟数创建提醒
+func newAlarms(offsets []int, prefix string) []icalendar.Alarm {
+	alarms := make([]icalendar.Alarm, 0, len(offsets))
+	for _, minutes := range offsets {
+		var desc string
+		switch {
+		case minutes > 0 && minutes%(24*60) == 0:
+			desc = fmt.Sprintf("%s%d天", prefix, minutes/(24*60))
+		case minutes > 0 && minutes%60 == 0:
+			desc = fmt.Sprintf("%s%d小时", prefix, minutes/60)
+		default:
+			desc = fmt.Sprintf("%s%d分钟", prefix, minutes)
+		}
+		// 提醒在事件开始之前触发，因此 TRIGGER 为负数
+		alarms = append(alarms, icalendar.NewIcsAlarm(icalendar.DISPLAY, -time.Duration(minutes)*time.Minute, desc))
+	}
+	return alarms
+}
+
+// parseMinutes 解析以逗号分隔的分钟数，空字符串表示没有提醒
+func parseMinutes(value string) ([]int, error) {
+	minutes := []int{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		m, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alarm offset %q", part)
+		}
+		minutes = append(minutes, m)
+	}
+	return minutes, nil
+}
+
+// ApplyQuery 使用请求参数覆盖选项，未出现的参数保持原值
+func (o CalendarOptions) ApplyQuery(query url.Values) (CalendarOptions, error) {
+	var err error
+	if query.Has("course_alarms") {
+		if o.CourseAlarms, err = parseMinutes(query.Get("course_alarms")); err != nil {
+			return o, err
+		}
+	}
+	if query.Has("exam_alarms") {
+		if o.ExamAlarms, err = parseMinutes(query.Get("exam_alarms")); err != nil {
+			return o, err
+		}
+	}
+	for key, field := range map[string]*bool{
+		"no_alarms":    &o.NoAlarms,
+		"hide_teacher": &o.HideTeacher,
+		"hide_weeks":   &o.HideWeeks,
+		"week_markers": &o.WeekMarkers,
+	} {
+		if query.Has(key) {
+			if *field, err = strconv.ParseBool(query.Get(key)); err != nil {
+				return o, fmt.Errorf("invalid %s %q", key, query.Get(key))
+			}
+		}
+	}
+	if query.Has("course_title") {
+		o.CourseTitle = query.Get("course_title")
+	}
+	if query.Has("exam_title") {
+		o.ExamTitle = query.Get("exam_title")
+	}
+	if query.Has("exclude") {
+		o.Exclude = nil
+		for _, value := range query["exclude"] {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					o.Exclude = append(o.Exclude, name)
+				}
+			}
+		}
+	}
+	return o, o.validate()
+}
+
+// CalendarOptionsGetter 管理账户保存的日历导出选项，GET 获取，PUT 保存
+type CalendarOptionsGetter struct {
+	TokenService
+}
+
+var (
+	CalendarOptionsHandler = &CalendarOptionsGetter{TokenService: TokenService{acc: AccountService}}
+)
+
+// getCalendarOptions 获取账户保存的选项，未保存时返回默认选项
+func getCalendarOptions(accountID string) CalendarOptions {
+	options, _ := CalendarOptionsRepository.Get(accountID)
+	return options
+}
+
+// decodeCalendarOptions 读取并校验请求体中的选项，请求体最多 MaxCalendarOptionsSize 字节
+func decodeCalendarOptions(w http.ResponseWriter, r *http.Request) (CalendarOptions, error) {
+	var options CalendarOptions
+	var tooLarge *http.MaxBytesError
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxCalendarOptionsSize)).Decode(&options)
+	switch {
+	case errors.As(err, &tooLarge):
+		return options, errRequestTooLarge
+	case err != nil:
+		return options, badRequest("invalid calendar options")
+	}
+	if err := options.validate(); err != nil {
+		return options, badRequest(err.Error())
+	}
+	return options, nil
+}
+
+func (c *CalendarOptionsGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
+	account := c.checkToken(w, r)
+	if account == nil {
+		return
+	}
+	var options CalendarOptions
+	switch r.Method {
+	case http.MethodGet:
+		options = getCalendarOptions(account.AccountID())
+	case http.MethodPut:
+		var err error
+		if options, err = decodeCalendarOptions(w, r); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		CalendarOptionsRepository.Set(account.AccountID(), options)
+	default:
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	resp := feign.CommonResponse[any]{
+		Code:    1,
+		Message: "success",
+		Data:    options,
+	}
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode calendar options", "error", err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"cached_proxy/feign"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCalendarOptions_ApplyQuery(t *testing.T) {
+	saved := CalendarOptions{CourseAlarms: []int{10}, CourseTitle: "{name}", Exclude: []string{"体育"}}
+	tests := []struct {
+		name    string
+		query   string
+		want    CalendarOptions
+		wantErr bool
+	}{
+		{"empty query keeps saved options", "", saved, false},
+		{"override alarms", "course_alarms=5,15&exam_alarms=60",
+			CalendarOptions{CourseAlarms: []int{5, 15}, ExamAlarms: []int{60}, CourseTitle: "{name}", Exclude: []string{"体育"}}, false},
+		{"empty alarms", "course_alarms=",
+			CalendarOptions{CourseAlarms: []int{}, CourseTitle: "{name}", Exclude: []string{"体育"}}, false},
+		{"flags and title", "no_alarms=true&hide_teacher=1&hide_weeks=true&course_title={name}@{classroom}",
+			CalendarOptions{CourseAlarms: []int{10}, NoAlarms: true, CourseTitle: "{name}@{classroom}", HideTeacher: true, HideWeeks: true, Exclude: []string{"体育"}}, false},
+		{"override exclude", "exclude=高等数学,大学英语&exclude=线性代数",
+			CalendarOptions{CourseAlarms: []int{10}, CourseTitle: "{name}", Exclude: []string{"高等数学", "大学英语", "线性代数"}}, false},
+		{"invalid alarm", "course_alarms=abc", CalendarOptions{}, true},
+		{"negative alarm", "course_alarms=-5", CalendarOptions{}, true},
+		{"invalid flag", "no_alarms=maybe", CalendarOptions{}, true},
+		{"too many alarms", "course_alarms=1,2,3,4,5,6,7,8,9,10,11", CalendarOptions{}, true},
+		{"long title", "course_title=" + strings.Repeat("课", MaxTitleLength+1), CalendarOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			got, err := saved.ApplyQuery(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCalendarOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid", `{"course_alarms":[10],"course_title":"{name}","exclude":["体育"]}`, 0},
+		{"malformed", `{"course_alarms":`, http.StatusBadRequest},
+		{"invalid alarm", `{"exam_alarms":[-1]}`, http.StatusBadRequest},
+		{"too many excludes", `{"exclude":["` + strings.Repeat(`体育","`, MaxExcludes) + `体育"]}`, http.StatusBadRequest},
+		{"too large", `{"course_title":"` + strings.Repeat("a", MaxCalendarOptionsSize) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/calendar/options", strings.NewReader(tt.body))
+			_, err := decodeCalendarOptions(httptest.NewRecorder(), r)
+			var httpErr *HTTPError
+			switch {
+			case tt.wantStatus == 0 && err != nil:
+				t.Errorf("decodeCalendarOptions() error = %v", err)
+			case tt.wantStatus != 0 && (!errors.As(err, &httpErr) || httpErr.Status != tt.wantStatus):
+				t.Errorf("decodeCalendarOptions() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCalendarOptions_Alarms(t *testing.T) {
+	ics := newAlarms(DefaultExamAlarms, "距离考试仅剩")[1].ToIcs(nil)
+	if !strings.Contains(ics, "TRIGGER:-P1D") || !strings.Contains(ics, "距离考试仅剩1天") {
+		t.Errorf("alarm should trigger one day before the exam:\n%s", ics)
+	}
+	if alarms := (&CalendarOptions{NoAlarms: true, CourseAlarms: []int{10}}).courseAlarms(); len(alarms) != 0 {
+		t.Errorf("courseAlarms() = %v, want none", alarms)
+	}
+	if alarms := (&CalendarOptions{}).examAlarms(); len(alarms) != len(DefaultExamAlarms) {
+		t.Errorf("examAlarms() should fall back to the defaults, got %d alarms", len(alarms))
+	}
+}
+
+func TestCoursesConvertCalendar_Options(t *testing.T) {
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	list := &feign.CourseList{Courses: []feign.Course{
+		{Name: "高等数学", Teacher: "张三", Classroom: "A101", Weeks: "1-8", StartTime: 1, Duration: 2, Day: "Monday"},
+		{Name: "体育", Teacher: "李四", Classroom: "操场", Weeks: "1-8", StartTime: 3, Duration: 2, Day: "Monday"},
+	}}
+	options := &CalendarOptions{
+		CourseTitle: "{name}@{classroom}",
+		HideTeacher: true,
+		NoAlarms:    true,
+		Exclude:     []string{"体育"},
+	}
+	ics := coursesConvertCalendar("student", list, calendar, nil, options).ToIcs(nil)
+	if !strings.Contains(ics, "SUMMARY:高等数学@A101") {
+		t.Errorf("summary should follow the title template:\n%s", ics)
+	}
+	for _, unwanted := range []string{"体育", "张三", "BEGIN:VALARM"} {
+		if strings.Contains(ics, unwanted) {
+			t.Errorf("calendar should not contain %q:\n%s", unwanted, ics)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"cached_proxy/icalendar"
+	"cached_proxy/repo"
+	"sync"
+	"time"
+)
+
+// CalendarVersions 记录每个用户每种日历上一次下发的事件版本，用于在事件变化时递增 SEQUENCE
+type CalendarVersions struct {
+	repo repo.KVRepo[string, CalendarVersion]
+	mu   sync.Mutex
+}
+
+// CalendarVersion 是一个日历上一次下发的版本
+type CalendarVersion struct {
+	Events   map[string]icalendar.EventVersion // 每个事件的版本，键为 UID
+	Modified time.Time                         // 事件集合最后一次变化的时间，包括事件被删除
+}
+
+// NewCalendarVersions 创建日历版本记录
+func NewCalendarVersions(repository repo.KVRepo[string, CalendarVersion]) *CalendarVersions {
+	return &CalendarVersions{repo: repository}
+}
+
+// Apply 对比上一次下发的版本，为发生变化的事件设置 SEQUENCE 和 LAST-MODIFIED，并保存本次下发的版本，
+// 返回日历的最后修改时间
+//
+// 事件被删除时其余事件的 LAST-MODIFIED 不变，因此日历的修改时间单独记录，事件新增、修改或删除时都会更新，
+// 只发送 If-Modified-Since 的客户端也能得知事件被删除。
+func (c *CalendarVersions) Apply(feed string, studentID string, calendar icalendar.Calendar) time.Time {
+	key := feed + ":" + studentID
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, _ := c.repo.Get(key)
+	now := time.Now()
+	current := icalendar.ApplyVersions(calendar, previous.Events, now)
+	if sameVersions(previous.Events, current) {
+		return previous.Modified
+	}
+	// Last-Modified 精确到秒，同一秒内的再次变化也需要让客户端看到更晚的时间
+	modified := now
+	if last := previous.Modified.Truncate(time.Second); !modified.Truncate(time.Second).After(last) && !previous.Modified.IsZero() {
+		modified = last.Add(time.Second)
+	}
+	c.repo.Set(key, CalendarVersion{Events: current, Modified: modified})
+	return modified
+}
+
+// sameVersions 判断两次下发的版本是否一致，一致时无需写回存储
+func sameVersions(a, b map[string]icalendar.EventVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for uid, version := range a {
+		other, found := b[uid]
+		if !found || other.Fingerprint != version.Fingerprint || other.Sequence != version.Sequence ||
+			!other.LastModified.Equal(version.LastModified) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"cached_proxy/feign"
+	"cached_proxy/repo"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarVersions_Apply(t *testing.T) {
+	versions := NewCalendarVersions(repo.NewMemRepo[string, CalendarVersion]())
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	course := feign.Course{Name: "Test", Teacher: "Test", Classroom: "A101", Weeks: "1-8", StartTime: 1, Duration: 2, Day: "Monday"}
+	serve := func(course feign.Course) string {
+		ical := coursesConvertCalendar("student", &feign.CourseList{Courses: []feign.Course{course}}, calendar, nil, &CalendarOptions{})
+		versions.Apply("courses", "student", ical)
+		return ical.ToIcs(nil)
+	}
+
+	if ics := serve(course); strings.Contains(ics, "SEQUENCE:") {
+		t.Errorf("first version should not contain SEQUENCE:\n%s", ics)
+	}
+	if ics := serve(course); strings.Contains(ics, "SEQUENCE:") {
+		t.Errorf("unchanged version should not contain SEQUENCE:\n%s", ics)
+	}
+	course.Classroom = "B202"
+	if ics := serve(course); !strings.Contains(ics, "SEQUENCE:1\r\n") {
+		t.Errorf("changed version should contain SEQUENCE:1:\n%s", ics)
+	}
+}
+
+func TestCalendarVersions_AlternatingOptions(t *testing.T) {
+	versions := NewCalendarVersions(repo.NewMemRepo[string, CalendarVersion]())
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	courses := &feign.CourseList{Courses: []feign.Course{
+		{Name: "Test", Teacher: "Test", Classroom: "A101", Weeks: "1-8", StartTime: 1, Duration: 2, Day: "Monday"},
+	}}
+	// 订阅地址使用保存的选项，下载时用查询参数覆盖提醒和标题
+	optionSets := []CalendarOptions{
+		{},
+		{CourseAlarms: []int{10, 60}, CourseTitle: "{name} @ {classroom}", HideTeacher: true},
+	}
+	for i := 0; i < 6; i++ {
+		ical := coursesConvertCalendar("student", courses, calendar, nil, &optionSets[i%2])
+		versions.Apply("courses", "student", ical)
+		if ics := ical.ToIcs(nil); strings.Contains(ics, "SEQUENCE:") {
+			t.Fatalf("request %d: alternating options should keep SEQUENCE at 0:\n%s", i, ics)
+		}
+	}
+}
+
+func TestCalendarVersions_Removal(t *testing.T) {
+	versions := NewCalendarVersions(repo.NewMemRepo[string, CalendarVersion]())
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	courses := []feign.Course{
+		{Name: "Test", Teacher: "Test", Classroom: "A101", Weeks: "1-8", StartTime: 1, Duration: 2, Day: "Monday"},
+		{Name: "Dropped", Teacher: "Test", Classroom: "A102", Weeks: "1-8", StartTime: 3, Duration: 2, Day: "Tuesday"},
+	}
+	apply := func(options CalendarOptions) time.Time {
+		ical := coursesConvertCalendar("student", &feign.CourseList{Courses: courses}, calendar, nil, &options)
+		return versions.Apply("courses", "student", ical)
+	}
+
+	first := apply(CalendarOptions{})
+	if first.IsZero() || !apply(CalendarOptions{}).Equal(first) {
+		t.Fatalf("unchanged calendar should keep its modification time %v", first)
+	}
+	// 排除一门课程后，剩余事件的 LAST-MODIFIED 不变，但日历的修改时间需要更新
+	if removed := apply(CalendarOptions{Exclude: []string{"Dropped"}}); removed.Truncate(time.Second).Compare(first.Truncate(time.Second)) <= 0 {
+		t.Errorf("removing an event should advance the modification time, got %v after %v", removed, first)
+	}
+}
@@ -0,0 +1,94 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// CBORContentType 是 CBOR 的媒体类型
+const CBORContentType = "application/cbor"
+
+// CBOR 的主类型，参见 RFC 8949 3.1 节
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborSimple   = 7
+)
+
+// MarshalCBOR 将值编码为 CBOR，长度和整数使用最短的编码
+func MarshalCBOR(v any) ([]byte, error) {
+	e := &cborEncoder{}
+	if err := marshal(v, e); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type cborEncoder struct {
+	buf bytes.Buffer
+}
+
+// head 写入数据项的头部，包括主类型和参数
+func (e *cborEncoder) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		e.buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		e.buf.Write([]byte{major<<5 | 24, byte(n)})
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(major<<5 | 25)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		e.buf.WriteByte(major<<5 | 26)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		e.buf.WriteByte(major<<5 | 27)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func (e *cborEncoder) null() {
+	e.buf.WriteByte(0xf6)
+}
+
+func (e *cborEncoder) boolean(b bool) {
+	if b {
+		e.buf.WriteByte(0xf5)
+		return
+	}
+	e.buf.WriteByte(0xf4)
+}
+
+func (e *cborEncoder) integer(n int64) {
+	if n < 0 {
+		e.head(cborNegative, uint64(-1-n))
+		return
+	}
+	e.head(cborUnsigned, uint64(n))
+}
+
+func (e *cborEncoder) unsigned(n uint64) {
+	e.head(cborUnsigned, n)
+}
+
+func (e *cborEncoder) float(f float64) {
+	e.buf.WriteByte(cborSimple<<5 | 27)
+	e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (e *cborEncoder) str(s string) {
+	e.head(cborText, uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *cborEncoder) array(n int) {
+	e.head(cborArray, uint64(n))
+}
+
+func (e *cborEncoder) object(n int) {
+	e.head(cborMap, uint64(n))
+}
@@ -0,0 +1,42 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// 测试用例来自 RFC 8949 附录 A
+func TestMarshalCBOR(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []byte
+	}{
+		{"Null", nil, []byte{0xf6}},
+		{"Bool", []bool{false, true}, []byte{0x82, 0xf4, 0xf5}},
+		{"Small", 23, []byte{0x17}},
+		{"Uint8", 24, []byte{0x18, 0x18}},
+		{"Uint16", 1000, []byte{0x19, 0x03, 0xe8}},
+		{"Uint32", 1000000, []byte{0x1a, 0x00, 0x0f, 0x42, 0x40}},
+		{"Uint64", uint64(18446744073709551615), []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"Negative", -1, []byte{0x20}},
+		{"Negative16", -1000, []byte{0x39, 0x03, 0xe7}},
+		{"Float", 1.1, []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+		{"Text", "水", []byte{0x63, 0xe6, 0xb0, 0xb4}},
+		{"Text8", strings.Repeat("a", 24), append([]byte{0x78, 24}, strings.Repeat("a", 24)...)},
+		{"Array", []any{1, []int{2, 3}}, []byte{0x82, 0x01, 0x82, 0x02, 0x03}},
+		{"Map", map[string]any{"b": []int{2, 3}, "a": 1}, []byte{0xa2, 0x61, 0x61, 0x01, 0x61, 0x62, 0x82, 0x02, 0x03}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalCBOR(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("MarshalCBOR(%v) = % x, want % x", tt.value, got, tt.want)
+			}
+		})
+	}
+}
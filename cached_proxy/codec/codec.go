@@ -0,0 +1,93 @@
+// Package codec 将可以编码为 JSON 的值编码为 MessagePack 和 CBOR
+//
+// 值先按照 JSON 编码，再转换为对应的二进制格式，因此字段名和省略规则与 JSON 的 tag 保持一致。
+// map 的键按照字节序排序，相同的值总是得到相同的编码。
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// encoder 将 JSON 的值写入二进制格式
+type encoder interface {
+	null()
+	boolean(b bool)
+	integer(n int64)
+	unsigned(n uint64)
+	float(f float64)
+	str(s string)
+	array(n int)
+	object(n int)
+}
+
+// normalize 将值转换为 JSON 解码得到的 nil、bool、json.Number、string、[]any 和 map[string]any
+func normalize(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err = decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func marshal(v any, e encoder) error {
+	value, err := normalize(v)
+	if err != nil {
+		return err
+	}
+	return encode(value, e)
+}
+
+func encode(value any, e encoder) error {
+	switch v := value.(type) {
+	case nil:
+		e.null()
+	case bool:
+		e.boolean(v)
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			e.integer(n)
+		} else if n, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			e.unsigned(n)
+		} else if f, err := v.Float64(); err == nil && !math.IsInf(f, 0) {
+			e.float(f)
+		} else {
+			return fmt.Errorf("invalid number %s", v)
+		}
+	case string:
+		e.str(v)
+	case []any:
+		e.array(len(v))
+		for _, item := range v {
+			if err := encode(item, e); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		e.object(len(v))
+		for _, key := range keys {
+			e.str(key)
+			if err := encode(v[key], e); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", value)
+	}
+	return nil
+}
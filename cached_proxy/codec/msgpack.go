@@ -0,0 +1,107 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// MsgpackContentType 是 MessagePack 的媒体类型
+const MsgpackContentType = "application/msgpack"
+
+// MarshalMsgpack 将值编码为 MessagePack，整数使用最短的编码
+func MarshalMsgpack(v any) ([]byte, error) {
+	e := &msgpackEncoder{}
+	if err := marshal(v, e); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type msgpackEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *msgpackEncoder) null() {
+	e.buf.WriteByte(0xc0)
+}
+
+func (e *msgpackEncoder) boolean(b bool) {
+	if b {
+		e.buf.WriteByte(0xc3)
+		return
+	}
+	e.buf.WriteByte(0xc2)
+}
+
+func (e *msgpackEncoder) integer(n int64) {
+	switch {
+	case n >= 0:
+		e.unsigned(uint64(n))
+	case n >= -32:
+		e.buf.WriteByte(byte(n))
+	case n >= math.MinInt8:
+		e.buf.Write([]byte{0xd0, byte(n)})
+	case n >= math.MinInt16:
+		e.buf.WriteByte(0xd1)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n >= math.MinInt32:
+		e.buf.WriteByte(0xd2)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		e.buf.WriteByte(0xd3)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(n)))
+	}
+}
+
+func (e *msgpackEncoder) unsigned(n uint64) {
+	switch {
+	case n <= 0x7f:
+		e.buf.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		e.buf.Write([]byte{0xcc, byte(n)})
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xcd)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		e.buf.WriteByte(0xce)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		e.buf.WriteByte(0xcf)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func (e *msgpackEncoder) float(f float64) {
+	e.buf.WriteByte(0xcb)
+	e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (e *msgpackEncoder) str(s string) {
+	e.length(len(s), 0xa0, 32, 0xd9, 0xda, 0xdb)
+	e.buf.WriteString(s)
+}
+
+func (e *msgpackEncoder) array(n int) {
+	e.length(n, 0x90, 16, 0, 0xdc, 0xdd)
+}
+
+func (e *msgpackEncoder) object(n int) {
+	e.length(n, 0x80, 16, 0, 0xde, 0xdf)
+}
+
+// length 写入字符串、数组和 map 的长度，fix 为短格式的前缀，没有 8 位长度格式时 len8 为 0
+func (e *msgpackEncoder) length(n int, fix byte, fixLimit int, len8, len16, len32 byte) {
+	switch {
+	case n < fixLimit:
+		e.buf.WriteByte(fix | byte(n))
+	case len8 != 0 && n <= math.MaxUint8:
+		e.buf.Write([]byte{len8, byte(n)})
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(len16)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.buf.WriteByte(len32)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
@@ -0,0 +1,51 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarshalMsgpack(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []byte
+	}{
+		{"Nil", nil, []byte{0xc0}},
+		{"Bool", []bool{true, false}, []byte{0x92, 0xc3, 0xc2}},
+		{"PositiveFixint", 127, []byte{0x7f}},
+		{"Uint8", 200, []byte{0xcc, 0xc8}},
+		{"Uint16", 1000, []byte{0xcd, 0x03, 0xe8}},
+		{"Uint32", 100000, []byte{0xce, 0x00, 0x01, 0x86, 0xa0}},
+		{"Uint64", uint64(1) << 63, []byte{0xcf, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{"NegativeFixint", -32, []byte{0xe0}},
+		{"Int8", -100, []byte{0xd0, 0x9c}},
+		{"Int16", -1000, []byte{0xd1, 0xfc, 0x18}},
+		{"Int32", -100000, []byte{0xd2, 0xff, 0xfe, 0x79, 0x60}},
+		{"Float", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"FixStr", "成绩", []byte{0xa6, 0xe6, 0x88, 0x90, 0xe7, 0xbb, 0xa9}},
+		{"Str8", strings.Repeat("a", 32), append([]byte{0xd9, 32}, strings.Repeat("a", 32)...)},
+		{"Array16", make([]int, 16), append([]byte{0xdc, 0, 16}, make([]byte, 16)...)},
+		{"Struct", struct {
+			Name  string `json:"name"`
+			Code  int    `json:"code"`
+			Empty string `json:"empty,omitempty"`
+		}{Name: "a", Code: 1}, []byte{0x82, 0xa4, 'c', 'o', 'd', 'e', 0x01, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'a'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalMsgpack(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("MarshalMsgpack(%v) = % x, want % x", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := MarshalMsgpack(func() {}); err == nil {
+		t.Error("expected error for unsupported value")
+	}
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
-	"cached_proxy/icalendar"
+	"cached_proxy/executor"
+	"cached_proxy/feign"
+	"cached_proxy/ratelimit"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,18 +16,50 @@ var (
 	SpiderUrl = os.Getenv("SPIDER_URL")
 )
 
-// 日历事件的默认提醒
+// HolidayDir 节假日和调休安排的配置目录，每个学期一个 <term_id>.json 文件，通过环境变量 HOLIDAY_DIR 设置
 var (
-	// DefaultCourseAlarms 课程事件的默认提醒
-	DefaultCourseAlarms = []icalendar.Alarm{
-		icalendar.NewIcsAlarm(icalendar.DISPLAY, 28*time.Minute, "距离上课仅剩28分钟"),
+	HolidayDir = getEnv("HOLIDAY_DIR", "./_data/holidays")
+)
+
+// CalendarDomain 日历事件 UID 的域名后缀，通过环境变量 CALENDAR_DOMAIN 设置
+var (
+	CalendarDomain = getEnv("CALENDAR_DOMAIN", "gonggong.sky31studio")
+)
+
+var (
+	// PublicUrl 服务对外访问的地址，用于生成订阅链接，通过环境变量 PUBLIC_URL 设置
+	PublicUrl = os.Getenv("PUBLIC_URL")
+	// AllowedHosts 未设置 PublicUrl 时允许根据请求推断订阅链接的 Host，包含端口，多个以逗号分隔，通过环境变量 ALLOWED_HOSTS 设置
+	//
+	// 两者都未设置时不生成订阅链接，避免伪造的 Host 头生成指向其他站点的链接。
+	AllowedHosts = getList("ALLOWED_HOSTS")
+)
+
+// getEnv 读取环境变量，如果未设置则返回默认值
+func getEnv(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
-	// DefaultExamAlarms 考试事件的默认提醒
-	DefaultExamAlarms = []icalendar.Alarm{
-		icalendar.NewIcsAlarm(icalendar.DISPLAY, 1*time.Hour, "距离考试仅剩1小时"),
-		icalendar.NewIcsAlarm(icalendar.DISPLAY, 24*time.Hour, "距离考试仅剩1天"),
-		icalendar.NewIcsAlarm(icalendar.DISPLAY, 7*24*time.Hour, "距离考试仅剩7天"),
+	return defaultValue
+}
+
+// getList 读取以逗号分隔的环境变量，忽略空项，未设置时返回 nil
+func getList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
 	}
+	return values
+}
+
+// 日历事件的默认提醒，单位为事件开始前的分钟数，可以通过 CalendarOptions 按账户或按请求覆盖
+var (
+	// DefaultCourseAlarms 课程事件的默认提醒
+	DefaultCourseAlarms = []int{28}
+	// DefaultExamAlarms 考试事件的默认提醒
+	DefaultExamAlarms = []int{60, 24 * 60, 7 * 24 * 60}
 )
 
 // 日历事件的标题和描述的配置
@@ -32,8 +69,175 @@ const (
 	CourseSummaryPrefix     = "【课程】"
 	CourseDescSummarySuffix = "数据来自【拱拱】"
 	ProdID                  = "-//sky31studio//GongGong//CN"
+	// DefaultCourseTitle 课程事件的默认标题模板
+	DefaultCourseTitle = CourseSummaryPrefix + " {name}"
+	// DefaultExamTitle 考试事件的默认标题模板
+	DefaultExamTitle = ExamSummaryPrefix + " {name}"
+	// 日历的名称
+	CoursesCalendarName = "拱拱课表"
+	ExamsCalendarName   = "拱拱考试"
+	AllCalendarName     = "拱拱日历"
+	// 事件的分类和颜色，颜色使用 RFC 7986 规定的 CSS3 颜色名称
+	CourseCategory  = "课程"
+	ExamCategory    = "考试"
+	WeekCategory    = "教学周"
+	CourseColor     = "steelblue"
+	ExamColor       = "crimson"
+	WeekMarkerColor = "gray"
+	// MaxAlarmMinutes 提醒最多提前的分钟数
+	MaxAlarmMinutes = 30 * 24 * 60
+	// MaxAlarms 课程和考试各自最多的提醒数量
+	MaxAlarms = 10
+	// MaxExcludes 最多不导出的课程或考试数量
+	MaxExcludes = 100
+	// MaxTitleLength 标题模板和不导出的名称最多的字符数
+	MaxTitleLength = 100
+	// MaxCalendarOptionsSize 保存日历导出选项时请求体最多的字节数
+	MaxCalendarOptionsSize = 16 << 10
 )
 
 const (
 	ApiPort = 8000
+	// CalendarRefreshInterval 订阅日历的建议刷新间隔
+	CalendarRefreshInterval = 4 * time.Hour
+	// WeekErrorsHeader 日历响应中课表无法解析的周次数量
+	WeekErrorsHeader = "X-Week-Errors"
+)
+
+// TranscriptFont 导出 PDF 成绩单时嵌入的 TrueType 中文字体，为空时使用随服务发布的文泉驿微米黑，通过环境变量 TRANSCRIPT_FONT 设置
+var (
+	TranscriptFont = os.Getenv("TRANSCRIPT_FONT")
+)
+
+// SpiderTimeout 每次更新缓存请求爬虫服务的最长时间，包括登录和重试，通过环境变量 SPIDER_TIMEOUT 设置，例如 90s
+var (
+	SpiderTimeout = getDuration("SPIDER_TIMEOUT", time.Minute)
+)
+
+// 更新缓存的协程池的配置
+var (
+	// RefreshWorkers 协程数量，通过环境变量 REFRESH_WORKERS 设置
+	RefreshWorkers = getInt("REFRESH_WORKERS", 10)
+	// RefreshQueueSize 等待执行的更新任务的上限，通过环境变量 REFRESH_QUEUE_SIZE 设置
+	RefreshQueueSize = getInt("REFRESH_QUEUE_SIZE", 1000)
+	// RefreshQueuePolicy 队列已满时的策略，shed 丢弃最早的后台刷新，reject 拒绝新的任务，通过环境变量 REFRESH_QUEUE_POLICY 设置
+	RefreshQueuePolicy = getPolicy("REFRESH_QUEUE_POLICY", executor.PolicyShedOldest)
+	// RefreshWorkersFile 收到 SIGHUP 时从该文件读取新的协程数量，通过环境变量 REFRESH_WORKERS_FILE 设置
+	RefreshWorkersFile = getEnv("REFRESH_WORKERS_FILE", "./_data/refresh_workers")
+)
+
+// LogLevel 日志级别，可以是 debug、info、warn 或 error，通过环境变量 LOG_LEVEL 设置
+var (
+	LogLevel = getEnv("LOG_LEVEL", "info")
+)
+
+// 限流的配置，格式为 "速率/容量"，速率为每秒的请求数，"off" 表示不限流
+var (
+	// SpiderLimits 请求爬虫服务的限流，通过环境变量 SPIDER_RATE_LIMIT（全局）、SPIDER_ACCOUNT_RATE_LIMIT（每个账户）
+	// 和 SPIDER_LOGIN_RATE_LIMIT（登录）设置
+	SpiderLimits = feign.LimiterConfig{
+		Global:  getLimit("SPIDER_RATE_LIMIT", feign.DefaultLimiterConfig.Global),
+		Account: getLimit("SPIDER_ACCOUNT_RATE_LIMIT", feign.DefaultLimiterConfig.Account),
+		Endpoints: map[string]ratelimit.Limit{
+			feign.EndpointLogin: getLimit("SPIDER_LOGIN_RATE_LIMIT", feign.DefaultLimiterConfig.Endpoints[feign.EndpointLogin]),
+		},
+	}
+	// TokenRateLimit 令牌有效的入站请求额外按令牌限流，通过环境变量 API_TOKEN_RATE_LIMIT 设置
+	TokenRateLimit = getLimit("API_TOKEN_RATE_LIMIT", ratelimit.Limit{Rate: 5, Burst: 20})
+	// IPRateLimit 所有入站请求按 IP 限流，通过环境变量 API_IP_RATE_LIMIT 设置
+	IPRateLimit = getLimit("API_IP_RATE_LIMIT", ratelimit.Limit{Rate: 10, Burst: 50})
+)
+
+// configWarning 是读取配置时发现的问题
+type configWarning struct {
+	msg  string
+	args []any
+}
+
+// configWarnings 收集包级变量初始化时的警告，此时 logging.Setup 还没有执行，直接输出会忽略日志级别和隐藏规则
+var configWarnings []configWarning
+
+// warnConfig 记录读取配置时的警告，由 logConfigWarnings 输出
+func warnConfig(msg string, args ...any) {
+	configWarnings = append(configWarnings, configWarning{msg: msg, args: args})
+}
+
+// logConfigWarnings 输出并清空收集的配置警告，在 logging.Setup 之后调用
+func logConfigWarnings() {
+	for _, warning := range configWarnings {
+		slog.Warn(warning.msg, warning.args...)
+	}
+	configWarnings = nil
+}
+
+// getLimit 读取限流配置的环境变量，未设置或格式错误时返回默认值
+func getLimit(key string, defaultValue ratelimit.Limit) ratelimit.Limit {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		warnConfig("invalid rate limit, using default", "key", key, "error", err)
+		return defaultValue
+	}
+	return limit
+}
+
+// getInt 读取正整数的环境变量，未设置或格式错误时返回默认值
+func getInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		warnConfig("invalid integer, using default", "key", key, "value", value)
+		return defaultValue
+	}
+	return n
+}
+
+// getPolicy 读取协程池队列策略的环境变量，未设置或格式错误时返回默认值
+func getPolicy(key string, defaultValue executor.Policy) executor.Policy {
+	switch value := os.Getenv(key); value {
+	case "":
+		return defaultValue
+	case "shed":
+		return executor.PolicyShedOldest
+	case "reject":
+		return executor.PolicyReject
+	default:
+		warnConfig("invalid queue policy, using default", "key", key, "value", value)
+		return defaultValue
+	}
+}
+
+// getDuration 读取时长的环境变量，未设置或格式错误时返回默认值
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		warnConfig("invalid duration, using default", "key", key, "value", value)
+		return defaultValue
+	}
+	return duration
+}
+
+// 追踪的配置，使用 OpenTelemetry 的标准环境变量
+var (
+	// TracesExporter 追踪的导出方式，otlp 发送到 Collector，console 输出到标准输出，none 不导出，通过环境变量 OTEL_TRACES_EXPORTER 设置
+	TracesExporter = getEnv("OTEL_TRACES_EXPORTER", "none")
+	// OTLPEndpoint OTLP/HTTP 的地址，通过环境变量 OTEL_EXPORTER_OTLP_ENDPOINT 设置
+	OTLPEndpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	// ServiceName 追踪中的服务名称，通过环境变量 OTEL_SERVICE_NAME 设置
+	ServiceName = getEnv("OTEL_SERVICE_NAME", "cached_proxy")
+)
+
+// ShutdownTimeout 收到退出信号后等待正在处理的请求和后台任务完成的最长时间，通过环境变量 SHUTDOWN_TIMEOUT 设置
+var (
+	ShutdownTimeout = getDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
 )
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"cached_proxy/logging"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigWarnings(t *testing.T) {
+	defer func(logger *slog.Logger) { slog.SetDefault(logger) }(slog.Default())
+	buf := bytes.Buffer{}
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	configWarnings = nil
+
+	t.Setenv("TEST_DURATION", "soon")
+	t.Setenv("TEST_INT", "-1")
+	if got := getDuration("TEST_DURATION", time.Second); got != time.Second {
+		t.Errorf("getDuration() = %v, want default", got)
+	}
+	if got := getInt("TEST_INT", 3); got != 3 {
+		t.Errorf("getInt() = %v, want default", got)
+	}
+	// 读取配置时只收集警告，日志配置完成后才输出
+	if buf.Len() != 0 || len(configWarnings) != 2 {
+		t.Fatalf("warnings should be deferred, got %d warnings, log %q", len(configWarnings), buf.String())
+	}
+
+	slog.SetDefault(logging.New(&buf, slog.LevelWarn))
+	logConfigWarnings()
+	for _, key := range []string{"TEST_DURATION", "TEST_INT"} {
+		if !strings.Contains(buf.String(), key) {
+			t.Errorf("expected warning for %s, got %s", key, buf.String())
+		}
+	}
+	if len(configWarnings) != 0 {
+		t.Errorf("warnings should be cleared after logging")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	account2 "cached_proxy/account"
+	"cached_proxy/executor"
+	"cached_proxy/feign"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CodeError 出错时响应的 Code
+const CodeError = 0
+
+// ErrorResponse 是出错时的响应，Error 为机器可读的错误码
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+// HTTPError 是直接对应 HTTP 状态码和错误码的错误，用于请求本身不合法的情况，RetryAfter 不为 0 时输出 Retry-After
+type HTTPError struct {
+	Status     int
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// badRequest 返回参数错误
+func badRequest(message string) error {
+	return &HTTPError{Status: http.StatusBadRequest, Code: "bad_request", Message: message}
+}
+
+var (
+	errMethodNotAllowed = &HTTPError{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Message: "Method Not Allowed"}
+	errMissingToken     = &HTTPError{Status: http.StatusUnauthorized, Code: "unauthorized", Message: "Missing Authorization header"}
+	errInvalidToken     = &HTTPError{Status: http.StatusUnauthorized, Code: "unauthorized", Message: "Invalid token format"}
+	errAccountLocked    = &HTTPError{Status: http.StatusUnauthorized, Code: "account_locked", Message: "Account is locked"}
+	errRequestTooLarge  = &HTTPError{Status: http.StatusRequestEntityTooLarge, Code: "request_too_large", Message: "Request body is too large"}
+	errHostNotAllowed   = &HTTPError{Status: http.StatusMisdirectedRequest, Code: "host_not_allowed", Message: "Host is not allowed, set PUBLIC_URL or ALLOWED_HOSTS"}
+)
+
+// errDataUpdating 表示请求所需的数据从未获取过或已经无法使用，已经触发更新，客户端稍后重试
+var errDataUpdating = errors.New("data updating")
+
+// errorMapping 是一类错误对应的 HTTP 状态码和错误码，retryAfter 不为 0 时输出 Retry-After，单位为秒
+type errorMapping struct {
+	err        error
+	status     int
+	code       string
+	retryAfter int
+}
+
+// errorMappings 按顺序使用 errors.Is 匹配，没有匹配的错误作为内部错误
+var errorMappings = []errorMapping{
+	{feign.ErrUnauthorized, http.StatusUnauthorized, "unauthorized", 0},
+	{account2.ErrAccountNotFound, http.StatusUnauthorized, "unauthorized", 0},
+	{feign.ErrCaptcha, http.StatusServiceUnavailable, "captcha_failed", 30},
+	{feign.ErrCircuitOpen, http.StatusServiceUnavailable, "circuit_open", 30},
+	{feign.ErrUpstreamUnavailable, http.StatusServiceUnavailable, "upstream_unavailable", 30},
+	{feign.ErrRateLimited, http.StatusTooManyRequests, "rate_limited", 60},
+	{executor.ErrQueueFull, http.StatusServiceUnavailable, "queue_full", 30},
+	{executor.ErrShed, http.StatusServiceUnavailable, "queue_full", 30},
+	{account2.ErrTokenCollision, http.StatusInternalServerError, "token_collision", 0},
+	{errInvalidCalendarOptions, http.StatusBadRequest, "invalid_calendar_options", 0},
+	{errDataUpdating, http.StatusServiceUnavailable, "updating", 5},
+}
+
+// writeError 将错误转换为 HTTP 状态码和 ErrorResponse 输出，JSON 接口的错误都通过这里输出
+//
+// 4xx 的错误输出错误信息，5xx 的错误只记录日志，不向客户端暴露内部信息。
+// CalDAV 和订阅日历的客户端是日历应用，仍然使用纯文本的错误。
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, retryAfter := classifyError(err)
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		// 数据正在更新是正常的情况，不记录为错误
+		if !errors.Is(err, errDataUpdating) {
+			slog.ErrorContext(r.Context(), "request failed", "code", code, "error", err)
+		}
+		message = http.StatusText(status)
+	}
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Code: CodeError, Message: message, Error: code})
+}
+
+// classifyError 返回错误对应的 HTTP 状态码、错误码和 Retry-After 的秒数
+func classifyError(err error) (status int, code string, retryAfter int) {
+	status, code = http.StatusInternalServerError, "internal_error"
+	var httpErr *HTTPError
+	var limitErr *feign.RateLimitError
+	if errors.As(err, &httpErr) {
+		return httpErr.Status, httpErr.Code, retryAfterSeconds(httpErr.RetryAfter)
+	}
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			status, code, retryAfter = mapping.status, mapping.code, mapping.retryAfter
+			break
+		}
+	}
+	// 代理自身的限流知道准确的等待时间
+	if errors.As(err, &limitErr) {
+		retryAfter = retryAfterSeconds(limitErr.Wait)
+	}
+	return status, code, retryAfter
+}
+
+// errorCode 返回错误的错误码，用于向客户端说明失败的类别而不暴露内部信息，err 为 nil 时返回空字符串
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	_, code, _ := classifyError(err)
+	return code
+}
+
+// retryAfterSeconds 将等待时间向上取整为秒，用于 Retry-After
+func retryAfterSeconds(wait time.Duration) int {
+	if wait <= 0 {
+		return 0
+	}
+	return int((wait + time.Second - 1) / time.Second)
+}
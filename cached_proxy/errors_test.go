@@ -0,0 +1,84 @@
+package main
+
+import (
+	account2 "cached_proxy/account"
+	"cached_proxy/feign"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       string
+		message    string
+		retryAfter string
+	}{
+		{"Unauthorized", fmt.Errorf("%w: invalid username or password", feign.ErrUnauthorized), http.StatusUnauthorized, "unauthorized", "unauthorized: invalid username or password", ""},
+		{"AccountNotFound", account2.ErrAccountNotFound, http.StatusUnauthorized, "unauthorized", "account not found", ""},
+		{"Captcha", fmt.Errorf("exceeded retry attempts: %w", feign.ErrCaptcha), http.StatusServiceUnavailable, "captcha_failed", "Service Unavailable", "30"},
+		{"Upstream", feign.ErrUpstreamUnavailable, http.StatusServiceUnavailable, "upstream_unavailable", "Service Unavailable", "30"},
+		{"CircuitOpen", fmt.Errorf("exceeded retry attempts: %w", feign.ErrCircuitOpen), http.StatusServiceUnavailable, "circuit_open", "Service Unavailable", "30"},
+		{"RateLimited", feign.ErrRateLimited, http.StatusTooManyRequests, "rate_limited", "rate limited", "60"},
+		{"ProxyRateLimited", fmt.Errorf("refresh: %w", &feign.RateLimitError{Scope: "account", Wait: 1500 * time.Millisecond}), http.StatusTooManyRequests, "rate_limited", "refresh: rate limited: account limit exceeded, retry after 1.5s", "2"},
+		{"TooManyRequests", errTooManyRequests(300 * time.Millisecond), http.StatusTooManyRequests, "rate_limited", "Too Many Requests", "1"},
+		{"TokenCollision", account2.ErrTokenCollision, http.StatusInternalServerError, "token_collision", "Internal Server Error", ""},
+		{"CalendarOptions", fmt.Errorf("%w: invalid alarm offset", errInvalidCalendarOptions), http.StatusBadRequest, "invalid_calendar_options", "invalid calendar options: invalid alarm offset", ""},
+		{"HTTPError", errMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed", "Method Not Allowed", ""},
+		{"BadRequest", badRequest("Invalid week"), http.StatusBadRequest, "bad_request", "Invalid week", ""},
+		{"Updating", errCalendarUpdating, http.StatusServiceUnavailable, "updating", "Service Unavailable", "5"},
+		{"Unknown", errors.New("disk full"), http.StatusInternalServerError, "internal_error", "Internal Server Error", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeError(w, httptest.NewRequest(http.MethodGet, "/info", nil), tt.err)
+			if w.Code != tt.status || w.Header().Get("Retry-After") != tt.retryAfter {
+				t.Errorf("unexpected status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != CodeError || resp.Error != tt.code || resp.Message != tt.message {
+				t.Errorf("unexpected response %+v", resp)
+			}
+		})
+	}
+}
+
+func TestTokenService_CheckToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		code   string
+	}{
+		{"Missing", "", "unauthorized"},
+		{"InvalidFormat", "Basic dXNlcjpwYXNz", "unauthorized"},
+		{"Short", "Bear", "unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/info", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			if account := (&TokenService{}).checkToken(w, r); account != nil {
+				t.Fatalf("expected no account, got %v", account)
+			}
+			var resp ErrorResponse
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+			if w.Code != http.StatusUnauthorized || resp.Error != tt.code {
+				t.Errorf("unexpected response %d %+v", w.Code, resp)
+			}
+		})
+	}
+}
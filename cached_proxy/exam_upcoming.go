@@ -0,0 +1,169 @@
+package main
+
+import (
+	"cached_proxy/cache"
+	"cached_proxy/feign"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// UpcomingExam 是解析了时间的考试
+type UpcomingExam struct {
+	feign.Examination
+	Start     time.Time      `json:"start"`
+	End       time.Time      `json:"end"`
+	Countdown int64          `json:"countdown"` // 距离考试开始的秒数，考试已经开始时为 0
+	Days      int            `json:"days"`      // 距离考试的天数，按照日期计算，当天为 0
+	Overlaps  []string       `json:"overlaps"`  // 时间重叠的其他考试
+	Clashes   []ScheduleItem `json:"clashes"`   // 时间冲突的课程
+}
+
+// UpcomingExams 是尚未结束的考试，按照开始时间排序
+type UpcomingExams struct {
+	Exams []UpcomingExam      `json:"exams"`
+	TBD   []feign.Examination `json:"tbd"` // 时间待定或无法解析的考试
+}
+
+// ExamsUpcoming 计算 now 之后尚未结束的考试，标记时间重叠的考试和与课程冲突的考试。
+// list 或 calendar 为 nil 时不检查与课程的冲突
+func ExamsUpcoming(exams *feign.ExamList, list *feign.CourseList, calendar *feign.TeachingCalendar, holidays *feign.HolidayCalendar, now time.Time) *UpcomingExams {
+	result := &UpcomingExams{Exams: []UpcomingExam{}, TBD: []feign.Examination{}}
+	if exams == nil {
+		return result
+	}
+	var parsed []UpcomingExam
+	for _, exam := range exams.Exams {
+		start, end, ok := parseExamTime(exam)
+		if !ok {
+			result.TBD = append(result.TBD, exam)
+			continue
+		}
+		parsed = append(parsed, UpcomingExam{Examination: exam, Start: start, End: end, Overlaps: []string{}, Clashes: []ScheduleItem{}})
+	}
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return parsed[i].Start.Before(parsed[j].Start)
+	})
+	// 已经结束的考试也参与重叠检查，但不会返回
+	for i := range parsed {
+		for j := range parsed {
+			if i != j && timeOverlaps(parsed[i].Start, parsed[i].End, parsed[j].Start, parsed[j].End) {
+				parsed[i].Overlaps = append(parsed[i].Overlaps, parsed[j].Name)
+			}
+		}
+	}
+	schedules := map[int]*Schedule{}
+	local := now.In(feign.Zone)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, feign.Zone)
+	for _, exam := range parsed {
+		if !exam.End.After(now) {
+			continue
+		}
+		if exam.Start.After(now) {
+			exam.Countdown = int64(exam.Start.Sub(now) / time.Second)
+		}
+		day := time.Date(exam.Start.Year(), exam.Start.Month(), exam.Start.Day(), 0, 0, 0, 0, feign.Zone)
+		exam.Days = int(day.Sub(today).Hours() / 24)
+		if list != nil && calendar != nil {
+			exam.Clashes = examClashes(exam, list, calendar, holidays, schedules)
+		}
+		result.Exams = append(result.Exams, exam)
+	}
+	return result
+}
+
+// examClashes 返回考试当天与考试时间冲突的课程，schedules 缓存已经计算过的周课表
+func examClashes(exam UpcomingExam, list *feign.CourseList, calendar *feign.TeachingCalendar, holidays *feign.HolidayCalendar, schedules map[int]*Schedule) []ScheduleItem {
+	clashes := []ScheduleItem{}
+	week, _ := calendar.WeekOf(exam.Start)
+	if week < 1 || (calendar.Weeks > 0 && week > calendar.Weeks) {
+		return clashes
+	}
+	schedule, found := schedules[week]
+	if !found {
+		schedule = CoursesSchedule(list, calendar, holidays, week)
+		schedules[week] = schedule
+	}
+	date := exam.Start.Format(feign.DateLayout)
+	for _, item := range schedule.Courses {
+		if item.Date != date {
+			continue
+		}
+		begin, err1 := time.ParseInLocation(feign.DateLayout+" 15:04", item.Date+" "+item.Begin, feign.Zone)
+		end, err2 := time.ParseInLocation(feign.DateLayout+" 15:04", item.Date+" "+item.End, feign.Zone)
+		if err1 == nil && err2 == nil && timeOverlaps(exam.Start, exam.End, begin, end) {
+			clashes = append(clashes, item)
+		}
+	}
+	return clashes
+}
+
+// timeOverlaps 判断两段时间是否重叠，开始时间相同的两段时间总是重叠
+func timeOverlaps(start1, end1, start2, end2 time.Time) bool {
+	return start1.Equal(start2) || (start1.Before(end2) && start2.Before(end1))
+}
+
+// UpcomingExamsGetter 获取即将到来的考试
+type UpcomingExamsGetter struct {
+	TokenService
+	examService     cache.InformationService[feign.ExamList]
+	courseService   cache.InformationService[feign.CourseList]
+	calendarService cache.InformationService[feign.TeachingCalendar]
+	holidayService  feign.HolidayService
+}
+
+var (
+	UpcomingExamsHandler = &UpcomingExamsGetter{
+		examService:     StudentExamService,
+		courseService:   StudentCourseService,
+		calendarService: CalendarService,
+		holidayService:  HolidayService,
+	}
+)
+
+func (u *UpcomingExamsGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+	account := u.checkToken(w, r)
+	if account == nil {
+		return
+	}
+	status := http.StatusOK
+	exams, err := u.examService.GetInfoContext(r.Context(), account.AccountID())
+	if err != nil {
+		status = http.StatusNonAuthoritativeInfo
+	}
+	if exams == nil {
+		writeError(w, r, errDataUpdating)
+		return
+	}
+	// 课表和校历只用于检查冲突，缺失时仍然返回考试
+	courses, err := u.courseService.GetInfoContext(r.Context(), account.AccountID())
+	if err != nil {
+		status = http.StatusNonAuthoritativeInfo
+	}
+	calendar, err := u.calendarService.GetInfoContext(r.Context(), account.AccountID())
+	if err != nil {
+		status = http.StatusNonAuthoritativeInfo
+	}
+	var holidays *feign.HolidayCalendar
+	if calendar != nil {
+		holidays = u.holidayService.GetHolidayCalendar(calendar.TermId)
+	}
+	upcoming := ExamsUpcoming(exams, courses, calendar, holidays, time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	resp := feign.CommonResponse[any]{
+		Code:    1,
+		Message: "success",
+		Data:    upcoming,
+	}
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode upcoming exams", "error", err)
+	}
+}
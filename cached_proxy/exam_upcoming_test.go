@@ -0,0 +1,60 @@
+package main
+
+import (
+	"cached_proxy/feign"
+	"testing"
+	"time"
+)
+
+func TestExamsUpcoming(t *testing.T) {
+	exams := &feign.ExamList{Exams: []feign.Examination{
+		{Name: "Later", StartTime: "2025-06-20 14:30:00", EndTime: "2025-06-20 16:30:00"},
+		{Name: "Math", StartTime: "2025-06-19 09:00:00", EndTime: "2025-06-19 11:00:00"},
+		{Name: "Physics", StartTime: "2025-06-19 10:00:00", EndTime: "2025-06-19 12:00:00"},
+		{Name: "Finished", StartTime: "2025-06-01 09:00:00", EndTime: "2025-06-01 11:00:00"},
+		{Name: "Unknown", StartTime: ""},
+		{Name: "Broken", StartTime: "下周一"},
+	}}
+	list := &feign.CourseList{Courses: []feign.Course{
+		{Name: "Friday", Weeks: "1-18", StartTime: 5, Duration: 2, Day: "Friday"},
+		{Name: "Thursday", Weeks: "1-18", StartTime: 9, Duration: 2, Day: "Thursday"},
+	}}
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	now := time.Date(2025, 6, 18, 20, 0, 0, 0, feign.Zone)
+
+	got := ExamsUpcoming(exams, list, calendar, nil, now)
+	if len(got.Exams) != 3 {
+		t.Fatalf("expected 3 upcoming exams, got %+v", got.Exams)
+	}
+	names := []string{got.Exams[0].Name, got.Exams[1].Name, got.Exams[2].Name}
+	if names[0] != "Math" || names[1] != "Physics" || names[2] != "Later" {
+		t.Errorf("exams should be sorted by time, got %v", names)
+	}
+	math := got.Exams[0]
+	if math.Countdown != 13*60*60 || math.Days != 1 {
+		t.Errorf("unexpected countdown of Math: %d seconds, %d days", math.Countdown, math.Days)
+	}
+	if len(math.Overlaps) != 1 || math.Overlaps[0] != "Physics" {
+		t.Errorf("Math should overlap with Physics, got %v", math.Overlaps)
+	}
+	if len(got.Exams[2].Overlaps) != 0 {
+		t.Errorf("Later should not overlap, got %v", got.Exams[2].Overlaps)
+	}
+	// 周五下午的课程 14:30 开始，与考试冲突；周四晚上的课程与考试不冲突
+	if clashes := got.Exams[2].Clashes; len(clashes) != 1 || clashes[0].Name != "Friday" {
+		t.Errorf("Later should clash with the Friday course, got %+v", clashes)
+	}
+	if len(math.Clashes) != 0 {
+		t.Errorf("Math should not clash with any course, got %+v", math.Clashes)
+	}
+	if len(got.TBD) != 2 || got.TBD[0].Name != "Unknown" || got.TBD[1].Name != "Broken" {
+		t.Errorf("expected exams with unknown time in TBD, got %+v", got.TBD)
+	}
+
+	t.Run("Ongoing exam", func(t *testing.T) {
+		got := ExamsUpcoming(exams, nil, nil, nil, time.Date(2025, 6, 19, 10, 30, 0, 0, feign.Zone))
+		if got.Exams[0].Name != "Math" || got.Exams[0].Countdown != 0 || got.Exams[0].Days != 0 {
+			t.Errorf("expected ongoing Math exam, got %+v", got.Exams[0])
+		}
+	})
+}
@@ -0,0 +1,313 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Priority 是任务的优先级，协程总是先执行高优先级的任务
+type Priority int
+
+const (
+	// PriorityBackground 是后台刷新，例如缓存过期后的更新，客户端已经拿到了旧数据
+	PriorityBackground Priority = iota
+	// PriorityInteractive 是用户正在等待的任务，例如缓存未命中时的更新
+	PriorityInteractive
+	numPriorities
+)
+
+// String 返回优先级的名称，用于日志和监控
+func (p Priority) String() string {
+	switch p {
+	case PriorityBackground:
+		return "background"
+	case PriorityInteractive:
+		return "interactive"
+	}
+	return "unknown"
+}
+
+// Policy 是队列已满时的处理策略
+type Policy int
+
+const (
+	// PolicyReject 拒绝新的任务
+	PolicyReject Policy = iota
+	// PolicyShedOldest 丢弃优先级不高于新任务的最早的任务，没有这样的任务时拒绝新的任务
+	PolicyShedOldest
+)
+
+var (
+	// ErrQueueFull 队列已满，任务被拒绝
+	ErrQueueFull = errors.New("executor queue is full")
+	// ErrShed 任务在队列中等待时被优先级更高或者更新的任务挤掉
+	ErrShed = errors.New("task shed from executor queue")
+)
+
+// Task 是提交给 PriorityPool 的任务
+type Task struct {
+	Name     string        // 任务名称，用于日志和追踪
+	Priority Priority      // 优先级
+	Timeout  time.Duration // 执行的最长时间，为 0 时使用协程池的默认值，任务需要响应 ctx 的取消
+	Run      func(ctx context.Context)
+	// OnShed 在任务被丢弃时调用，可以为 nil，提交时就被拒绝的任务通过 SubmitTask 的返回值通知
+	OnShed func(err error)
+}
+
+// TaskExecutor 是支持优先级、超时和拒绝的 Executor，SubmitTask 不会阻塞
+type TaskExecutor interface {
+	Executor
+	// SubmitTask 提交任务，任务的 ctx 继承 ctx 中的值和取消，队列已满时返回 ErrQueueFull
+	SubmitTask(ctx context.Context, task Task) error
+}
+
+// PoolConfig 是 PriorityPool 的配置
+type PoolConfig struct {
+	Workers     int           // 协程数量
+	QueueSize   int           // 等待执行的任务的上限，所有优先级共用
+	Policy      Policy        // 队列已满时的策略
+	TaskTimeout time.Duration // 任务默认的最长执行时间，为 0 表示不限制
+}
+
+// queuedTask 是队列中的任务
+type queuedTask struct {
+	ctx  context.Context
+	task Task
+}
+
+// PriorityPool 是有界队列、按优先级执行的协程池
+//
+// 与 WorkerPool 不同，提交任务不会阻塞调用方，队列已满时按 Policy 拒绝或者丢弃任务。
+// 任务 panic 时记录日志并继续执行其他任务。协程数量可以通过 Resize 在运行时调整。
+type PriorityPool struct {
+	mu       sync.Mutex
+	cond     *sync.Cond                  // 有新任务、停止或者缩容时唤醒等待任务的协程
+	idle     *sync.Cond                  // 所有任务完成或者协程退出时唤醒 Wait
+	lanes    [numPriorities][]queuedTask // 每个优先级一个先进先出的队列
+	config   PoolConfig
+	running  bool // 协程是否已经启动，停止后再次提交会重新启动
+	workers  int  // 当前的协程数
+	busy     int  // 正在执行任务的协程数
+	rejected int  // 被拒绝的任务数
+	shed     int  // 被丢弃的任务数
+	panics   int  // panic 的任务数
+}
+
+// NewPriorityPool 创建 PriorityPool，Workers 和 QueueSize 至少为 1
+func NewPriorityPool(config PoolConfig) *PriorityPool {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.QueueSize < 1 {
+		config.QueueSize = 1
+	}
+	p := &PriorityPool{config: config}
+	p.cond = sync.NewCond(&p.mu)
+	p.idle = sync.NewCond(&p.mu)
+	return p
+}
+
+// Run 启动协程池，已经启动时没有效果
+func (p *PriorityPool) Run() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.start()
+}
+
+// start 在持有锁时启动协程，补足到配置的协程数
+func (p *PriorityPool) start() {
+	p.running = true
+	for ; p.workers < p.config.Workers; p.workers++ {
+		go p.worker()
+	}
+}
+
+// Submit 以后台优先级提交任务，被拒绝时只记录日志
+func (p *PriorityPool) Submit(task func()) {
+	err := p.SubmitTask(context.Background(), Task{Priority: PriorityBackground, Run: func(context.Context) { task() }})
+	if err != nil {
+		slog.Warn("task rejected", "error", err)
+	}
+}
+
+// SubmitTask 提交任务，协程池没有启动时先启动
+func (p *PriorityPool) SubmitTask(ctx context.Context, task Task) error {
+	if task.Priority < 0 || task.Priority >= numPriorities {
+		return fmt.Errorf("invalid priority %d", task.Priority)
+	}
+	p.mu.Lock()
+	if !p.running {
+		p.start()
+	}
+	var shed *queuedTask
+	if p.queuedLocked() >= p.config.QueueSize {
+		if p.config.Policy == PolicyShedOldest {
+			shed = p.shedLocked(task.Priority)
+		}
+		if shed == nil {
+			p.rejected++
+			p.mu.Unlock()
+			return ErrQueueFull
+		}
+	}
+	p.lanes[task.Priority] = append(p.lanes[task.Priority], queuedTask{ctx: ctx, task: task})
+	p.cond.Signal()
+	p.mu.Unlock()
+	if shed != nil && shed.task.OnShed != nil {
+		shed.task.OnShed(ErrShed)
+	}
+	return nil
+}
+
+// shedLocked 从最低的优先级开始，取出优先级不高于 priority 的最早的任务，没有时返回 nil
+func (p *PriorityPool) shedLocked(priority Priority) *queuedTask {
+	for lane := Priority(0); lane <= priority; lane++ {
+		if len(p.lanes[lane]) > 0 {
+			shed := p.lanes[lane][0]
+			p.lanes[lane] = p.lanes[lane][1:]
+			p.shed++
+			return &shed
+		}
+	}
+	return nil
+}
+
+func (p *PriorityPool) queuedLocked() int {
+	n := 0
+	for _, lane := range p.lanes {
+		n += len(lane)
+	}
+	return n
+}
+
+// next 在持有锁时取出优先级最高的任务
+func (p *PriorityPool) next() (queuedTask, bool) {
+	for lane := numPriorities - 1; lane >= 0; lane-- {
+		if len(p.lanes[lane]) > 0 {
+			task := p.lanes[lane][0]
+			p.lanes[lane][0] = queuedTask{} // 释放引用
+			p.lanes[lane] = p.lanes[lane][1:]
+			return task, true
+		}
+	}
+	return queuedTask{}, false
+}
+
+// worker 不断取出任务执行，协程数超过配置或者停止后队列为空时退出
+func (p *PriorityPool) worker() {
+	p.mu.Lock()
+	defer func() {
+		p.workers--
+		p.idle.Broadcast()
+		p.mu.Unlock()
+	}()
+	for {
+		if p.workers > p.config.Workers {
+			return
+		}
+		task, ok := p.next()
+		if !ok {
+			if !p.running {
+				return
+			}
+			p.cond.Wait()
+			continue
+		}
+		p.busy++
+		p.mu.Unlock()
+		panicked := p.execute(task)
+		p.mu.Lock()
+		p.busy--
+		if panicked {
+			p.panics++
+		}
+		p.idle.Broadcast()
+	}
+}
+
+// execute 执行任务，返回任务是否 panic
+func (p *PriorityPool) execute(t queuedTask) (panicked bool) {
+	timeout := t.task.Timeout
+	if timeout <= 0 {
+		timeout = p.config.TaskTimeout
+	}
+	ctx, cancel := t.ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "task panicked", "task", t.task.Name, "panic", r, "stack", string(debug.Stack()))
+			panicked = true
+		}
+	}()
+	t.task.Run(ctx)
+	return false
+}
+
+// Wait 等待队列中和正在执行的任务完成，然后停止协程池
+func (p *PriorityPool) Wait() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.queuedLocked() > 0 || p.busy > 0 {
+		p.idle.Wait()
+	}
+	p.running = false
+	p.cond.Broadcast()
+	for p.workers > 0 && !p.running {
+		p.idle.Wait()
+	}
+}
+
+// Shutdown 与 Wait 相同，但最多等待到 ctx 结束，超时返回 ctx 的错误，此时仍在执行的任务会在后台继续
+func (p *PriorityPool) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Resize 调整协程数量，缩容时多出的协程执行完当前的任务后退出
+func (p *PriorityPool) Resize(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config.Workers = workers
+	if p.running {
+		p.start()
+	}
+	p.cond.Broadcast()
+}
+
+// Stats 返回协程池当前的状态
+func (p *PriorityPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := PoolStats{
+		Workers:  p.config.Workers,
+		Queued:   p.queuedLocked(),
+		Busy:     p.busy,
+		Rejected: p.rejected,
+		Shed:     p.shed,
+		Panics:   p.panics,
+	}
+	for lane := range p.lanes {
+		stats.QueuedByPriority[lane] = len(p.lanes[lane])
+	}
+	return stats
+}
@@ -0,0 +1,189 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockPool 提交 n 个阻塞的任务占满协程，返回释放这些任务的函数
+func blockPool(t *testing.T, p *PriorityPool, n int) func() {
+	release := make(chan struct{})
+	started := make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		err := p.SubmitTask(context.Background(), Task{Priority: PriorityInteractive, Run: func(context.Context) {
+			started <- struct{}{}
+			<-release
+		}})
+		if err != nil {
+			t.Fatalf("failed to submit blocking task: %v", err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		<-started
+	}
+	return func() { close(release) }
+}
+
+func TestPriorityPool_Order(t *testing.T) {
+	p := NewPriorityPool(PoolConfig{Workers: 1, QueueSize: 10})
+	release := blockPool(t, p, 1)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func(context.Context) {
+		return func(context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+		}
+	}
+	tasks := []Task{
+		{Priority: PriorityBackground, Run: record("background 1")},
+		{Priority: PriorityInteractive, Run: record("interactive 1")},
+		{Priority: PriorityBackground, Run: record("background 2")},
+		{Priority: PriorityInteractive, Run: record("interactive 2")},
+	}
+	for _, task := range tasks {
+		if err := p.SubmitTask(context.Background(), task); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if stats := p.Stats(); stats.Queued != 4 || stats.QueuedByPriority != [numPriorities]int{2, 2} || stats.Busy != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	release()
+	p.Wait()
+
+	want := []string{"interactive 1", "interactive 2", "background 1", "background 2"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("execution order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestPriorityPool_Policy(t *testing.T) {
+	noop := func(context.Context) {}
+	tests := []struct {
+		name   string
+		policy Policy
+		queued Priority // 队列中已有的任务的优先级
+		submit Priority // 队列已满时提交的任务的优先级
+		err    error    // 提交的结果
+		shed   bool     // 已有的任务是否被丢弃
+	}{
+		{"reject interactive", PolicyReject, PriorityBackground, PriorityInteractive, ErrQueueFull, false},
+		{"shed background for interactive", PolicyShedOldest, PriorityBackground, PriorityInteractive, nil, true},
+		{"shed oldest of same priority", PolicyShedOldest, PriorityBackground, PriorityBackground, nil, true},
+		{"never shed higher priority", PolicyShedOldest, PriorityInteractive, PriorityBackground, ErrQueueFull, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPriorityPool(PoolConfig{Workers: 1, QueueSize: 1, Policy: tt.policy})
+			release := blockPool(t, p, 1)
+			var shedErr error
+			err := p.SubmitTask(context.Background(), Task{Priority: tt.queued, Run: noop, OnShed: func(err error) { shedErr = err }})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err := p.SubmitTask(context.Background(), Task{Priority: tt.submit, Run: noop}); !errors.Is(err, tt.err) {
+				t.Errorf("submit error = %v, want %v", err, tt.err)
+			}
+			if shed := errors.Is(shedErr, ErrShed); shed != tt.shed {
+				t.Errorf("shed = %v, want %v", shed, tt.shed)
+			}
+			stats := p.Stats()
+			if stats.Queued != 1 || (stats.Shed == 1) != tt.shed || (stats.Rejected == 1) != (tt.err != nil) {
+				t.Errorf("unexpected stats %+v", stats)
+			}
+			release()
+			p.Wait()
+		})
+	}
+
+	p := NewPriorityPool(PoolConfig{})
+	if err := p.SubmitTask(context.Background(), Task{Priority: numPriorities, Run: noop}); err == nil {
+		t.Error("invalid priority should be rejected")
+	}
+}
+
+func TestPriorityPool_Timeout(t *testing.T) {
+	p := NewPriorityPool(PoolConfig{Workers: 2, QueueSize: 10, TaskTimeout: 10 * time.Millisecond})
+	errs := make(chan error, 2)
+	wait := func(ctx context.Context) {
+		<-ctx.Done()
+		errs <- ctx.Err()
+	}
+	start := time.Now()
+	_ = p.SubmitTask(context.Background(), Task{Run: wait})
+	// 任务自己的超时优先于默认值
+	_ = p.SubmitTask(context.Background(), Task{Run: wait, Timeout: time.Millisecond})
+	p.Wait()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("tasks should time out quickly, took %v", elapsed)
+	}
+}
+
+func TestPriorityPool_Panic(t *testing.T) {
+	p := NewPriorityPool(PoolConfig{Workers: 1, QueueSize: 10})
+	ran := false
+	_ = p.SubmitTask(context.Background(), Task{Name: "broken", Run: func(context.Context) { panic("boom") }})
+	_ = p.SubmitTask(context.Background(), Task{Run: func(context.Context) { ran = true }})
+	p.Wait()
+	if !ran || p.Stats().Panics != 1 {
+		t.Errorf("pool should survive a panicking task, ran %v, stats %+v", ran, p.Stats())
+	}
+}
+
+func TestPriorityPool_Resize(t *testing.T) {
+	p := NewPriorityPool(PoolConfig{Workers: 1, QueueSize: 10})
+	release := blockPool(t, p, 1)
+	// 扩容后排队的任务立即开始执行
+	p.Resize(3)
+	release2 := blockPool(t, p, 2)
+	if stats := p.Stats(); stats.Workers != 3 || stats.Busy != 3 || stats.Queued != 0 {
+		t.Errorf("unexpected stats after growing %+v", stats)
+	}
+
+	p.Resize(1)
+	release()
+	release2()
+	for {
+		p.mu.Lock()
+		workers := p.workers
+		p.mu.Unlock()
+		if workers == 1 {
+			break
+		}
+		runtime.Gosched()
+	}
+	p.Wait()
+}
+
+func TestPriorityPool_Restart(t *testing.T) {
+	p := NewPriorityPool(PoolConfig{Workers: 2, QueueSize: 10})
+	count := 0
+	var mu sync.Mutex
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 5; i++ {
+			p.Submit(func() {
+				mu.Lock()
+				defer mu.Unlock()
+				count++
+			})
+		}
+		p.Wait()
+	}
+	if err := p.Shutdown(context.Background()); err != nil || count != 15 {
+		t.Errorf("expected 15 tasks after restarts, got %d %v", count, err)
+	}
+}
@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"cached_proxy/tracing"
+	"context"
+	"time"
+)
+
+// SubmitContext 提交使用 ctx 的任务，并为任务在队列中的等待和执行各记录一个 span
+//
+// exec 是 TaskExecutor 时按优先级提交，队列已满时返回错误；否则通过 Submit 提交，只使用任务的 Run 和 Timeout。
+func SubmitContext(exec Executor, ctx context.Context, task Task) error {
+	submitAt := time.Now()
+	attrs := []tracing.Attribute{
+		{Key: "executor.task", Value: task.Name},
+		{Key: "executor.priority", Value: task.Priority.String()},
+	}
+	run := task.Run
+	task.Run = func(ctx context.Context) {
+		_, wait := tracing.Start(ctx, "executor.queue", tracing.WithStartTime(submitAt), tracing.WithAttributes(attrs...))
+		wait.End()
+		ctx, span := tracing.Start(ctx, "executor.run", tracing.WithAttributes(attrs...))
+		defer span.End()
+		run(ctx)
+		// 超时的任务标记为出错
+		span.RecordError(ctx.Err())
+	}
+	if te, ok := exec.(TaskExecutor); ok {
+		return te.SubmitTask(ctx, task)
+	}
+	exec.Submit(func() {
+		ctx := ctx
+		if task.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, task.Timeout)
+			defer cancel()
+		}
+		task.Run(ctx)
+	})
+	return nil
+}
@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"cached_proxy/tracing"
+	"context"
+	"testing"
+)
+
+// syncExecutor 在提交时立即执行任务
+type syncExecutor struct{}
+
+func (syncExecutor) Run()               {}
+func (syncExecutor) Submit(task func()) { task() }
+func (syncExecutor) Wait()              {}
+
+func TestSubmitContext(t *testing.T) {
+	recorder := &tracing.Recorder{}
+	tracer := tracing.NewTracer(recorder)
+	old := tracing.Default()
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(old)
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	var taskSpan tracing.SpanContext
+	err := SubmitContext(syncExecutor{}, ctx, Task{Name: "refresh", Priority: PriorityInteractive, Run: func(ctx context.Context) {
+		taskSpan = tracing.SpanContextFromContext(ctx)
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Spans()
+	if len(spans) != 3 || spans[0].Name != "executor.queue" || spans[1].Name != "executor.run" {
+		t.Fatalf("expected queue and run spans, got %v", spans)
+	}
+	for _, span := range spans[:2] {
+		if span.Parent != parent.SpanContext().SpanID || span.Attribute("executor.task") != "refresh" || span.Attribute("executor.priority") != "interactive" {
+			t.Errorf("unexpected span %+v", span)
+		}
+	}
+	if taskSpan != spans[1].SpanContext {
+		t.Error("task should run inside the run span")
+	}
+}
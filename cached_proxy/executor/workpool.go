@@ -1,51 +1,152 @@
 package executor
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
 
 // WorkerPool 定义一个协程池
+//
+// 协程池在 Run 或者第一次 Submit 时启动，Wait 或 Shutdown 之后停止，停止后再次 Submit 会重新启动。
 type WorkerPool struct {
-	tasks     chan func()    // 任务队列
-	wg        sync.WaitGroup // 用于等待所有任务完成
-	workerNum int            // 协程数量
-	startup   bool
+	mu        sync.RWMutex    // 保护 tasks 和 running，Submit 持有读锁，启动和停止持有写锁
+	tasks     chan func()     // 任务队列
+	running   bool            // 协程是否已经启动
+	pendingMu sync.Mutex      // 保护 pending
+	idle      *sync.Cond      // pending 归零时广播
+	pending   int             // 已经提交、还没有执行完的任务数
+	workers   *sync.WaitGroup // 用于等待这一批协程退出，每次启动创建新的
+	workerNum int             // 协程数量
+	queued    atomic.Int64    // 已经提交、还没有被协程取走的任务数
+	busy      atomic.Int64    // 正在执行任务的协程数
+}
+
+// PoolStats 是协程池某一时刻的状态
+type PoolStats struct {
+	Workers          int                // 协程数量
+	Queued           int                // 等待执行的任务数
+	Busy             int                // 正在执行任务的协程数
+	QueuedByPriority [numPriorities]int // 每个优先级等待执行的任务数，只有 PriorityPool 统计
+	Rejected         int                // 队列已满被拒绝的任务数，只有 PriorityPool 统计
+	Shed             int                // 在队列中被丢弃的任务数，只有 PriorityPool 统计
+	Panics           int                // panic 的任务数，只有 PriorityPool 统计
 }
 
 // NewWorkerPool 创建一个新的协程池
 func NewWorkerPool(workerNum int) *WorkerPool {
-	return &WorkerPool{
-		tasks:     make(chan func()),
+	wp := &WorkerPool{
 		workerNum: workerNum,
 	}
+	wp.idle = sync.NewCond(&wp.pendingMu)
+	return wp
 }
 
 // worker 是每个协程的具体执行逻辑
-func (wp *WorkerPool) worker() {
-	for task := range wp.tasks {
-		task()       // 执行任务
-		wp.wg.Done() // 标记任务完成
+func (wp *WorkerPool) worker(tasks <-chan func(), workers *sync.WaitGroup) {
+	defer workers.Done()
+	for task := range tasks {
+		wp.queued.Add(-1)
+		wp.busy.Add(1)
+		task() // 执行任务
+		wp.busy.Add(-1)
+		wp.done() // 标记任务完成
 	}
 }
 
-// Run 启动协程池，开启指定数量的协程
+// Run 启动协程池，开启指定数量的协程，已经启动时没有效果
 func (wp *WorkerPool) Run() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.start()
+}
+
+// start 在持有写锁时启动协程
+func (wp *WorkerPool) start() {
+	if wp.running {
+		return
+	}
+	wp.tasks = make(chan func())
+	wp.running = true
+	wp.workers = &sync.WaitGroup{}
+	wp.workers.Add(wp.workerNum)
 	for i := 0; i < wp.workerNum; i++ {
-		go wp.worker()
+		go wp.worker(wp.tasks, wp.workers)
 	}
 }
 
-// Submit 提交任务到任务队列
+// Submit 提交任务到任务队列，协程池没有启动时先启动
+//
+// 启动在写锁下进行，拿到读锁后重新检查 running，避免向已经被 stop 关闭的队列发送任务。
 func (wp *WorkerPool) Submit(task func()) {
-	if !wp.startup {
+	wp.mu.RLock()
+	for !wp.running {
+		wp.mu.RUnlock()
 		wp.Run()
-		wp.startup = true
+		wp.mu.RLock()
 	}
-	wp.wg.Add(1) // 增加一个任务
+	defer wp.mu.RUnlock()
+	wp.pendingMu.Lock()
+	wp.pending++ // 增加一个任务
+	wp.pendingMu.Unlock()
+	wp.queued.Add(1)
 	wp.tasks <- task
+}
 
+// done 标记一个任务执行完成，没有未完成的任务时唤醒 Wait
+func (wp *WorkerPool) done() {
+	wp.pendingMu.Lock()
+	defer wp.pendingMu.Unlock()
+	wp.pending--
+	if wp.pending == 0 {
+		wp.idle.Broadcast()
+	}
 }
 
-// Wait 等待所有任务完成
+// Wait 等待所有任务完成，然后停止协程池
+//
+// 不使用 sync.WaitGroup 计数，因为 Wait 期间可能有新的 Submit，WaitGroup 不允许此时 Add。
 func (wp *WorkerPool) Wait() {
-	wp.wg.Wait()    // 等待任务完成
+	wp.pendingMu.Lock()
+	for wp.pending > 0 {
+		wp.idle.Wait() // 等待任务完成
+	}
+	wp.pendingMu.Unlock()
+	wp.stop()
+}
+
+// Shutdown 与 Wait 相同，但最多等待到 ctx 结束，超时返回 ctx 的错误，此时仍在执行的任务会在后台继续
+func (wp *WorkerPool) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		wp.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop 关闭任务队列并等待协程退出，正在提交的任务会先被取走执行
+//
+// 等待协程退出时不持有锁，任务中提交的新任务会启动新的一批协程。
+func (wp *WorkerPool) stop() {
+	wp.mu.Lock()
+	if !wp.running {
+		wp.mu.Unlock()
+		return
+	}
 	close(wp.tasks) // 关闭任务队列
+	wp.running = false
+	workers := wp.workers
+	wp.mu.Unlock()
+	workers.Wait()
+}
+
+// Stats 返回协程池当前的状态
+func (wp *WorkerPool) Stats() PoolStats {
+	return PoolStats{Workers: wp.workerNum, Queued: int(wp.queued.Load()), Busy: int(wp.busy.Load())}
 }
@@ -1,8 +1,13 @@
 package executor
 
 import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // 测试线程池基本运行情况
@@ -63,3 +68,106 @@ func TestWorkerPoolRunMultipleTimes(t *testing.T) {
 		t.Errorf("执行的任务数量错误：期望 %d，实际 %d", taskCount, executedTaskCount)
 	}
 }
+
+func TestWorkerPoolStats(t *testing.T) {
+	// Submit 会启动协程池，这里不能再调用 Run
+	wp := NewWorkerPool(2)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		wp.Submit(func() {
+			started <- struct{}{}
+			<-release
+		})
+	}
+	<-started
+	<-started
+	// 两个协程都在执行任务，第三个任务在队列中等待
+	go wp.Submit(func() {})
+	for wp.Stats().Queued != 1 {
+		runtime.Gosched()
+	}
+	if stats := wp.Stats(); stats != (PoolStats{Workers: 2, Queued: 1, Busy: 2}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	close(release)
+	wp.Wait()
+	if stats := wp.Stats(); stats.Queued != 0 || stats.Busy != 0 {
+		t.Errorf("unexpected stats after completion %+v", stats)
+	}
+}
+
+func TestWorkerPoolRestart(t *testing.T) {
+	wp := NewWorkerPool(2)
+	var executedTaskCount int32 = 0
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 5; i++ {
+			wp.Submit(func() {
+				atomic.AddInt32(&executedTaskCount, 1)
+			})
+		}
+		// 停止后再次提交会重新启动协程池
+		wp.Wait()
+	}
+	wp.Wait()
+	if executedTaskCount != 15 {
+		t.Errorf("执行的任务数量错误：期望 %d，实际 %d", 15, executedTaskCount)
+	}
+}
+
+func TestWorkerPoolConcurrentSubmitWait(t *testing.T) {
+	wp := NewWorkerPool(2)
+	var executedTaskCount int32 = 0
+	var submitters sync.WaitGroup
+	// Submit 与 Wait 并发时，不能向已经关闭的队列发送任务，也不能丢失任务
+	for i := 0; i < 8; i++ {
+		submitters.Add(1)
+		go func() {
+			defer submitters.Done()
+			for j := 0; j < 50; j++ {
+				wp.Submit(func() {
+					atomic.AddInt32(&executedTaskCount, 1)
+				})
+			}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		submitters.Add(1)
+		go func() {
+			defer submitters.Done()
+			for j := 0; j < 20; j++ {
+				wp.Wait()
+			}
+		}()
+	}
+	submitters.Wait()
+	wp.Wait()
+	if executedTaskCount != 400 {
+		t.Errorf("执行的任务数量错误：期望 %d，实际 %d", 400, executedTaskCount)
+	}
+}
+
+func TestWorkerPoolShutdown(t *testing.T) {
+	wp := NewWorkerPool(1)
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	wp.Submit(func() {
+		<-release
+		close(finished)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := wp.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded while the task is running, got %v", err)
+	}
+	close(release)
+	if err := wp.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected the pool to drain, got %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("in-flight task should complete before shutdown returns")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	account2 "cached_proxy/account"
+	"cached_proxy/feign"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// calendarRenderer 生成账户的日历
+type calendarRenderer interface {
+	render(ctx context.Context, accountID string, query url.Values) (*renderedCalendar, error)
+}
+
+// FeedLinks 是账户的订阅日历地址
+type FeedLinks struct {
+	Secret        string `json:"secret"`
+	Courses       string `json:"courses"`
+	Exams         string `json:"exams"`
+	All           string `json:"all"`
+	WebcalCourses string `json:"webcal_courses"`
+	WebcalExams   string `json:"webcal_exams"`
+	WebcalAll     string `json:"webcal_all"`
+}
+
+// FeedGetter 提供可订阅的日历地址，地址中携带每个账户独立的密钥，不需要 Authorization 头
+type FeedGetter struct {
+	TokenService
+	feeds     account2.FeedService
+	calendars map[string]calendarRenderer // 文件名到日历的映射，例如 courses.ics
+}
+
+var (
+	FeedHandler = &FeedGetter{
+		TokenService: TokenService{acc: AccountService},
+		feeds:        FeedService,
+		calendars: map[string]calendarRenderer{
+			"courses.ics": &CoursesCalendarHandler,
+			"exams.ics":   &ExamCalendarHandler,
+			"all.ics":     AllCalendarHandler,
+		},
+	}
+)
+
+// baseUrl 返回服务对外访问的地址
+//
+// 未设置 PublicUrl 时根据请求推断，Host 必须在 AllowedHosts 中，X-Forwarded-Proto 只接受 http 和 https。
+func baseUrl(r *http.Request) (string, error) {
+	if PublicUrl != "" {
+		return strings.TrimSuffix(PublicUrl, "/"), nil
+	}
+	if !hostAllowed(r.Host) {
+		return "", errHostNotAllowed
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host, nil
+}
+
+// hostAllowed 判断 Host 是否在 AllowedHosts 中，不区分大小写
+func hostAllowed(host string) bool {
+	for _, allowed := range AllowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func newFeedLinks(base string, secret string) *FeedLinks {
+	base += "/feeds/" + secret
+	webcal := "webcal://" + base[strings.Index(base, "://")+3:]
+	return &FeedLinks{
+		Secret:        secret,
+		Courses:       base + "/courses.ics",
+		Exams:         base + "/exams.ics",
+		All:           base + "/all.ics",
+		WebcalCourses: webcal + "/courses.ics",
+		WebcalExams:   webcal + "/exams.ics",
+		WebcalAll:     webcal + "/all.ics",
+	}
+}
+
+// GetInfo 管理账户的订阅地址，GET 获取（不存在时创建），POST 重新生成，DELETE 停用
+func (f *FeedGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
+	account := f.checkToken(w, r)
+	if account == nil {
+		return
+	}
+	var base, secret string
+	var err error
+	if r.Method == http.MethodGet || r.Method == http.MethodPost {
+		// 在创建或重新生成密钥之前检查地址，避免密钥已经改变却无法返回链接
+		if base, err = baseUrl(r); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodGet:
+		secret, err = f.feeds.GetFeedSecret(account.AccountID())
+	case http.MethodPost:
+		secret, err = f.feeds.RegenerateFeedSecret(account.AccountID())
+	case http.MethodDelete:
+		err = f.feeds.RevokeFeedSecret(account.AccountID())
+	default:
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to manage feed of %s: %w", account.AccountID(), err))
+		return
+	}
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	resp := feign.CommonResponse[any]{
+		Code:    1,
+		Message: "success",
+		Data:    newFeedLinks(base, secret),
+	}
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode feed links", "error", err)
+	}
+}
+
+// ServeFeed 输出订阅日历 GET /feeds/{secret}/{name}.ics，支持 ETag 和 Last-Modified 条件请求
+func (f *FeedGetter) ServeFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/feeds/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	secret, name := parts[0], parts[1]
+	renderer, found := f.calendars[name]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	account, err := f.feeds.GetAccountByFeedSecret(secret)
+	if err != nil || account == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if account.Status() != account2.Normal {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	rendered, err := renderer.render(r.Context(), account.AccountID(), r.URL.Query())
+	if errors.Is(err, errCalendarUpdating) {
+		// 订阅的日历应用无法处理 203，数据未就绪时让其稍后重试
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Data Updating", http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, errInvalidCalendarOptions) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	hash := sha256.Sum256([]byte(rendered.ics))
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	rendered.setHeaders(w.Header())
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:16])))
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(CalendarRefreshInterval/time.Second)))
+	http.ServeContent(w, r, name, rendered.modified, strings.NewReader(rendered.ics))
+}
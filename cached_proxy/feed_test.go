@@ -0,0 +1,120 @@
+package main
+
+import (
+	account2 "cached_proxy/account"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeRenderer 返回固定内容的日历
+type fakeRenderer struct {
+	rendered *renderedCalendar
+	err      error
+}
+
+func (f *fakeRenderer) render(_ context.Context, _ string, _ url.Values) (*renderedCalendar, error) {
+	return f.rendered, f.err
+}
+
+// fakeFeedService 只有一个固定密钥的订阅服务
+type fakeFeedService struct {
+	account2.FeedService
+	secret  string
+	account account2.Account
+}
+
+func (f *fakeFeedService) GetAccountByFeedSecret(secret string) (account2.Account, error) {
+	if secret != f.secret {
+		return nil, errCalendarUpdating
+	}
+	return f.account, nil
+}
+
+func TestFeedGetter_ServeFeed(t *testing.T) {
+	modified := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	getter := &FeedGetter{
+		feeds: &fakeFeedService{secret: "secret", account: &account2.SimpleAccountImpl{Username: "user1"}},
+		calendars: map[string]calendarRenderer{
+			"courses.ics": &fakeRenderer{rendered: &renderedCalendar{ics: "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n", modified: modified}},
+			"exams.ics":   &fakeRenderer{err: errCalendarUpdating},
+		},
+	}
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		getter.ServeFeed(w, r)
+		return w
+	}
+
+	w := serve("/feeds/secret/courses.ics", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n" {
+		t.Fatalf("expected calendar, got %d %q", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+		t.Errorf("expected ETag and Last-Modified, got %v", w.Header())
+	}
+	if w := serve("/feeds/secret/courses.ics", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 with If-None-Match, got %d", w.Code)
+	}
+	if w := serve("/feeds/secret/exams.ics", nil); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 503 with Retry-After, got %d", w.Code)
+	}
+	if w := serve("/feeds/wrong/courses.ics", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for wrong secret, got %d", w.Code)
+	}
+	if w := serve("/feeds/secret/unknown.ics", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown feed, got %d", w.Code)
+	}
+}
+
+func TestBaseUrl(t *testing.T) {
+	defer func(publicUrl string, allowedHosts []string) {
+		PublicUrl, AllowedHosts = publicUrl, allowedHosts
+	}(PublicUrl, AllowedHosts)
+	tests := []struct {
+		name         string
+		publicUrl    string
+		allowedHosts []string
+		host         string
+		proto        string
+		want         string
+		wantErr      bool
+	}{
+		{"PublicUrl", "https://cal.example.com/", nil, "evil.com", "https", "https://cal.example.com", false},
+		{"No configuration", "", nil, "example.com", "https", "", true},
+		{"Allowed host", "", []string{"example.com"}, "Example.com", "https", "https://Example.com", false},
+		{"Host not allowed", "", []string{"example.com"}, "evil.com", "https", "", true},
+		{"Allowed host with port", "", []string{"example.com:8080"}, "example.com:8080", "", "http://example.com:8080", false},
+		{"Invalid proto", "", []string{"example.com"}, "example.com", "javascript", "http://example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PublicUrl, AllowedHosts = tt.publicUrl, tt.allowedHosts
+			r := httptest.NewRequest(http.MethodGet, "/feeds", nil)
+			r.Host = tt.host
+			r.Header.Set("X-Forwarded-Proto", tt.proto)
+			got, err := baseUrl(r)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("baseUrl() = %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewFeedLinks(t *testing.T) {
+	links := newFeedLinks("https://example.com", "secret")
+	if links.Courses != "https://example.com/feeds/secret/courses.ics" {
+		t.Errorf("unexpected courses link: %s", links.Courses)
+	}
+	if links.WebcalExams != "webcal://example.com/feeds/secret/exams.ics" {
+		t.Errorf("unexpected webcal link: %s", links.WebcalExams)
+	}
+}
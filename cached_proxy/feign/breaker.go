@@ -0,0 +1,288 @@
+package feign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 表示熔断器处于打开状态，请求没有发送到爬虫服务
+var ErrCircuitOpen = fmt.Errorf("%w: circuit open", ErrUpstreamUnavailable)
+
+// Backoff 是带有随机抖动的指数退避
+type Backoff struct {
+	Base time.Duration // 第一次退避的时间
+	Max  time.Duration // 退避时间的上限
+}
+
+// Delay 返回第 attempt 次（从 0 开始）退避的时间，在指数退避时间的一半到全部之间随机取值
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Max
+	if attempt < 32 && b.Base<<attempt > 0 && b.Base<<attempt < b.Max {
+		delay = b.Base << attempt
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryBackoff 是 doGetter 和 refreshDynamicToken 重试之间的退避
+var retryBackoff = Backoff{Base: 200 * time.Millisecond, Max: 2 * time.Second}
+
+// BreakerState 是熔断器的状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常放行请求
+	BreakerOpen                         // 拒绝所有请求，直到退避时间结束
+	BreakerHalfOpen                     // 放行一个探测请求，成功后关闭，失败后重新打开
+)
+
+// String 返回熔断器状态的名称
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// MarshalText 在 JSON 中使用熔断器状态的名称
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// BreakerConfig 是熔断器的配置
+type BreakerConfig struct {
+	FailureThreshold int     // 连续失败多少次后打开
+	OpenBackoff      Backoff // 打开的时间，连续打开时按照指数增长
+}
+
+// DefaultBreakerConfig 是默认的熔断器配置
+var DefaultBreakerConfig = BreakerConfig{
+	FailureThreshold: 5,
+	OpenBackoff:      Backoff{Base: 5 * time.Second, Max: 5 * time.Minute},
+}
+
+// BreakerSnapshot 是熔断器某一时刻的状态
+type BreakerSnapshot struct {
+	State    BreakerState `json:"state"`
+	Failures int          `json:"failures"`           // 连续失败的次数
+	RetryAt  *time.Time   `json:"retry_at,omitempty"` // 打开时下一次探测的时间
+	Trips    uint64       `json:"trips"`              // 累计打开的次数
+	Rejected uint64       `json:"rejected"`           // 累计拒绝的请求数
+}
+
+// Breaker 是单个接口的熔断器
+type Breaker struct {
+	config   BreakerConfig
+	now      func() time.Time
+	mu       sync.Mutex
+	state    BreakerState
+	failures int       // 关闭状态下连续失败的次数
+	opens    int       // 连续打开的次数，用于计算退避时间，关闭后清零
+	retryAt  time.Time // 打开状态结束的时间
+	probing  bool      // 半开状态下是否已经有探测请求
+	trips    uint64
+	rejected uint64
+}
+
+// NewBreaker 创建熔断器
+func NewBreaker(config BreakerConfig) *Breaker {
+	return &Breaker{config: config, now: time.Now}
+}
+
+// allow 判断是否放行请求，打开状态的退避时间结束后转为半开状态并放行一个探测请求
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && !b.now().Before(b.retryAt) {
+		b.state, b.probing = BreakerHalfOpen, false
+	}
+	switch b.state {
+	case BreakerOpen:
+		b.rejected++
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record 记录请求的结果，只有爬虫服务不可用的错误计为失败
+//
+// 调用方取消的请求没有得到爬虫服务的结果，只释放探测请求，不改变状态和计数。
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if errors.Is(err, context.Canceled) {
+		b.probing = false
+		return
+	}
+	if !isUpstreamFailure(err) {
+		b.state, b.failures, b.opens, b.probing = BreakerClosed, 0, 0, false
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.trip()
+	}
+}
+
+// trip 打开熔断器，调用前需要持有锁
+func (b *Breaker) trip() {
+	b.retryAt = b.now().Add(b.config.OpenBackoff.Delay(b.opens))
+	b.state, b.probing = BreakerOpen, false
+	b.opens++
+	b.trips++
+}
+
+// Snapshot 返回熔断器当前的状态
+func (b *Breaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snapshot := BreakerSnapshot{State: b.state, Failures: b.failures, Trips: b.trips, Rejected: b.rejected}
+	if b.state == BreakerOpen {
+		retryAt := b.retryAt
+		snapshot.RetryAt = &retryAt
+	}
+	return snapshot
+}
+
+// isUpstreamFailure 判断错误是否说明爬虫服务或教务系统不可用，账户错误、验证码错误和调用方取消的请求不计入
+func isUpstreamFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var urlErr *url.Error
+	return errors.Is(err, ErrUpstreamUnavailable) || errors.Is(err, ErrRateLimited) || errors.As(err, &urlErr)
+}
+
+// guard 通过 endpoint 的熔断器调用爬虫服务，并记录请求的指标
+func guard[V any](c *BreakerClient, endpoint string, call func() (V, error)) (V, error) {
+	b := c.breakers[endpoint]
+	if err := b.allow(); err != nil {
+		spiderRequestsMetric.Inc(endpoint, callResult(err))
+		var zero V
+		return zero, err
+	}
+	start := time.Now()
+	value, err := call()
+	observeCall(endpoint, start, err)
+	b.record(err)
+	return value, err
+}
+
+// 爬虫服务的接口名称，每个接口使用独立的熔断器和限流
+const (
+	EndpointLogin     = "login"
+	EndpointCalendar  = "calendar"
+	EndpointClassroom = "classroom"
+	EndpointCourses   = "courses"
+	EndpointExams     = "exams"
+	EndpointInfo      = "info"
+	EndpointScores    = "scores"
+	EndpointRank      = "rank"
+)
+
+// BreakerClient 是为每个接口添加熔断器的 SpiderClient
+type BreakerClient struct {
+	client   SpiderClient
+	breakers map[string]*Breaker
+}
+
+// NewBreakerClient 为 client 的每个接口创建熔断器
+func NewBreakerClient(client SpiderClient, config BreakerConfig) *BreakerClient {
+	b := &BreakerClient{client: client, breakers: map[string]*Breaker{}}
+	for _, endpoint := range []string{EndpointLogin, EndpointCalendar, EndpointClassroom, EndpointCourses,
+		EndpointExams, EndpointInfo, EndpointScores, EndpointRank} {
+		b.breakers[endpoint] = NewBreaker(config)
+	}
+	return b
+}
+
+// Snapshot 返回每个接口的熔断器状态
+func (b *BreakerClient) Snapshot() map[string]BreakerSnapshot {
+	snapshots := make(map[string]BreakerSnapshot, len(b.breakers))
+	for endpoint, breaker := range b.breakers {
+		snapshots[endpoint] = breaker.Snapshot()
+	}
+	return snapshots
+}
+
+// Endpoints 返回按名称排序的接口列表
+func (b *BreakerClient) Endpoints() []string {
+	endpoints := make([]string, 0, len(b.breakers))
+	for endpoint := range b.breakers {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+func (b *BreakerClient) GetTeachingCalendar(ctx context.Context, token string) (*TeachingCalendar, error) {
+	return guard(b, EndpointCalendar, func() (*TeachingCalendar, error) {
+		return b.client.GetTeachingCalendar(ctx, token)
+	})
+}
+
+func (b *BreakerClient) GetClassroomStatus(ctx context.Context, token string, day int) (*ClassroomStatusTable, error) {
+	return guard(b, EndpointClassroom, func() (*ClassroomStatusTable, error) {
+		return b.client.GetClassroomStatus(ctx, token, day)
+	})
+}
+
+func (b *BreakerClient) GetStudentCourses(ctx context.Context, token string) (*CourseList, error) {
+	return guard(b, EndpointCourses, func() (*CourseList, error) {
+		return b.client.GetStudentCourses(ctx, token)
+	})
+}
+
+func (b *BreakerClient) GetStudentExams(ctx context.Context, token string) (*ExamList, error) {
+	return guard(b, EndpointExams, func() (*ExamList, error) {
+		return b.client.GetStudentExams(ctx, token)
+	})
+}
+
+func (b *BreakerClient) GetStudentInfo(ctx context.Context, token string) (*StudentInfo, error) {
+	return guard(b, EndpointInfo, func() (*StudentInfo, error) {
+		return b.client.GetStudentInfo(ctx, token)
+	})
+}
+
+func (b *BreakerClient) Login(ctx context.Context, username string, password string) (LoginResponse, error) {
+	return guard(b, EndpointLogin, func() (LoginResponse, error) {
+		return b.client.Login(ctx, username, password)
+	})
+}
+
+func (b *BreakerClient) GetStudentScore(ctx context.Context, token string, isMajor bool) (*ScoreBoard, error) {
+	return guard(b, EndpointScores, func() (*ScoreBoard, error) {
+		return b.client.GetStudentScore(ctx, token, isMajor)
+	})
+}
+
+func (b *BreakerClient) GetStudentRank(ctx context.Context, token string, onlyRequired bool) (*Rank, error) {
+	return guard(b, EndpointRank, func() (*Rank, error) {
+		return b.client.GetStudentRank(ctx, token, onlyRequired)
+	})
+}
+
+// NewStudent 创建学生账户，学生的所有请求都经过熔断器
+func (b *BreakerClient) NewStudent(ctx context.Context, username string, password string) (Student, error) {
+	return NewStudentImpl(ctx, username, password, b)
+}
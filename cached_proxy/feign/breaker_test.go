@@ -0,0 +1,156 @@
+package feign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	backoff := Backoff{Base: time.Second, Max: 10 * time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, time.Second}, {1, 2 * time.Second}, {3, 8 * time.Second}, {4, 10 * time.Second}, {100, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := backoff.Delay(tt.attempt); got < tt.max/2 || got > tt.max {
+				t.Errorf("Delay(%d) = %v, want between %v and %v", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+	if got := (Backoff{}).Delay(3); got != 0 {
+		t.Errorf("zero backoff should not wait, got %v", got)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	breaker := NewBreaker(BreakerConfig{FailureThreshold: 2, OpenBackoff: Backoff{Base: 10 * time.Second, Max: time.Minute}})
+	breaker.now = func() time.Time { return now }
+
+	// 账户错误不计为失败
+	breaker.record(ErrUnauthorized)
+	breaker.record(ErrUpstreamUnavailable)
+	if s := breaker.Snapshot(); s.State != BreakerClosed || s.Failures != 1 {
+		t.Fatalf("unexpected snapshot %+v", s)
+	}
+	breaker.record(ErrRateLimited)
+	s := breaker.Snapshot()
+	if s.State != BreakerOpen || s.Trips != 1 || s.RetryAt.Before(now.Add(5*time.Second)) || s.RetryAt.After(now.Add(10*time.Second)) {
+		t.Fatalf("breaker should be open, got %+v", s)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Errorf("expected circuit open, got %v", err)
+	}
+
+	// 退避结束后只放行一个探测请求
+	now = *s.RetryAt
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("probe should be allowed, got %v", err)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("only one probe should be allowed, got %v", err)
+	}
+	breaker.record(ErrUpstreamUnavailable)
+	s = breaker.Snapshot()
+	if s.State != BreakerOpen || s.Trips != 2 || s.RetryAt.Before(now.Add(10*time.Second)) || s.RetryAt.After(now.Add(20*time.Second)) {
+		t.Fatalf("failed probe should reopen with longer backoff, got %+v", s)
+	}
+
+	now = *s.RetryAt
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("probe should be allowed, got %v", err)
+	}
+	breaker.record(nil)
+	if s := breaker.Snapshot(); s.State != BreakerClosed || s.Failures != 0 || s.Rejected != 2 || s.RetryAt != nil {
+		t.Errorf("successful probe should close the breaker, got %+v", s)
+	}
+}
+
+func TestBreaker_Canceled(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	breaker := NewBreaker(BreakerConfig{FailureThreshold: 2, OpenBackoff: Backoff{Base: 10 * time.Second, Max: time.Minute}})
+	breaker.now = func() time.Time { return now }
+
+	// 关闭状态下取消的请求不清空连续失败的次数
+	breaker.record(ErrUpstreamUnavailable)
+	breaker.record(context.Canceled)
+	if s := breaker.Snapshot(); s.State != BreakerClosed || s.Failures != 1 {
+		t.Fatalf("canceled request should keep the failure streak, got %+v", s)
+	}
+	breaker.record(ErrUpstreamUnavailable)
+	s := breaker.Snapshot()
+	if s.State != BreakerOpen {
+		t.Fatalf("breaker should be open, got %+v", s)
+	}
+
+	// 半开状态下探测请求被取消，保持半开并允许下一个探测请求
+	now = *s.RetryAt
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("probe should be allowed, got %v", err)
+	}
+	breaker.record(fmt.Errorf("probe: %w", context.Canceled))
+	if s := breaker.Snapshot(); s.State != BreakerHalfOpen || s.Trips != 1 {
+		t.Fatalf("canceled probe should keep the breaker half open, got %+v", s)
+	}
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("another probe should be allowed after cancellation, got %v", err)
+	}
+	// 退避仍然按照连续打开的次数增长
+	breaker.record(ErrUpstreamUnavailable)
+	s = breaker.Snapshot()
+	if s.State != BreakerOpen || s.Trips != 2 || s.RetryAt.Before(now.Add(10*time.Second)) {
+		t.Errorf("failed probe should reopen with longer backoff, got %+v", s)
+	}
+}
+
+func TestBreakerClient(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/login" {
+			_, _ = w.Write([]byte(`{"code":1,"message":"success","data":{"token":"token"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	backoff := retryBackoff
+	retryBackoff = Backoff{}
+	defer func() { retryBackoff = backoff }()
+
+	client := NewBreakerClient(NewSpiderClientImpl(server.URL, http.Client{}), BreakerConfig{
+		FailureThreshold: 2, OpenBackoff: Backoff{Base: time.Minute, Max: time.Minute},
+	})
+	student, err := client.NewStudent(context.Background(), "user", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls = 0
+	unavailable, rejected := spiderRequestsMetric.Get(EndpointExams, "unavailable"), spiderRequestsMetric.Get(EndpointExams, "circuit_open")
+	// 第三次重试时熔断器已经打开，请求没有发送
+	if _, err = student.GetStudentExams(context.Background()); !errors.Is(err, ErrCircuitOpen) || calls != 2 {
+		t.Errorf("expected circuit open after 2 calls, got %v after %d calls", err, calls)
+	}
+	if spiderRequestsMetric.Get(EndpointExams, "unavailable")-unavailable != 2 || spiderRequestsMetric.Get(EndpointExams, "circuit_open")-rejected != 1 {
+		t.Error("requests should be counted by result")
+	}
+	calls = 0
+	if _, err = student.GetInfo(context.Background()); calls != 2 {
+		t.Errorf("breakers of other endpoints should be independent, got %v after %d calls", err, calls)
+	}
+	snapshot := client.Snapshot()
+	if snapshot[EndpointExams].State != BreakerOpen || snapshot[EndpointLogin].State != BreakerClosed {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+	if endpoints := client.Endpoints(); len(endpoints) != 8 || endpoints[0] != EndpointCalendar {
+		t.Errorf("unexpected endpoints %v", endpoints)
+	}
+}
@@ -0,0 +1,40 @@
+package feign
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 爬虫服务返回的错误，调用方使用 errors.Is 判断
+var (
+	// ErrUnauthorized 账户或密码错误，或者 token 失效
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUpstreamUnavailable 教务系统或爬虫服务暂时不可用
+	ErrUpstreamUnavailable = errors.New("service unavailable")
+	// ErrCaptcha 登陆教务系统时验证码识别失败，可以重试
+	ErrCaptcha = errors.New("captcha recognition failed")
+	// ErrRateLimited 请求过于频繁，被爬虫服务限流
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// captchaMessage 是爬虫服务在验证码识别失败时返回的信息中包含的内容
+const captchaMessage = "验证码"
+
+// statusError 根据爬虫服务的状态码返回对应的错误，503 时通过返回的信息区分验证码错误
+func statusError(response *http.Response) error {
+	switch response.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusServiceUnavailable:
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		if strings.Contains(string(message), captchaMessage) {
+			return ErrCaptcha
+		}
+		return ErrUpstreamUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package feign
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSpiderClient_StatusErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"Unauthorized", http.StatusUnauthorized, "unauthorized", ErrUnauthorized},
+		{"RateLimited", http.StatusTooManyRequests, "", ErrRateLimited},
+		{"Captcha", http.StatusServiceUnavailable, "【202305550001】登陆时验证码识别错误", ErrCaptcha},
+		{"Unavailable", http.StatusServiceUnavailable, "远程连接错误", ErrUpstreamUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			_, err := NewSpiderClientImpl(server.URL, http.Client{}).Login(context.Background(), "user", "password")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStudentImpl_Retry(t *testing.T) {
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		switch {
+		case r.URL.Path == "/login":
+			_, _ = w.Write([]byte(`{"code":1,"message":"success","data":{"token":"token"}}`))
+		case r.URL.Path == "/calendar" && calls[r.URL.Path] == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("验证码识别错误"))
+		case r.URL.Path == "/calendar":
+			_, _ = w.Write([]byte(`{"code":1,"message":"success","data":{"start":"2025-02-17","weeks":18}}`))
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+	backoff := retryBackoff
+	retryBackoff = Backoff{}
+	defer func() { retryBackoff = backoff }()
+	client := NewSpiderClientImpl(server.URL, http.Client{})
+	student, err := NewStudentImpl(context.Background(), "user", "password", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar, err := student.GetTeachingCalendar(context.Background())
+	if err != nil || calendar.Start != "2025-02-17" || calls["/calendar"] != 2 {
+		t.Errorf("captcha error should be retried, got %+v %v after %d calls", calendar, err, calls["/calendar"])
+	}
+	// 被限流时不重试
+	_, err = student.GetInfo(context.Background())
+	if !errors.Is(err, ErrRateLimited) || calls["/info"] != 1 {
+		t.Errorf("expected rate limited error without retry, got %v after %d calls", err, calls["/info"])
+	}
+}
+
+func TestStudentImpl_Canceled(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			_, _ = w.Write([]byte(`{"code":1,"message":"success","data":{"token":"token"}}`))
+			return
+		}
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	student, err := NewStudentImpl(context.Background(), "user", "password", NewSpiderClientImpl(server.URL, http.Client{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 退避期间取消时立即返回，不再重试
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	backoff := retryBackoff
+	retryBackoff = Backoff{Base: time.Minute, Max: time.Minute}
+	defer func() { retryBackoff = backoff }()
+	start := time.Now()
+	_, err = student.GetInfo(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || calls != 1 || time.Since(start) > 10*time.Second {
+		t.Errorf("expected deadline exceeded after 1 call, got %v after %d calls", err, calls)
+	}
+}
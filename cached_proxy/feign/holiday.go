@@ -0,0 +1,152 @@
+package feign
+
+import (
+	"cached_proxy/repo"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+// Holiday 表示一个放假日，当天的课程全部取消
+type Holiday struct {
+	Date string `json:"date"` // 放假日期，格式为 2006-01-02
+	Name string `json:"name"` // 节日名称
+}
+
+// Adjustment 表示一个调休日，当天按照 Follow 日期的课表上课
+type Adjustment struct {
+	Date   string `json:"date"`   // 调休上课的日期
+	Follow string `json:"follow"` // 被补上的日期，调休日将按照该日期所在周次和星期的课表上课
+	Name   string `json:"name"`   // 调休说明
+}
+
+// HolidayCalendar 某个学期的节假日和调休安排
+type HolidayCalendar struct {
+	TermId      string       `json:"term_id"`
+	Holidays    []Holiday    `json:"holidays"`
+	Adjustments []Adjustment `json:"adjustments"`
+}
+
+// IsHoliday 判断某一天是否放假
+func (h *HolidayCalendar) IsHoliday(date time.Time) bool {
+	if h == nil {
+		return false
+	}
+	day := date.Format(DateLayout)
+	for _, holiday := range h.Holidays {
+		if holiday.Date == day {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdjusted 判断某一天是否为调休日，调休日原本的课程将被替换
+func (h *HolidayCalendar) IsAdjusted(date time.Time) bool {
+	if h == nil {
+		return false
+	}
+	day := date.Format(DateLayout)
+	for _, adjustment := range h.Adjustments {
+		if adjustment.Date == day {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCancelled 判断某一天原本的课程是否取消，放假日和调休日的原课程都会被取消
+func (h *HolidayCalendar) IsCancelled(date time.Time) bool {
+	return h.IsHoliday(date) || h.IsAdjusted(date)
+}
+
+// validate 校验配置中的日期格式
+func (h *HolidayCalendar) validate() error {
+	for _, holiday := range h.Holidays {
+		if _, err := time.Parse(DateLayout, holiday.Date); err != nil {
+			return fmt.Errorf("invalid holiday date %q: %w", holiday.Date, err)
+		}
+	}
+	for _, adjustment := range h.Adjustments {
+		if _, err := time.Parse(DateLayout, adjustment.Date); err != nil {
+			return fmt.Errorf("invalid adjustment date %q: %w", adjustment.Date, err)
+		}
+		if _, err := time.Parse(DateLayout, adjustment.Follow); err != nil {
+			return fmt.Errorf("invalid adjustment follow date %q: %w", adjustment.Follow, err)
+		}
+	}
+	return nil
+}
+
+// LoadHolidayCalendar 从 JSON 文件中加载节假日安排
+func LoadHolidayCalendar(filePath string) (*HolidayCalendar, error) {
+	bytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var calendar HolidayCalendar
+	if err := json.Unmarshal(bytes, &calendar); err != nil {
+		return nil, fmt.Errorf("failed to decode holiday calendar %s: %w", filePath, err)
+	}
+	if err := calendar.validate(); err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// HolidayService 节假日安排服务
+type HolidayService interface {
+	// GetHolidayCalendar 获取指定学期的节假日安排，如果没有配置，则返回 nil
+	GetHolidayCalendar(termId string) *HolidayCalendar
+}
+
+// FileHolidayService 从目录中读取节假日安排，每个学期一个 <term_id>.json 文件
+//
+// 文件按修改时间缓存，新增、修改或删除文件后不需要重启即可生效。
+type FileHolidayService struct {
+	dir   string
+	cache repo.KVRepo[string, holidayFile]
+}
+
+// holidayFile 是缓存的节假日安排文件，calendar 为 nil 表示文件无法解析
+type holidayFile struct {
+	calendar *HolidayCalendar
+	modTime  time.Time // 读取时文件的修改时间
+}
+
+// NewFileHolidayService 创建基于文件的节假日安排服务
+func NewFileHolidayService(dir string) *FileHolidayService {
+	return &FileHolidayService{dir: dir, cache: repo.NewMemRepo[string, holidayFile]()}
+}
+
+func (s *FileHolidayService) GetHolidayCalendar(termId string) *HolidayCalendar {
+	if termId == "" {
+		return nil
+	}
+	filePath := path.Join(s.dir, termId+".json")
+	info, err := os.Stat(filePath)
+	if err != nil {
+		// 文件不存在时不缓存，之后添加的文件可以被读取
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to load holiday calendar", "term", termId, "error", err)
+		}
+		s.cache.Delete(termId)
+		return nil
+	}
+	if cached, found := s.cache.Get(termId); found && cached.modTime.Equal(info.ModTime()) {
+		return cached.calendar
+	}
+	calendar, err := LoadHolidayCalendar(filePath)
+	if err != nil {
+		// 无法解析的文件同样按修改时间缓存，修改后重新读取，避免每次请求都输出日志
+		slog.Warn("failed to load holiday calendar", "term", termId, "error", err)
+		calendar = nil
+	}
+	s.cache.Set(termId, holidayFile{calendar: calendar, modTime: info.ModTime()})
+	return calendar
+}
@@ -0,0 +1,92 @@
+package feign
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestFileHolidayService_GetHolidayCalendar(t *testing.T) {
+	service := NewFileHolidayService("testdata/holidays")
+	t.Run("Term configured", func(t *testing.T) {
+		calendar := service.GetHolidayCalendar("2024-2025-2")
+		if calendar == nil {
+			t.Fatalf("expected holiday calendar, got nil")
+		}
+		if len(calendar.Holidays) != 5 || len(calendar.Adjustments) != 1 {
+			t.Errorf("unexpected holiday calendar: %+v", calendar)
+		}
+	})
+	t.Run("Term not configured", func(t *testing.T) {
+		if calendar := service.GetHolidayCalendar("2099-2100-1"); calendar != nil {
+			t.Errorf("expected nil, got %+v", calendar)
+		}
+	})
+}
+
+func TestFileHolidayService_Reload(t *testing.T) {
+	valid, err := os.ReadFile("testdata/holidays/2024-2025-2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	filePath := path.Join(dir, "2024-2025-2.json")
+	service := NewFileHolidayService(dir)
+	// 每一步设置不同的修改时间，避免文件系统的时间精度不足
+	modTime := time.Now().Add(-time.Hour)
+	write := func(content []byte) {
+		if err := os.WriteFile(filePath, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modTime = modTime.Add(time.Minute)
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		name    string
+		setup   func()
+		wantNil bool
+	}{
+		{"File missing", func() {}, true},
+		{"File appears", func() { write(valid) }, false},
+		{"File broken", func() { write([]byte("{")) }, true},
+		{"File fixed", func() { write(valid) }, false},
+		{"File removed", func() { _ = os.Remove(filePath) }, true},
+	}
+	for _, step := range steps {
+		step.setup()
+		if calendar := service.GetHolidayCalendar("2024-2025-2"); (calendar == nil) != step.wantNil {
+			t.Errorf("%s: got %+v, want nil %v", step.name, calendar, step.wantNil)
+		}
+	}
+}
+
+func TestHolidayCalendar_IsCancelled(t *testing.T) {
+	calendar, err := LoadHolidayCalendar("testdata/holidays/2024-2025-2.json")
+	if err != nil {
+		t.Fatalf("failed to load holiday calendar: %v", err)
+	}
+	tests := []struct {
+		name string
+		date time.Time
+		want bool
+	}{
+		{name: "Holiday", date: time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "Adjustment", date: time.Date(2025, 4, 27, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "Normal day", date: time.Date(2025, 4, 3, 0, 0, 0, 0, time.UTC), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.IsCancelled(tt.date); got != tt.want {
+				t.Errorf("IsCancelled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	var empty *HolidayCalendar
+	if empty.IsCancelled(time.Now()) {
+		t.Errorf("nil holiday calendar should not cancel any day")
+	}
+}
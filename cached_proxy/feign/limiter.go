@@ -0,0 +1,171 @@
+package feign
+
+import (
+	"cached_proxy/ratelimit"
+	"context"
+	"fmt"
+	"time"
+)
+
+// RateLimitError 表示请求被代理自身的限流拒绝，没有发送到爬虫服务
+type RateLimitError struct {
+	Scope string        // 拒绝请求的限流范围：global、account 或接口名称
+	Wait  time.Duration // 需要等待的时间
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: %s limit exceeded, retry after %v", ErrRateLimited, e.Scope, e.Wait)
+}
+
+// Unwrap 使 errors.Is(err, ErrRateLimited) 成立
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// LimiterConfig 是请求爬虫服务的限流配置
+type LimiterConfig struct {
+	Global    ratelimit.Limit            // 所有请求共享的限流
+	Account   ratelimit.Limit            // 每个账户的限流
+	Endpoints map[string]ratelimit.Limit // 每个接口所有账户共享的限流，例如登录
+}
+
+// DefaultLimiterConfig 是默认的限流配置，登录会触发教务系统的验证码，单独限制
+var DefaultLimiterConfig = LimiterConfig{
+	Global:    ratelimit.Limit{Rate: 20, Burst: 40},
+	Account:   ratelimit.Limit{Rate: 1, Burst: 10},
+	Endpoints: map[string]ratelimit.Limit{EndpointLogin: {Rate: 0.5, Burst: 5}},
+}
+
+// limiters 是 LimitedClient 及其按账户区分的副本共享的限流器
+type limiters struct {
+	global    *ratelimit.Limiter
+	account   *ratelimit.Limiter
+	endpoints map[string]*ratelimit.Limiter
+}
+
+// LimitedClient 是为请求添加限流的 SpiderClient，被限流的请求返回 RateLimitError
+//
+// SpiderClient 的方法只有动态 token，无法得知账户，因此按账户限流需要通过 ForAccount 得到该账户的副本，
+// 学生的请求都经过这个副本。Login 总是按照用户名限流。
+type LimitedClient struct {
+	*limiters
+	client  SpiderClient
+	account string
+}
+
+// NewLimitedClient 为 client 添加限流
+func NewLimitedClient(client SpiderClient, config LimiterConfig) *LimitedClient {
+	l := &limiters{
+		global:    ratelimit.NewLimiter(config.Global),
+		account:   ratelimit.NewLimiter(config.Account),
+		endpoints: map[string]*ratelimit.Limiter{},
+	}
+	for endpoint, limit := range config.Endpoints {
+		l.endpoints[endpoint] = ratelimit.NewLimiter(limit)
+	}
+	return &LimitedClient{limiters: l, client: client}
+}
+
+// ForAccount 返回按 username 限流的副本，与原客户端共享全局和接口的限流
+func (l *LimitedClient) ForAccount(username string) SpiderClient {
+	return &LimitedClient{limiters: l.limiters, client: l.client, account: username}
+}
+
+// allow 依次检查账户、接口和全局的限流，先检查范围小的限流，避免单个账户耗尽全局的配额
+func (l *limiters) allow(endpoint string, account string) error {
+	if limitErr := l.check(endpoint, account); limitErr != nil {
+		spiderThrottledMetric.Inc(limitErr.Scope)
+		return limitErr
+	}
+	return nil
+}
+
+func (l *limiters) check(endpoint string, account string) *RateLimitError {
+	if account != "" {
+		if ok, wait := l.account.Allow(account); !ok {
+			return &RateLimitError{Scope: "account", Wait: wait}
+		}
+	}
+	if ok, wait := l.endpoints[endpoint].Allow(""); !ok {
+		return &RateLimitError{Scope: endpoint, Wait: wait}
+	}
+	if ok, wait := l.global.Allow(""); !ok {
+		return &RateLimitError{Scope: "global", Wait: wait}
+	}
+	return nil
+}
+
+// throttle 通过限流调用爬虫服务
+func throttle[V any](l *LimitedClient, endpoint string, account string, call func() (V, error)) (V, error) {
+	if err := l.allow(endpoint, account); err != nil {
+		var zero V
+		return zero, err
+	}
+	return call()
+}
+
+func (l *LimitedClient) GetTeachingCalendar(ctx context.Context, token string) (*TeachingCalendar, error) {
+	return throttle(l, EndpointCalendar, l.account, func() (*TeachingCalendar, error) {
+		return l.client.GetTeachingCalendar(ctx, token)
+	})
+}
+
+func (l *LimitedClient) GetClassroomStatus(ctx context.Context, token string, day int) (*ClassroomStatusTable, error) {
+	return throttle(l, EndpointClassroom, l.account, func() (*ClassroomStatusTable, error) {
+		return l.client.GetClassroomStatus(ctx, token, day)
+	})
+}
+
+func (l *LimitedClient) GetStudentCourses(ctx context.Context, token string) (*CourseList, error) {
+	return throttle(l, EndpointCourses, l.account, func() (*CourseList, error) {
+		return l.client.GetStudentCourses(ctx, token)
+	})
+}
+
+func (l *LimitedClient) GetStudentExams(ctx context.Context, token string) (*ExamList, error) {
+	return throttle(l, EndpointExams, l.account, func() (*ExamList, error) {
+		return l.client.GetStudentExams(ctx, token)
+	})
+}
+
+func (l *LimitedClient) GetStudentInfo(ctx context.Context, token string) (*StudentInfo, error) {
+	return throttle(l, EndpointInfo, l.account, func() (*StudentInfo, error) {
+		return l.client.GetStudentInfo(ctx, token)
+	})
+}
+
+func (l *LimitedClient) Login(ctx context.Context, username string, password string) (LoginResponse, error) {
+	return throttle(l, EndpointLogin, username, func() (LoginResponse, error) {
+		return l.client.Login(ctx, username, password)
+	})
+}
+
+func (l *LimitedClient) GetStudentScore(ctx context.Context, token string, isMajor bool) (*ScoreBoard, error) {
+	return throttle(l, EndpointScores, l.account, func() (*ScoreBoard, error) {
+		return l.client.GetStudentScore(ctx, token, isMajor)
+	})
+}
+
+func (l *LimitedClient) GetStudentRank(ctx context.Context, token string, onlyRequired bool) (*Rank, error) {
+	return throttle(l, EndpointRank, l.account, func() (*Rank, error) {
+		return l.client.GetStudentRank(ctx, token, onlyRequired)
+	})
+}
+
+// NewStudent 创建学生账户，学生的所有请求都按照该账户限流
+func (l *LimitedClient) NewStudent(ctx context.Context, username string, password string) (Student, error) {
+	return NewStudentImpl(ctx, username, password, l.ForAccount(username))
+}
+
+// accountScoped 由可以按账户区分请求的 SpiderClient 实现
+type accountScoped interface {
+	ForAccount(username string) SpiderClient
+}
+
+// forAccount 返回 client 按 username 区分的副本，不支持时返回 client 本身
+func forAccount(client SpiderClient, username string) SpiderClient {
+	if scoped, ok := client.(accountScoped); ok {
+		return scoped.ForAccount(username)
+	}
+	return client
+}
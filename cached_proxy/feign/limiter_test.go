@@ -0,0 +1,143 @@
+package feign
+
+import (
+	"cached_proxy/ratelimit"
+	"context"
+	"errors"
+	"testing"
+)
+
+// countingClient 只实现测试用到的接口，记录发送到爬虫服务的请求数
+type countingClient struct {
+	SpiderClient
+	calls int
+}
+
+func (c *countingClient) Login(context.Context, string, string) (LoginResponse, error) {
+	c.calls++
+	return LoginResponse{Token: "token"}, nil
+}
+
+func (c *countingClient) GetStudentInfo(context.Context, string) (*StudentInfo, error) {
+	c.calls++
+	return &StudentInfo{}, nil
+}
+
+func TestLimitedClient(t *testing.T) {
+	// 速率很低，测试期间不会补充令牌
+	slow := func(burst int) ratelimit.Limit { return ratelimit.Limit{Rate: 0.001, Burst: burst} }
+	tests := []struct {
+		name      string
+		config    LimiterConfig
+		calls     func(l *LimitedClient) error
+		wantScope string
+		wantCalls int
+	}{
+		{
+			name:   "account",
+			config: LimiterConfig{Account: slow(2)},
+			calls: func(l *LimitedClient) error {
+				alice, bob := l.ForAccount("alice"), l.ForAccount("bob")
+				_, _ = alice.GetStudentInfo(context.Background(), "a")
+				_, _ = alice.GetStudentInfo(context.Background(), "b")
+				_, _ = bob.GetStudentInfo(context.Background(), "c")
+				_, err := alice.GetStudentInfo(context.Background(), "a")
+				return err
+			},
+			wantScope: "account", wantCalls: 3,
+		},
+		{
+			name:   "login counts against the account",
+			config: LimiterConfig{Account: slow(1)},
+			calls: func(l *LimitedClient) error {
+				_, _ = l.Login(context.Background(), "alice", "password")
+				_, err := l.ForAccount("alice").GetStudentInfo(context.Background(), "a")
+				return err
+			},
+			wantScope: "account", wantCalls: 1,
+		},
+		{
+			name:   "endpoint",
+			config: LimiterConfig{Endpoints: map[string]ratelimit.Limit{EndpointLogin: slow(2)}},
+			calls: func(l *LimitedClient) error {
+				_, _ = l.Login(context.Background(), "alice", "password")
+				_, _ = l.Login(context.Background(), "bob", "password")
+				_, _ = l.GetStudentInfo(context.Background(), "a")
+				_, err := l.Login(context.Background(), "carol", "password")
+				return err
+			},
+			wantScope: EndpointLogin, wantCalls: 3,
+		},
+		{
+			name:   "global",
+			config: LimiterConfig{Global: slow(2)},
+			calls: func(l *LimitedClient) error {
+				_, _ = l.ForAccount("alice").GetStudentInfo(context.Background(), "a")
+				_, _ = l.ForAccount("bob").GetStudentInfo(context.Background(), "b")
+				_, err := l.ForAccount("carol").GetStudentInfo(context.Background(), "c")
+				return err
+			},
+			wantScope: "global", wantCalls: 2,
+		},
+		{
+			name:   "unlimited",
+			config: LimiterConfig{},
+			calls: func(l *LimitedClient) error {
+				for i := 0; i < 100; i++ {
+					if _, err := l.ForAccount("alice").GetStudentInfo(context.Background(), "a"); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			wantCalls: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &countingClient{}
+			err := tt.calls(NewLimitedClient(client, tt.config))
+			if client.calls != tt.wantCalls {
+				t.Errorf("expected %d upstream calls, got %d", tt.wantCalls, client.calls)
+			}
+			if tt.wantScope == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			var limitErr *RateLimitError
+			if !errors.As(err, &limitErr) || !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("expected RateLimitError, got %v", err)
+			}
+			if limitErr.Scope != tt.wantScope || limitErr.Wait <= 0 {
+				t.Errorf("unexpected error %+v", limitErr)
+			}
+		})
+	}
+}
+
+func TestLimitedClient_NewStudent(t *testing.T) {
+	client := &countingClient{}
+	limited := NewLimitedClient(client, LimiterConfig{Account: ratelimit.Limit{Rate: 0.001, Burst: 2}})
+	student, err := limited.NewStudent(context.Background(), "alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = student.GetInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = student.GetInfo(context.Background()); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("student requests should be limited per account, got %v", err)
+	}
+
+	var spider SpiderClient = limited
+	service := NewStudentServiceImpl(&spider)
+	if err = service.SetStudent(context.Background(), "bob", "password", false); err != nil {
+		t.Fatal(err)
+	}
+	bob, _ := service.GetStudent("bob")
+	if impl := (*bob).(*StudentImpl); impl.spider.(*LimitedClient).account != "bob" {
+		t.Error("unverified students should also be limited per account")
+	}
+}
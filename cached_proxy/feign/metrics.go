@@ -0,0 +1,51 @@
+package feign
+
+import (
+	"cached_proxy/metrics"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// spiderRequestsMetric 按接口和结果统计请求爬虫服务的次数，熔断器拒绝的请求记为 circuit_open
+	spiderRequestsMetric = metrics.NewCounterVec("proxy_spider_requests_total",
+		"Spider requests by endpoint and result.", "endpoint", "result")
+	// spiderLatencyMetric 统计实际发送到爬虫服务的请求的耗时
+	spiderLatencyMetric = metrics.NewHistogramVec("proxy_spider_request_duration_seconds",
+		"Latency of requests sent to the spider by endpoint.", nil, "endpoint")
+	// spiderThrottledMetric 统计被代理自身限流拒绝的请求
+	spiderThrottledMetric = metrics.NewCounterVec("proxy_spider_throttled_total",
+		"Spider requests rejected by the proxy rate limiter by scope.", "scope")
+)
+
+func init() {
+	metrics.Default.Register(spiderRequestsMetric, spiderLatencyMetric, spiderThrottledMetric)
+}
+
+// callResult 返回请求结果在指标中的名称
+func callResult(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrCaptcha):
+		return "captcha"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case isUpstreamFailure(err):
+		return "unavailable"
+	}
+	return "error"
+}
+
+// observeCall 记录一次发送到爬虫服务的请求
+func observeCall(endpoint string, start time.Time, err error) {
+	spiderLatencyMetric.ObserveSince(start, endpoint)
+	spiderRequestsMetric.Inc(endpoint, callResult(err))
+}
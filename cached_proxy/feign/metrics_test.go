@@ -0,0 +1,33 @@
+package feign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestCallResult(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "success"},
+		{fmt.Errorf("login: %w", ErrUnauthorized), "unauthorized"},
+		{ErrCaptcha, "captcha"},
+		{&RateLimitError{Scope: "global"}, "rate_limited"},
+		{ErrRateLimited, "rate_limited"},
+		{ErrCircuitOpen, "circuit_open"},
+		{ErrUpstreamUnavailable, "unavailable"},
+		{&url.Error{Op: "Get", URL: "http://spider", Err: errors.New("connection refused")}, "unavailable"},
+		{&url.Error{Op: "Get", URL: "http://spider", Err: context.Canceled}, "canceled"},
+		{&url.Error{Op: "Get", URL: "http://spider", Err: context.DeadlineExceeded}, "unavailable"},
+		{errors.New("返回错误：0"), "error"},
+	}
+	for _, tt := range tests {
+		if got := callResult(tt.err); got != tt.want {
+			t.Errorf("callResult(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
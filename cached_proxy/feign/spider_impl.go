@@ -2,10 +2,14 @@ package feign
 
 import (
 	"bytes"
+	"cached_proxy/logging"
+	"cached_proxy/tracing"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"path"
@@ -18,8 +22,8 @@ type SpiderClientImpl struct {
 	client  http.Client
 }
 
-// buildRequest 构建实际请求
-func (c *SpiderClientImpl) buildRequest(method string, uri string, token string, data any) (*http.Request, error) {
+// buildRequest 构建实际请求，请求随 ctx 取消，ctx 中的请求 ID 通过 X-Request-ID 转发给爬虫服务
+func (c *SpiderClientImpl) buildRequest(ctx context.Context, method string, uri string, token string, data any) (*http.Request, error) {
 	// 参数合法性验证
 	if method == "" || uri == "" {
 		return nil, fmt.Errorf("method 和 uri 都不能为空")
@@ -30,6 +34,9 @@ func (c *SpiderClientImpl) buildRequest(method string, uri string, token string,
 	if token != "" {
 		headers["token"] = token
 	}
+	if id := logging.RequestID(ctx); id != "" {
+		headers["X-Request-ID"] = id
+	}
 
 	// 构造请求 URL
 	u, err := url.Parse(c.baseUrl)
@@ -44,7 +51,7 @@ func (c *SpiderClientImpl) buildRequest(method string, uri string, token string,
 	if data != nil {
 		jsonData, err := json.Marshal(data)
 		if err != nil {
-			log.Printf("JSON 编码失败 (method: %s, uri: %s): %v", method, uri, err)
+			slog.Error("failed to encode request body", "method", method, "uri", uri, "error", err)
 			return nil, fmt.Errorf("JSON 编码失败: %w", err)
 		}
 		body = bytes.NewBuffer(jsonData)
@@ -52,9 +59,9 @@ func (c *SpiderClientImpl) buildRequest(method string, uri string, token string,
 	}
 
 	// 创建 HTTP 请求
-	r, err := http.NewRequest(method, actualRequestUrl, body)
+	r, err := http.NewRequestWithContext(ctx, method, actualRequestUrl, body)
 	if err != nil {
-		log.Printf("创建请求失败 (method: %s, url: %s): %v", method, actualRequestUrl, err)
+		slog.Error("failed to create request", "method", method, "url", u, "error", err)
 		return nil, fmt.Errorf("创建请求失败 (method: %s, url: %s): %w", method, actualRequestUrl, err)
 	}
 
@@ -67,32 +74,52 @@ func (c *SpiderClientImpl) buildRequest(method string, uri string, token string,
 }
 
 // 发送请求
-func (c *SpiderClientImpl) sendRequest(r *http.Request) (*http.Response, error) {
+func (c *SpiderClientImpl) sendRequest(r *http.Request) (response *http.Response, err error) {
+	// 每次请求一个 span，并通过 traceparent 传给爬虫服务
+	ctx, span := tracing.Start(r.Context(), "spider "+r.Method+" "+r.URL.Path, tracing.WithKind(tracing.KindClient),
+		tracing.WithAttributes(
+			tracing.Attribute{Key: "http.request.method", Value: r.Method},
+			tracing.Attribute{Key: "server.address", Value: r.URL.Host},
+			tracing.Attribute{Key: "url.path", Value: r.URL.Path},
+		))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	r = r.WithContext(ctx)
+	r.Header.Set(tracing.TraceparentHeader, span.SpanContext().Traceparent())
+
 	// 发起 HTTP 请求
-	response, err := c.client.Do(r)
+	response, err = c.client.Do(r)
 	if err != nil {
-		log.Printf("请求失败: method=%s, url=%s, error=%v", r.Method, r.URL, err)
+		// url.Error 的信息包含完整的 URL，会绕过日志的隐藏规则，日志中只记录操作和原始错误，
+		// 返回的错误仍然是 url.Error，但其中的 URL 隐藏了敏感参数
+		op, cause := r.Method, err
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = logging.RedactURL(r.URL)
+			op, cause = urlErr.Op, urlErr.Err
+		}
+		slog.WarnContext(r.Context(), "spider request failed", "op", op, "path", r.URL.Path, "error", cause)
 		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
+	span.SetAttribute("http.response.status_code", response.StatusCode)
 
 	// 检查响应状态码
-	switch response.StatusCode {
-	case http.StatusOK:
+	if response.StatusCode == http.StatusOK {
 		// 正常返回
 		return response, nil
-	case http.StatusUnauthorized:
-		// 未授权
-		log.Printf("Unauthorized: method=%s, url=%s", r.Method, r.URL)
-		return nil, fmt.Errorf("unauthorized")
-	case http.StatusServiceUnavailable:
-		// 服务不可用
-		log.Printf("ServiceUnavailable: method=%s, url=%s", r.Method, r.URL)
-		return nil, fmt.Errorf("service unavailable")
-	default:
-		// 其他错误
-		log.Printf("Unkown Error: method=%s, url=%s, status=%d", r.Method, r.URL, response.StatusCode)
-		return nil, fmt.Errorf("unkown error: status=%d", response.StatusCode)
 	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(response.Body)
+	if err := statusError(response); err != nil {
+		slog.WarnContext(r.Context(), "spider returned an error", "method", r.Method, "url", r.URL, "status", response.StatusCode, "error", err)
+		return nil, err
+	}
+	// 其他错误
+	slog.WarnContext(r.Context(), "spider returned an unknown error", "method", r.Method, "url", r.URL, "status", response.StatusCode)
+	return nil, fmt.Errorf("unkown error: status=%d", response.StatusCode)
 }
 
 // decodeResponse 解码统一返回
@@ -100,21 +127,21 @@ func decodeResponse[V any](response *http.Response) (CommonResponse[V], error) {
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			log.Printf("返回解析失败: %v", err)
+			slog.Warn("failed to close response body", "error", err)
 		}
 	}(response.Body)
 	var result CommonResponse[V]
 	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
-		fmt.Println("返回体 JSON 解码失败：", err)
+		slog.Warn("failed to decode response body", "error", err)
 		return CommonResponse[V]{}, err
 	}
 	return result, nil
 }
 
 // getWithToken 发送头部携带token的get请求
-func getWithToken[V any](c *SpiderClientImpl, uri string, token string) (*CommonResponse[V], error) {
+func getWithToken[V any](ctx context.Context, c *SpiderClientImpl, uri string, token string) (*CommonResponse[V], error) {
 	// 构建请求
-	request, err := c.buildRequest("GET", uri, token, nil)
+	request, err := c.buildRequest(ctx, "GET", uri, token, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -134,55 +161,54 @@ func getWithToken[V any](c *SpiderClientImpl, uri string, token string) (*Common
 	return &commonResponse, nil
 }
 
-func (c *SpiderClientImpl) GetTeachingCalendar(token string) (*TeachingCalendar, error) {
-	commonResponse, err := getWithToken[TeachingCalendar](c, "/calendar", token)
+func (c *SpiderClientImpl) GetTeachingCalendar(ctx context.Context, token string) (*TeachingCalendar, error) {
+	commonResponse, err := getWithToken[TeachingCalendar](ctx, c, "/calendar", token)
 	if err != nil {
 		return nil, err
 	}
 	return &commonResponse.Data, nil
 }
 
-func (c *SpiderClientImpl) GetClassroomStatus(token string, day int) (*ClassroomStatusTable, error) {
+func (c *SpiderClientImpl) GetClassroomStatus(ctx context.Context, token string, day int) (*ClassroomStatusTable, error) {
 	uri := fmt.Sprintf("/classroom/%d", day)
-	commonResponse, err := getWithToken[ClassroomStatusTable](c, uri, token)
+	commonResponse, err := getWithToken[ClassroomStatusTable](ctx, c, uri, token)
 	if err != nil {
 		return nil, err
 	}
 	return &commonResponse.Data, nil
 }
 
-func (c *SpiderClientImpl) GetStudentCourses(token string) (*CourseList, error) {
-	commonResponse, err := getWithToken[CourseList](c, "/courses", token)
+func (c *SpiderClientImpl) GetStudentCourses(ctx context.Context, token string) (*CourseList, error) {
+	commonResponse, err := getWithToken[CourseList](ctx, c, "/courses", token)
 	if err != nil {
 		return nil, err
 	}
 	return &commonResponse.Data, nil
 }
 
-func (c *SpiderClientImpl) GetStudentExams(token string) (*ExamList, error) {
-	commonResponse, err := getWithToken[ExamList](c, "/exams", token)
+func (c *SpiderClientImpl) GetStudentExams(ctx context.Context, token string) (*ExamList, error) {
+	commonResponse, err := getWithToken[ExamList](ctx, c, "/exams", token)
 	if err != nil {
 		return nil, err
 	}
 	return &commonResponse.Data, nil
 }
 
-func (c *SpiderClientImpl) GetStudentInfo(token string) (*StudentInfo, error) {
-	commonResponse, err := getWithToken[StudentInfo](c, "/info", token)
+func (c *SpiderClientImpl) GetStudentInfo(ctx context.Context, token string) (*StudentInfo, error) {
+	commonResponse, err := getWithToken[StudentInfo](ctx, c, "/info", token)
 	if err != nil {
 		return nil, err
 	}
 	return &commonResponse.Data, nil
 }
 
-func (c *SpiderClientImpl) Login(username string, password string) (LoginResponse, error) {
-	request, err := c.buildRequest("POST", "/login", "", map[string]string{"username": username, "password": password})
+func (c *SpiderClientImpl) Login(ctx context.Context, username string, password string) (LoginResponse, error) {
+	request, err := c.buildRequest(ctx, "POST", "/login", "", map[string]string{"username": username, "password": password})
 	if err != nil {
 		return LoginResponse{}, err
 	}
 	response, err := c.sendRequest(request)
 	if err != nil {
-		log.Printf("请求失败: %v", err)
 		return LoginResponse{}, err
 	}
 	loginResponse, err := decodeResponse[LoginResponse](response)
@@ -192,13 +218,13 @@ func (c *SpiderClientImpl) Login(username string, password string) (LoginRespons
 	return loginResponse.Data, nil
 }
 
-func (c *SpiderClientImpl) GetStudentScore(token string, isMajor bool) (*ScoreBoard, error) {
+func (c *SpiderClientImpl) GetStudentScore(ctx context.Context, token string, isMajor bool) (*ScoreBoard, error) {
 	var commonResponse *CommonResponse[ScoreBoard]
 	var err error
 	if isMajor {
-		commonResponse, err = getWithToken[ScoreBoard](c, "/scores", token)
+		commonResponse, err = getWithToken[ScoreBoard](ctx, c, "/scores", token)
 	} else {
-		commonResponse, err = getWithToken[ScoreBoard](c, "/minor/scores", token)
+		commonResponse, err = getWithToken[ScoreBoard](ctx, c, "/minor/scores", token)
 	}
 	if err != nil {
 		return nil, err
@@ -206,13 +232,13 @@ func (c *SpiderClientImpl) GetStudentScore(token string, isMajor bool) (*ScoreBo
 	return &commonResponse.Data, nil
 }
 
-func (c *SpiderClientImpl) GetStudentRank(token string, onlyRequired bool) (*Rank, error) {
+func (c *SpiderClientImpl) GetStudentRank(ctx context.Context, token string, onlyRequired bool) (*Rank, error) {
 	var commonResponse *CommonResponse[Rank]
 	var err error
 	if onlyRequired {
-		commonResponse, err = getWithToken[Rank](c, "/compulsory/rank", token)
+		commonResponse, err = getWithToken[Rank](ctx, c, "/compulsory/rank", token)
 	} else {
-		commonResponse, err = getWithToken[Rank](c, "/rank", token)
+		commonResponse, err = getWithToken[Rank](ctx, c, "/rank", token)
 	}
 	if err != nil {
 		return nil, err
@@ -221,8 +247,8 @@ func (c *SpiderClientImpl) GetStudentRank(token string, onlyRequired bool) (*Ran
 }
 
 // NewStudent 创建一个新的学生账户。
-func (c *SpiderClientImpl) NewStudent(username string, password string) (Student, error) {
-	return NewStudentImpl(username, password, c)
+func (c *SpiderClientImpl) NewStudent(ctx context.Context, username string, password string) (Student, error) {
+	return NewStudentImpl(ctx, username, password, c)
 }
 
 func NewSpiderClientImpl(baseUrl string, client http.Client) *SpiderClientImpl {
@@ -1,10 +1,19 @@
 package feign
 
 import (
+	"bytes"
+	"cached_proxy/logging"
+	"cached_proxy/tracing"
+	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSpiderClient_Login(t *testing.T) {
@@ -87,7 +96,7 @@ func TestSpiderClient_Login(t *testing.T) {
 
 			client := NewSpiderClientImpl(server.URL, http.Client{})
 
-			response, err := client.Login(tt.username, tt.password)
+			response, err := client.Login(context.Background(), tt.username, tt.password)
 
 			if (err != nil) != tt.expectedError {
 				t.Fatalf("Expected error: %v, got: %v", tt.expectedError, err)
@@ -159,7 +168,7 @@ func TestSpiderClient_getWithToken(t *testing.T) {
 
 			client := NewSpiderClientImpl(server.URL, http.Client{})
 
-			response, err := getWithToken[any](client, "/test-uri", tt.token)
+			response, err := getWithToken[any](context.Background(), client, "/test-uri", tt.token)
 
 			if (err != nil) != tt.expectedError {
 				t.Fatalf("Expected error: %v, got: %v", tt.expectedError, err)
@@ -221,34 +230,34 @@ func TestSpiderClientImplIntegrate(t *testing.T) {
 	baseUrl := server.URL
 	client := NewSpiderClientImpl(baseUrl, http.Client{})
 	t.Run("Integrate Test Login With Valid Username and password", func(t *testing.T) {
-		login, err := client.Login(validUsername, validPassword)
+		login, err := client.Login(context.Background(), validUsername, validPassword)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
 		validToken = login.Token
 	})
-	login, err := client.Login(validUsername, validPassword)
+	login, err := client.Login(context.Background(), validUsername, validPassword)
 	if err != nil {
 		t.Fatalf("Expected error: %v, got: %v", nil, err)
 	}
 	validToken = login.Token
 
 	t.Run("Integrate Test Login With Invalid Username and password", func(t *testing.T) {
-		_, err := client.Login("invalid-user", "invalid-password")
+		_, err := client.Login(context.Background(), "invalid-user", "invalid-password")
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
 	})
 
 	t.Run("Integrate Test GetClassroomStatus With Valid Token", func(t *testing.T) {
-		resp, err := client.GetClassroomStatus(validToken, 0)
+		resp, err := client.GetClassroomStatus(context.Background(), validToken, 0)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
 		if resp == nil {
 			t.Fatalf("Expected data is not nil, but got: %v", resp)
 		}
-		resp, err = client.GetClassroomStatus(validToken, 1)
+		resp, err = client.GetClassroomStatus(context.Background(), validToken, 1)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
@@ -258,18 +267,18 @@ func TestSpiderClientImplIntegrate(t *testing.T) {
 	})
 
 	t.Run("Integrate Test GetClassroomStatus With Invalid Token", func(t *testing.T) {
-		_, err := client.GetClassroomStatus("invalid-token", 0)
+		_, err := client.GetClassroomStatus(context.Background(), "invalid-token", 0)
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
-		_, err = client.GetClassroomStatus("invalid-token", 1)
+		_, err = client.GetClassroomStatus(context.Background(), "invalid-token", 1)
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
 	})
 
 	t.Run("Integrate Test GetStudentInfo With Valid Token", func(t *testing.T) {
-		resp, err := client.GetStudentInfo(validToken)
+		resp, err := client.GetStudentInfo(context.Background(), validToken)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
@@ -279,14 +288,14 @@ func TestSpiderClientImplIntegrate(t *testing.T) {
 	})
 
 	t.Run("Integrate Test GetStudentInfo With Invalid Token", func(t *testing.T) {
-		_, err := client.GetStudentInfo("invalid-token")
+		_, err := client.GetStudentInfo(context.Background(), "invalid-token")
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
 	})
 
 	t.Run("Integrate Test GetStudentCourses With Valid Token", func(t *testing.T) {
-		resp, err := client.GetStudentCourses(validToken)
+		resp, err := client.GetStudentCourses(context.Background(), validToken)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
@@ -296,14 +305,14 @@ func TestSpiderClientImplIntegrate(t *testing.T) {
 	})
 
 	t.Run("Integrate Test GetStudentCourses With Invalid Token", func(t *testing.T) {
-		_, err := client.GetStudentCourses("invalid-token")
+		_, err := client.GetStudentCourses(context.Background(), "invalid-token")
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
 	})
 
 	t.Run("Integrate Test GetStudentExams With Valid Token", func(t *testing.T) {
-		resp, err := client.GetStudentExams(validToken)
+		resp, err := client.GetStudentExams(context.Background(), validToken)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
@@ -313,14 +322,14 @@ func TestSpiderClientImplIntegrate(t *testing.T) {
 	})
 
 	t.Run("Integrate Test GetStudentExams With Invalid Token", func(t *testing.T) {
-		_, err := client.GetStudentExams("invalid-token")
+		_, err := client.GetStudentExams(context.Background(), "invalid-token")
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
 	})
 
 	t.Run("Integrate Test GetTeachingCalendar With Valid Token", func(t *testing.T) {
-		resp, err := client.GetTeachingCalendar(validToken)
+		resp, err := client.GetTeachingCalendar(context.Background(), validToken)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
@@ -330,21 +339,21 @@ func TestSpiderClientImplIntegrate(t *testing.T) {
 	})
 
 	t.Run("Integrate Test GetTeachingCalendar With Invalid Token", func(t *testing.T) {
-		_, err := client.GetTeachingCalendar("invalid-token")
+		_, err := client.GetTeachingCalendar(context.Background(), "invalid-token")
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
 	})
 
 	t.Run("Integrate Test GetStudentRank With Valid Token", func(t *testing.T) {
-		resp, err := client.GetStudentRank(validToken, false)
+		resp, err := client.GetStudentRank(context.Background(), validToken, false)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
 		if resp == nil {
 			t.Fatalf("Expected data is not nil, but got: %v", resp)
 		}
-		resp, err = client.GetStudentRank(validToken, true)
+		resp, err = client.GetStudentRank(context.Background(), validToken, true)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
@@ -355,25 +364,25 @@ func TestSpiderClientImplIntegrate(t *testing.T) {
 	})
 
 	t.Run("Integrate Test GetStudentRank With Invalid Token", func(t *testing.T) {
-		_, err := client.GetStudentRank("invalid-token", false)
+		_, err := client.GetStudentRank(context.Background(), "invalid-token", false)
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
-		_, err = client.GetStudentRank("invalid-token", true)
+		_, err = client.GetStudentRank(context.Background(), "invalid-token", true)
 		if err == nil || err.Error() != "unauthorized" {
 			t.Fatalf("Expected error: %v, got: %v", "unauthorized", err)
 		}
 	})
 
 	t.Run("Integrate Test GetStudentScore With Valid Token", func(t *testing.T) {
-		resp, err := client.GetStudentScore(validToken, false)
+		resp, err := client.GetStudentScore(context.Background(), validToken, false)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
 		if resp == nil {
 			t.Fatalf("Expected data is not nil, but got: %v", resp)
 		}
-		resp, err = client.GetStudentScore(validToken, true)
+		resp, err = client.GetStudentScore(context.Background(), validToken, true)
 		if err != nil {
 			t.Fatalf("Expected error: %v, got: %v", nil, err)
 		}
@@ -384,3 +393,65 @@ func TestSpiderClientImplIntegrate(t *testing.T) {
 	})
 
 }
+
+func TestSpiderClient_Context(t *testing.T) {
+	requestID, traceparent := "", ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID, traceparent = r.Header.Get("X-Request-ID"), r.Header.Get("traceparent")
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":1,"message":"success","data":{}}`))
+	}))
+	defer server.Close()
+	client := NewSpiderClientImpl(server.URL, http.Client{})
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	if _, err := client.GetStudentInfo(ctx, "token"); err != nil || requestID != "req-1" {
+		t.Errorf("request ID should be forwarded, got %q %v", requestID, err)
+	}
+	ctx, span := tracing.NewTracer(nil).Start(ctx, "parent")
+	if _, err := client.GetStudentInfo(ctx, "token"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if sc, ok := tracing.ParseTraceparent(traceparent); !ok || sc.TraceID != span.SpanContext().TraceID || sc.SpanID == span.SpanContext().SpanID {
+		t.Errorf("traceparent should carry a child span of the caller, got %q", traceparent)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := getWithToken[any](ctx, client, "/slow", "token")
+	if !errors.Is(err, context.DeadlineExceeded) || !isUpstreamFailure(err) {
+		t.Errorf("deadline should abort the request and count as an upstream failure, got %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetStudentInfo(ctx, "token")
+	if !errors.Is(err, context.Canceled) || isUpstreamFailure(err) {
+		t.Errorf("canceled request should not count as an upstream failure, got %v", err)
+	}
+}
+
+func TestSpiderClient_TransportErrorRedacted(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	client := NewSpiderClientImpl(server.URL, http.Client{})
+	// 不使用 logging 的 Handler，确认日志和返回的错误本身不包含敏感参数
+	buf := bytes.Buffer{}
+	defer func(logger *slog.Logger) { slog.SetDefault(logger) }(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	r, _ := http.NewRequest(http.MethodGet, server.URL+"/info?token=top-secret&term=1", nil)
+	_, err := client.sendRequest(r)
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || !isUpstreamFailure(err) {
+		t.Fatalf("expected url.Error counted as upstream failure, got %v", err)
+	}
+	if strings.Contains(err.Error(), "top-secret") || strings.Contains(buf.String(), "top-secret") {
+		t.Errorf("token leaked:\nerror: %v\nlog: %s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), "op=Get") {
+		t.Errorf("expected operation in log, got %s", buf.String())
+	}
+}
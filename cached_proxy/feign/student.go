@@ -1,38 +1,41 @@
 package feign
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strings"
 	"sync"
+	"time"
 )
 
+// Student 是学生账户的代理，所有请求方法的 ctx 用于取消请求和设置截止时间，取消时也会停止重试
 type Student interface {
 	// Username 获取学生的用户名。
 	Username() string
 
 	// GetTeachingCalendar 获取当前学期的教学日历。
-	GetTeachingCalendar() (*TeachingCalendar, error)
+	GetTeachingCalendar(ctx context.Context) (*TeachingCalendar, error)
 
 	// GetClassroomStatus 获取指定日期的教室考试状态。
 	// day: 要查询的具体日期（例如，0 表示今天，-1 表示昨天）。
-	GetClassroomStatus(day int) (*ClassroomStatusTable, error)
+	GetClassroomStatus(ctx context.Context, day int) (*ClassroomStatusTable, error)
 
 	// GetStudentCourses 获取已认证学生的课程信息。
-	GetStudentCourses() (*CourseList, error)
+	GetStudentCourses(ctx context.Context) (*CourseList, error)
 
 	// GetStudentExams 获取已认证学生的考试安排。
-	GetStudentExams() (*ExamList, error)
+	GetStudentExams(ctx context.Context) (*ExamList, error)
 
 	// GetInfo 获取已认证学生的个人信息。
-	GetInfo() (*StudentInfo, error)
+	GetInfo(ctx context.Context) (*StudentInfo, error)
 
 	// GetStudentScore 获取已认证学生的成绩信息。
 	// isMajor: 是否仅获取主修相关的成绩。
-	GetStudentScore(isMajor bool) (*ScoreBoard, error)
+	GetStudentScore(ctx context.Context, isMajor bool) (*ScoreBoard, error)
 
 	// GetStudentRank 获取已认证学生的排名信息。
 	// onlyRequired: 是否仅包括必修课程的排名计算。
-	GetStudentRank(onlyRequired bool) (*Rank, error)
+	GetStudentRank(ctx context.Context, onlyRequired bool) (*Rank, error)
 }
 
 type StudentImpl struct {
@@ -44,9 +47,9 @@ type StudentImpl struct {
 	mu           sync.Mutex
 }
 
-func NewStudentImpl(username string, password string, client SpiderClient) (*StudentImpl, error) {
+func NewStudentImpl(ctx context.Context, username string, password string, client SpiderClient) (*StudentImpl, error) {
 	s := StudentImpl{username: username, password: password, spider: client}
-	_, err := s.refreshDynamicToken(3)
+	_, err := s.refreshDynamicToken(ctx, 3)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +61,7 @@ func (s *StudentImpl) Username() string {
 }
 
 // refreshDynamicToken 刷新动态token
-func (s *StudentImpl) refreshDynamicToken(maxRetryTime int) (string, error) {
+func (s *StudentImpl) refreshDynamicToken(ctx context.Context, maxRetryTime int) (string, error) {
 	var finalError error
 	version := s.version
 	s.mu.Lock()
@@ -70,15 +73,18 @@ func (s *StudentImpl) refreshDynamicToken(maxRetryTime int) (string, error) {
 		return s.dynamicToken, nil
 	}
 	for i := 0; i < maxRetryTime; i++ {
-		response, err := s.spider.Login(s.username, s.password)
+		response, err := s.spider.Login(ctx, s.username, s.password)
 		if err != nil {
 			finalError = err
-			errorString := err.Error()
-			// 如果是账号密码错误，那么重试
-			if strings.Contains(errorString, "unauthorized") {
+			// 如果是账号密码错误、被限流或者已经熔断，那么直接返回，否则退避后重试
+			if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrCircuitOpen) {
 				return "", err
 			}
-			// 否则重试
+			if i < maxRetryTime-1 {
+				if err := sleepContext(ctx, retryBackoff.Delay(i)); err != nil {
+					return "", err
+				}
+			}
 			continue
 		}
 		s.dynamicToken = response.Token
@@ -87,12 +93,12 @@ func (s *StudentImpl) refreshDynamicToken(maxRetryTime int) (string, error) {
 	return "", finalError
 }
 
-func doGetter[V any](s *StudentImpl, function func(token string) (*V, error)) (*V, error) {
+func doGetter[V any](ctx context.Context, s *StudentImpl, function func(ctx context.Context, token string) (*V, error)) (*V, error) {
 	var finalErr error
 	maxRetryTimes := 3
 	// 如果token为空，那么刷新token
 	if s.dynamicToken == "" {
-		_, err := s.refreshDynamicToken(3)
+		_, err := s.refreshDynamicToken(ctx, 3)
 		if err != nil {
 			return nil, err
 		}
@@ -100,20 +106,27 @@ func doGetter[V any](s *StudentImpl, function func(token string) (*V, error)) (*
 
 	for i := 0; i < maxRetryTimes; i++ {
 		token := s.dynamicToken
-		data, err := function(token)
+		data, err := function(ctx, token)
 		if err != nil {
 			finalErr = err
-			errorString := err.Error()
-			switch errorString {
-			case "unauthorized":
+			switch {
+			case errors.Is(err, ErrCircuitOpen), ctx.Err() != nil:
+				// 如果已经熔断或者请求已经取消，那么直接返回，由调用方使用缓存的数据
+				return nil, err
+			case errors.Is(err, ErrUnauthorized):
 				// 如果是token失效，那么重试登陆
-				_, err := s.refreshDynamicToken(3)
+				_, err := s.refreshDynamicToken(ctx, 3)
 				if err != nil {
 					return nil, err
 				}
 				continue
-			case "service unavailable":
-				// 如果是服务不可用，那么重试
+			case errors.Is(err, ErrUpstreamUnavailable), errors.Is(err, ErrCaptcha):
+				// 如果是服务不可用，那么退避后重试
+				if i < maxRetryTimes-1 {
+					if err := sleepContext(ctx, retryBackoff.Delay(i)); err != nil {
+						return nil, err
+					}
+				}
 				continue
 			default:
 				// 否则返回错误
@@ -124,39 +137,54 @@ func doGetter[V any](s *StudentImpl, function func(token string) (*V, error)) (*
 		}
 	}
 	// 如果重试次数超过限制，那么返回错误
-	return nil, fmt.Errorf("exceeded retry attempts: %v", finalErr)
+	return nil, fmt.Errorf("exceeded retry attempts: %w", finalErr)
 }
 
-func (s *StudentImpl) GetTeachingCalendar() (*TeachingCalendar, error) {
-	return doGetter(s, s.spider.GetTeachingCalendar)
+func (s *StudentImpl) GetTeachingCalendar(ctx context.Context) (*TeachingCalendar, error) {
+	return doGetter(ctx, s, s.spider.GetTeachingCalendar)
 }
 
-func (s *StudentImpl) GetClassroomStatus(day int) (*ClassroomStatusTable, error) {
-	return doGetter(s, func(token string) (*ClassroomStatusTable, error) {
-		return s.spider.GetClassroomStatus(token, day)
+func (s *StudentImpl) GetClassroomStatus(ctx context.Context, day int) (*ClassroomStatusTable, error) {
+	return doGetter(ctx, s, func(ctx context.Context, token string) (*ClassroomStatusTable, error) {
+		return s.spider.GetClassroomStatus(ctx, token, day)
 	})
 }
 
-func (s *StudentImpl) GetStudentCourses() (*CourseList, error) {
-	return doGetter(s, s.spider.GetStudentCourses)
+func (s *StudentImpl) GetStudentCourses(ctx context.Context) (*CourseList, error) {
+	return doGetter(ctx, s, s.spider.GetStudentCourses)
 }
 
-func (s *StudentImpl) GetStudentExams() (*ExamList, error) {
-	return doGetter(s, s.spider.GetStudentExams)
+func (s *StudentImpl) GetStudentExams(ctx context.Context) (*ExamList, error) {
+	return doGetter(ctx, s, s.spider.GetStudentExams)
 }
 
-func (s *StudentImpl) GetInfo() (*StudentInfo, error) {
-	return doGetter(s, s.spider.GetStudentInfo)
+func (s *StudentImpl) GetInfo(ctx context.Context) (*StudentInfo, error) {
+	return doGetter(ctx, s, s.spider.GetStudentInfo)
 }
 
-func (s *StudentImpl) GetStudentScore(isMajor bool) (*ScoreBoard, error) {
-	return doGetter(s, func(token string) (*ScoreBoard, error) {
-		return s.spider.GetStudentScore(token, isMajor)
+func (s *StudentImpl) GetStudentScore(ctx context.Context, isMajor bool) (*ScoreBoard, error) {
+	return doGetter(ctx, s, func(ctx context.Context, token string) (*ScoreBoard, error) {
+		return s.spider.GetStudentScore(ctx, token, isMajor)
 	})
 }
 
-func (s *StudentImpl) GetStudentRank(onlyRequired bool) (*Rank, error) {
-	return doGetter(s, func(token string) (*Rank, error) {
-		return s.spider.GetStudentRank(token, onlyRequired)
+func (s *StudentImpl) GetStudentRank(ctx context.Context, onlyRequired bool) (*Rank, error) {
+	return doGetter(ctx, s, func(ctx context.Context, token string) (*Rank, error) {
+		return s.spider.GetStudentRank(ctx, token, onlyRequired)
 	})
 }
+
+// sleepContext 等待 d，ctx 取消时提前返回 ctx 的错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -2,6 +2,7 @@ package feign
 
 import (
 	"cached_proxy/repo"
+	"context"
 	"fmt"
 	"strings"
 )
@@ -10,8 +11,8 @@ type StudentService interface {
 	// GetStudent 获取学生代理类
 	GetStudent(username string) (*Student, error)
 
-	// SetStudent 设置学生账户，如果该账户未通过验证，则返回错误
-	SetStudent(username string, password string, verify bool) error
+	// SetStudent 设置学生账户，如果该账户未通过验证，则返回错误，ctx 用于取消验证时的登录请求
+	SetStudent(ctx context.Context, username string, password string, verify bool) error
 }
 
 type StudentServiceImpl struct {
@@ -36,21 +37,21 @@ func (s *StudentServiceImpl) GetStudent(username string) (*Student, error) {
 	return student, nil
 }
 
-func (s *StudentServiceImpl) SetStudent(username string, password string, verify bool) error {
+func (s *StudentServiceImpl) SetStudent(ctx context.Context, username string, password string, verify bool) error {
 	username = strings.TrimSpace(username)
 	password = strings.TrimSpace(password)
 	if username == "" || password == "" {
-		return fmt.Errorf("invalid username or password")
+		return fmt.Errorf("%w: invalid username or password", ErrUnauthorized)
 	}
 	var student Student
 	var err error
 	if verify {
-		student, err = s.client.NewStudent(username, password)
+		student, err = s.client.NewStudent(ctx, username, password)
 		if err != nil {
 			return err
 		}
 	} else {
-		student = &StudentImpl{username: username, password: password, spider: s.client}
+		student = &StudentImpl{username: username, password: password, spider: forAccount(s.client, username)}
 	}
 	s.repo.Set(username, &student)
 	return nil
@@ -14,6 +14,9 @@ type TimeTable struct {
 	EventTimes []EventTimes
 }
 
+// Zone 是学校所在的时区，课程和考试的时间都使用该时区
+var Zone = zone
+
 var (
 	zone            = time.FixedZone("Asia/Shanghai", 8*60*60)
 	summerStart     = time.Date(0, 5, 1, 0, 0, 0, 0, zone)
@@ -127,3 +130,33 @@ func (t *TeachingCalendar) GetTermTimeTable() TermTimeTable {
 	termTimeTable.SepWeeks = sepWeeks
 	return termTimeTable
 }
+
+// On 返回该节次在指定日期的开始和结束时间
+func (e EventTimes) On(date time.Time) (time.Time, time.Time) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), e.StartTime.Hour(), e.StartTime.Minute(), 0, 0, zone)
+	end := time.Date(date.Year(), date.Month(), date.Day(), e.EndTime.Hour(), e.EndTime.Minute(), 0, 0, zone)
+	return start, end
+}
+
+// TimeTableOf 返回指定周次使用的作息时间表
+func (t TermTimeTable) TimeTableOf(week int) TimeTable {
+	if week >= t.SepWeeks {
+		return t.SufTimeTable
+	}
+	return t.PreTimeTable
+}
+
+// DateOf 返回指定周次和星期（1 表示星期一）对应的日期
+func (t *TeachingCalendar) DateOf(week int, day int) time.Time {
+	return t.StartTime().AddDate(0, 0, (week-1)*7+day-1)
+}
+
+// WeekOf 返回指定日期所在的周次和星期（1 表示星期一）
+func (t *TeachingCalendar) WeekOf(date time.Time) (week int, day int) {
+	start := t.StartTime()
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, start.Location())
+	days := int(math.Floor(date.Sub(start).Hours() / 24))
+	week = int(math.Floor(float64(days)/7)) + 1
+	day = days - (week-1)*7 + 1
+	return week, day
+}
@@ -46,3 +46,26 @@ func TestTeachingCalendar_GetTermTimeTable(t1 *testing.T) {
 		})
 	}
 }
+
+func TestTeachingCalendar_WeekOf(t *testing.T) {
+	calendar := &TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	tests := []struct {
+		date time.Time
+		week int
+		day  int
+	}{
+		{date: time.Date(2025, 2, 17, 0, 0, 0, 0, time.UTC), week: 1, day: 1},
+		{date: time.Date(2025, 2, 23, 20, 0, 0, 0, time.UTC), week: 1, day: 7},
+		{date: time.Date(2025, 4, 27, 0, 0, 0, 0, time.UTC), week: 10, day: 7},
+		{date: time.Date(2025, 5, 5, 0, 0, 0, 0, time.UTC), week: 12, day: 1},
+	}
+	for _, tt := range tests {
+		week, day := calendar.WeekOf(tt.date)
+		if week != tt.week || day != tt.day {
+			t.Errorf("WeekOf(%v) = %d, %d, want %d, %d", tt.date, week, day, tt.week, tt.day)
+		}
+		if got := calendar.DateOf(tt.week, tt.day); got.Format(DateLayout) != tt.date.Format(DateLayout) {
+			t.Errorf("DateOf(%d, %d) = %v, want %v", tt.week, tt.day, got, tt.date)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package feign
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxWeeks 是学期周数未知时允许的最大周次，避免异常的周次生成过多的事件
+const MaxWeeks = 60
+
+// WeekSet 是课程上课的周次集合，按升序排列且不重复
+type WeekSet []int
+
+// WeekRange 是一段等间隔的周次，Step 为 1 表示连续的周次，为 2 表示单周或双周
+type WeekRange struct {
+	Start int
+	End   int
+	Step  int
+}
+
+// WeekError 描述周次表达式中无法解析的一项
+type WeekError struct {
+	Expr   string `json:"expr"`   // 完整的周次表达式
+	Item   string `json:"item"`   // 无法解析的一项
+	Reason string `json:"reason"` // 无法解析的原因
+}
+
+func (e *WeekError) Error() string {
+	return fmt.Sprintf("invalid week %q in %q: %s", e.Item, e.Expr, e.Reason)
+}
+
+// WeekErrors 是一个周次表达式中所有无法解析的项
+type WeekErrors []*WeekError
+
+func (e WeekErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// weekReplacer 将全角符号和中文连接词统一为半角的分隔符和连字符
+var weekReplacer = strings.NewReplacer(
+	"，", ",", "、", ",", "；", ",", ";", ",",
+	"（", "(", "）", ")", "【", "(", "】", ")", "[", "(", "]", ")",
+	"－", "-", "—", "-", "–", "-", "~", "-", "～", "-", "至", "-", "到", "-",
+)
+
+// paritySuffixes 是表示单双周的后缀，较长的后缀在前
+var paritySuffixes = []struct {
+	suffix string
+	parity int
+}{
+	{"(单周)", 1}, {"(双周)", 0}, {"(单)", 1}, {"(双)", 0},
+	{"单周", 1}, {"双周", 0}, {"单", 1}, {"双", 0},
+}
+
+// ParseWeeks 解析课程的周次表达式，total 是学期的周数，为 0 时表示未知。
+// 支持 "1-16"、"1-16周"、"第1-8周"、"3-15(单)"、"2-16双周"、"5" 以及单独的 "单周" 和 "双周"，
+// 各项以逗号或顿号分隔。无法解析的项会被跳过并以 WeekErrors 返回，此时周次集合仍然包含其余各项。
+// 超出学期周数的区间会截断到最后一周，同样以 WeekErrors 返回。
+func ParseWeeks(expr string, total int) (WeekSet, error) {
+	limit := total
+	if limit <= 0 {
+		limit = MaxWeeks
+	}
+	seen := map[int]bool{}
+	var errs WeekErrors
+	for _, item := range strings.Split(weekReplacer.Replace(expr), ",") {
+		item = strings.Join(strings.Fields(item), "")
+		if item == "" {
+			continue
+		}
+		weeks, reason := parseWeekItem(item, total, limit)
+		if reason != "" {
+			errs = append(errs, &WeekError{Expr: expr, Item: item, Reason: reason})
+		}
+		for _, week := range weeks {
+			seen[week] = true
+		}
+	}
+	set := make(WeekSet, 0, len(seen))
+	for week := range seen {
+		set = append(set, week)
+	}
+	sort.Ints(set)
+	if len(errs) > 0 {
+		return set, errs
+	}
+	return set, nil
+}
+
+// parseWeekItem 解析周次表达式中的一项，无法解析时返回原因。
+// 区间超出 limit 时返回截断后的周次和原因。
+func parseWeekItem(item string, total int, limit int) ([]int, string) {
+	parity := -1
+	item = strings.TrimSuffix(strings.TrimPrefix(item, "第"), "周")
+	for _, p := range paritySuffixes {
+		if strings.HasSuffix(item, p.suffix) {
+			parity = p.parity
+			item = strings.TrimSuffix(item, p.suffix)
+			break
+		}
+	}
+	item = strings.TrimSuffix(strings.TrimPrefix(item, "第"), "周")
+	var start, end int
+	if item == "" {
+		// 单独的 "单周" 或 "双周" 表示整个学期
+		if parity < 0 {
+			return nil, "empty week"
+		}
+		if total <= 0 {
+			return nil, "number of weeks in the term is unknown"
+		}
+		start, end = 1, total
+	} else {
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+			return nil, "too many '-'"
+		}
+		var err error
+		if start, err = strconv.Atoi(bounds[0]); err != nil {
+			return nil, "not a number"
+		}
+		end = start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, "not a number"
+			}
+		}
+	}
+	if start < 1 || end < start {
+		return nil, "invalid range"
+	}
+	reason := ""
+	if end > limit {
+		reason = fmt.Sprintf("week out of range 1-%d", limit)
+		if start > limit {
+			return nil, reason
+		}
+		end = limit
+	}
+	var weeks []int
+	for week := start; week <= end; week++ {
+		if parity < 0 || week%2 == parity {
+			weeks = append(weeks, week)
+		}
+	}
+	if len(weeks) == 0 && reason == "" {
+		return nil, "no week matches the odd or even constraint"
+	}
+	return weeks, reason
+}
+
+// Has 判断 week 是否在集合中
+func (s WeekSet) Has(week int) bool {
+	i := sort.SearchInts(s, week)
+	return i < len(s) && s[i] == week
+}
+
+// Ranges 将周次合并为尽量少的等间隔区间，间隔为 1 或 2，例如 [1 3 5 6 7 8] 合并为 [{1 5 2} {6 8 1}]
+func (s WeekSet) Ranges() []WeekRange {
+	var ranges []WeekRange
+	for i := 0; i < len(s); {
+		r := WeekRange{Start: s[i], End: s[i], Step: 1}
+		if i+1 < len(s) && s[i+1]-s[i] <= 2 {
+			r.Step = s[i+1] - s[i]
+		}
+		j := i + 1
+		for j < len(s) && s[j]-s[j-1] == r.Step {
+			r.End = s[j]
+			j++
+		}
+		ranges = append(ranges, r)
+		i = j
+	}
+	return ranges
+}
+
+// Has 判断 week 是否在区间中
+func (r WeekRange) Has(week int) bool {
+	return r.Start <= week && week <= r.End && (week-r.Start)%r.Step == 0
+}
+
+// Count 返回区间中的周数
+func (r WeekRange) Count() int {
+	return (r.End-r.Start)/r.Step + 1
+}
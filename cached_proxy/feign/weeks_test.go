@@ -0,0 +1,111 @@
+package feign
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseWeeks(t *testing.T) {
+	tests := []struct {
+		expr   string
+		total  int
+		want   WeekSet
+		errors []string // 无法解析的项
+	}{
+		{expr: "1-8,10-16", total: 18, want: WeekSet{1, 2, 3, 4, 5, 6, 7, 8, 10, 11, 12, 13, 14, 15, 16}},
+		{expr: "5", total: 18, want: WeekSet{5}},
+		{expr: "1-4周", total: 18, want: WeekSet{1, 2, 3, 4}},
+		{expr: "第1-3周", total: 18, want: WeekSet{1, 2, 3}},
+		{expr: "3-15(单)", total: 18, want: WeekSet{3, 5, 7, 9, 11, 13, 15}},
+		{expr: "1-8周（双）", total: 18, want: WeekSet{2, 4, 6, 8}},
+		{expr: "2-7单周", total: 18, want: WeekSet{3, 5, 7}},
+		{expr: "双周", total: 8, want: WeekSet{2, 4, 6, 8}},
+		{expr: "单", total: 5, want: WeekSet{1, 3, 5}},
+		{expr: "1～3，5、7", total: 18, want: WeekSet{1, 2, 3, 5, 7}},
+		{expr: " 1 - 3 , 2-4 ", total: 18, want: WeekSet{1, 2, 3, 4}},
+		{expr: "", total: 18, want: WeekSet{}},
+		{expr: "1-8,x", total: 18, want: WeekSet{1, 2, 3, 4, 5, 6, 7, 8}, errors: []string{"x"}},
+		{expr: "双周", total: 0, want: WeekSet{}, errors: []string{"双周"}},
+		{expr: "8-1", total: 18, want: WeekSet{}, errors: []string{"8-1"}},
+		{expr: "0,19", total: 18, want: WeekSet{}, errors: []string{"0", "19"}},
+		{expr: "1-20", total: 18, want: WeekSet{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18}, errors: []string{"1-20"}},
+		{expr: "1-4,15-20双", total: 18, want: WeekSet{1, 2, 3, 4, 16, 18}, errors: []string{"15-20双"}},
+		{expr: "19-20", total: 18, want: WeekSet{}, errors: []string{"19-20"}},
+		{expr: "1-2-3", total: 18, want: WeekSet{}, errors: []string{"1-2-3"}},
+		{expr: "2(单)", total: 18, want: WeekSet{}, errors: []string{"2(单)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseWeeks(tt.expr, tt.total)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseWeeks() = %v, want %v", got, tt.want)
+			}
+			var errs WeekErrors
+			if !errors.As(err, &errs) {
+				errs = nil
+			}
+			if len(errs) != len(tt.errors) {
+				t.Fatalf("ParseWeeks() error = %v, want errors for %v", err, tt.errors)
+			}
+			for i, e := range errs {
+				if e.Item != tt.errors[i] || e.Expr != tt.expr || e.Reason == "" {
+					t.Errorf("error[%d] = %+v, want item %q", i, e, tt.errors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWeekSet_Ranges(t *testing.T) {
+	tests := []struct {
+		set  WeekSet
+		want []WeekRange
+	}{
+		{WeekSet{}, nil},
+		{WeekSet{5}, []WeekRange{{5, 5, 1}}},
+		{WeekSet{1, 2, 3, 4, 5, 6, 7, 8, 10, 11, 12}, []WeekRange{{1, 8, 1}, {10, 12, 1}}},
+		{WeekSet{1, 3, 5, 6, 7, 8}, []WeekRange{{1, 5, 2}, {6, 8, 1}}},
+		{WeekSet{2, 9, 16}, []WeekRange{{2, 2, 1}, {9, 9, 1}, {16, 16, 1}}},
+	}
+	for _, tt := range tests {
+		if got := tt.set.Ranges(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v.Ranges() = %v, want %v", tt.set, got, tt.want)
+		}
+	}
+}
+
+func FuzzParseWeeks(f *testing.F) {
+	for _, expr := range []string{"1-16", "1-8,10-16", "3-15(单)", "第1-8周", "双周", "1～3，5、7", "1-2-3", ""} {
+		f.Add(expr, 18)
+	}
+	f.Fuzz(func(t *testing.T, expr string, total int) {
+		set, err := ParseWeeks(expr, total)
+		limit := total
+		if limit <= 0 {
+			limit = MaxWeeks
+		}
+		for i, week := range set {
+			if week < 1 || week > limit || (i > 0 && week <= set[i-1]) {
+				t.Fatalf("ParseWeeks(%q, %d) = %v is not sorted within 1-%d", expr, total, set, limit)
+			}
+		}
+		var errs WeekErrors
+		if err != nil && !errors.As(err, &errs) {
+			t.Fatalf("ParseWeeks(%q, %d) returned an unstructured error %v", expr, total, err)
+		}
+		// 区间展开后应该与集合完全相同
+		var expanded WeekSet
+		for _, r := range set.Ranges() {
+			for week := r.Start; week <= r.End; week += r.Step {
+				expanded = append(expanded, week)
+			}
+			if r.Count() < 1 {
+				t.Fatalf("empty range %v", r)
+			}
+		}
+		if len(expanded) != len(set) || (len(set) > 0 && !reflect.DeepEqual(expanded, set)) {
+			t.Fatalf("Ranges() of %v expands to %v", set, expanded)
+		}
+	})
+}
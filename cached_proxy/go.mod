@@ -1,3 +1,5 @@
 module cached_proxy
 
 go 1.21
+
+require github.com/andybalholm/brotli v1.1.1
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"cached_proxy/feign"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// 服务的健康状态，爬虫服务不可用时仍然可以返回缓存的数据，因此只标记为降级
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// HealthResponse 是健康检查的结果
+type HealthResponse struct {
+	Status   string                           `json:"status"`
+	Breakers map[string]feign.BreakerSnapshot `json:"breakers"` // 爬虫服务每个接口的熔断器状态
+}
+
+// breakerSnapshotter 返回熔断器的状态
+type breakerSnapshotter interface {
+	Snapshot() map[string]feign.BreakerSnapshot
+}
+
+// HealthGetter 提供不需要认证的健康检查
+type HealthGetter struct {
+	breakers breakerSnapshotter
+}
+
+var (
+	HealthHandler = &HealthGetter{breakers: SpiderBreaker}
+)
+
+// health 汇总熔断器的状态，任意接口的熔断器没有关闭时为降级
+func (h *HealthGetter) health() HealthResponse {
+	resp := HealthResponse{Status: HealthOK, Breakers: h.breakers.Snapshot()}
+	for _, breaker := range resp.Breakers {
+		if breaker.State != feign.BreakerClosed {
+			resp.Status = HealthDegraded
+		}
+	}
+	return resp
+}
+
+func (h *HealthGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	resp := feign.CommonResponse[any]{
+		Code:    CodeSuccess,
+		Message: "success",
+		Data:    h.health(),
+	}
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode health", "error", err)
+	}
+}
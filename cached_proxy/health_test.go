@@ -0,0 +1,51 @@
+package main
+
+import (
+	"cached_proxy/feign"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeBreakers 返回固定的熔断器状态
+type fakeBreakers map[string]feign.BreakerSnapshot
+
+func (f fakeBreakers) Snapshot() map[string]feign.BreakerSnapshot {
+	return f
+}
+
+func TestHealthGetter_GetInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		breakers fakeBreakers
+		status   string
+	}{
+		{"OK", fakeBreakers{"login": {State: feign.BreakerClosed}, "exams": {State: feign.BreakerClosed}}, HealthOK},
+		{"Open", fakeBreakers{"login": {State: feign.BreakerClosed}, "exams": {State: feign.BreakerOpen, Trips: 1}}, HealthDegraded},
+		{"HalfOpen", fakeBreakers{"login": {State: feign.BreakerHalfOpen}}, HealthDegraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			(&HealthGetter{breakers: tt.breakers}).GetInfo(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+			var resp struct {
+				Data struct {
+					Status   string                    `json:"status"`
+					Breakers map[string]map[string]any `json:"breakers"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != http.StatusOK || resp.Data.Status != tt.status {
+				t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+			}
+			for name, breaker := range tt.breakers {
+				if resp.Data.Breakers[name]["state"] != breaker.State.String() {
+					t.Errorf("unexpected breaker %s: %v", name, resp.Data.Breakers[name])
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package icalendar
 
 import (
-	"strings"
 	"time"
 )
 
@@ -16,17 +15,17 @@ func NewIcsAlarm(action Action, trigger time.Duration, description string) *IcsA
 }
 
 func (a *IcsAlarm) ToIcs(_ *Timezone) string {
-	result := strings.Builder{}
-	result.WriteString("BEGIN:VALARM\n")
+	w := ContentWriter{}
+	w.Begin("VALARM")
 	action := a.action
 	if action == "" {
 		action = DISPLAY
 	}
-	result.WriteString("ACTION:" + (string(action)) + "\n")
-	result.WriteString("TRIGGER:" + DurationToIcs(a.trigger) + "\n")
-	result.WriteString("DESCRIPTION:" + a.description + "\n")
-	result.WriteString("END:VALARM\n")
-	return result.String()
+	w.WriteLine("ACTION", string(action))
+	w.WriteLine("TRIGGER", DurationToIcs(a.trigger))
+	w.WriteText("DESCRIPTION", a.description)
+	w.End("VALARM")
+	return w.String()
 }
 
 func (a *IcsAlarm) SetAction(action Action) {
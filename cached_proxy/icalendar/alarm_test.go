@@ -18,35 +18,35 @@ func TestIcsAlarm_ToIcs(t *testing.T) {
 			action:   "",
 			trigger:  0,
 			desc:     "Test",
-			expected: "BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:PT0S\nDESCRIPTION:Test\nEND:VALARM\n",
+			expected: "BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:PT0S\r\nDESCRIPTION:Test\r\nEND:VALARM\r\n",
 		},
 		{
 			name:     "TestIcsAlarm_ToIcs_WithCustomAction",
 			action:   AUDIO,
 			trigger:  0,
 			desc:     "Test",
-			expected: "BEGIN:VALARM\nACTION:AUDIO\nTRIGGER:PT0S\nDESCRIPTION:Test\nEND:VALARM\n",
+			expected: "BEGIN:VALARM\r\nACTION:AUDIO\r\nTRIGGER:PT0S\r\nDESCRIPTION:Test\r\nEND:VALARM\r\n",
 		},
 		{
 			name:     "TestIcsAlarm_ToIcs_WithCustomTrigger",
 			action:   DISPLAY,
 			trigger:  1*time.Hour + 20*time.Second,
 			desc:     "Test",
-			expected: "BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:PT1H20S\nDESCRIPTION:Test\nEND:VALARM\n",
+			expected: "BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:PT1H20S\r\nDESCRIPTION:Test\r\nEND:VALARM\r\n",
 		},
 		{
 			name:     "TestIcsAlarm_ToIcs_WithCustomActionAndTrigger",
 			action:   DISPLAY,
 			trigger:  25*time.Hour + 20*time.Second,
 			desc:     "Test",
-			expected: "BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:P1DT1H20S\nDESCRIPTION:Test\nEND:VALARM\n",
+			expected: "BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:P1DT1H20S\r\nDESCRIPTION:Test\r\nEND:VALARM\r\n",
 		},
 		{
 			name:     "TestIcsAlarm_ToIcs_WithCustomDescription",
 			action:   DISPLAY,
 			trigger:  0,
 			desc:     "Custom",
-			expected: "BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:PT0S\nDESCRIPTION:Custom\nEND:VALARM\n",
+			expected: "BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:PT0S\r\nDESCRIPTION:Custom\r\nEND:VALARM\r\n",
 		},
 	}
 	for _, tt := range tests {
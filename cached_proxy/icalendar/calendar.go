@@ -1,37 +1,56 @@
 package icalendar
 
-import (
-	"strings"
-)
+import "time"
 
 type IcsCalendar struct {
-	events    []Event
-	productID string
-	timezone  Timezone
+	events          []Event
+	productID       string
+	timezone        Timezone
+	timezones       []Timezone
+	refreshInterval time.Duration
+	name            string
+	color           string
 }
 
 func (c *IcsCalendar) ToIcs(timezone *Timezone) string {
-	result := strings.Builder{}
-	result.WriteString("BEGIN:VCALENDAR\nVERSION:2.0\n")
+	w := ContentWriter{}
+	w.Begin("VCALENDAR")
+	w.WriteLine("VERSION", "2.0")
 	if c.productID != "" {
-		result.WriteString("PRODID:" + c.productID + "\n")
+		w.WriteText("PRODID", c.productID)
+	}
+	if c.name != "" {
+		// NAME 由 RFC 7986 定义，X-WR-CALNAME 用于兼容 Apple 和 Google 日历
+		w.WriteText("NAME", c.name)
+		w.WriteText("X-WR-CALNAME", c.name)
+	}
+	if c.color != "" {
+		w.WriteLine("COLOR", c.color)
+	}
+	if c.timezone != nil {
+		w.WriteLine("X-WR-TIMEZONE", c.timezone.GetID())
+	}
+	if c.refreshInterval > 0 {
+		// REFRESH-INTERVAL 由 RFC 7986 定义，X-PUBLISHED-TTL 用于兼容 Outlook 和旧版本的日历应用
+		w.WriteLine("REFRESH-INTERVAL", DurationToIcs(c.refreshInterval), "VALUE=DURATION")
+		w.WriteLine("X-PUBLISHED-TTL", DurationToIcs(c.refreshInterval))
 	}
 
-	if timezone == nil {
-		if c.timezone == nil {
-			timezone = nil
-		} else {
-			timezone = &c.timezone
-		}
+	if timezone == nil && c.timezone != nil {
+		timezone = &c.timezone
 	}
-	if timezone != nil {
-		result.WriteString(c.timezone.ToIcs(nil))
+	start, end := c.span()
+	for _, tz := range c.zones(timezone) {
+		if !start.IsZero() {
+			tz.SetRange(start, end)
+		}
+		w.WriteComponent(tz, nil)
 	}
 	for _, e := range c.events {
-		result.WriteString(e.ToIcs(timezone))
+		w.WriteComponent(e, timezone)
 	}
-	result.WriteString("END:VCALENDAR\n")
-	return result.String()
+	w.End("VCALENDAR")
+	return w.String()
 }
 
 func (c *IcsCalendar) AddEvent(event Event) {
@@ -45,3 +64,81 @@ func (c *IcsCalendar) SetProductID(productID string) {
 func (c *IcsCalendar) SetTimezone(timezone Timezone) {
 	c.timezone = timezone
 }
+
+func (c *IcsCalendar) AddTimezone(timezone Timezone) {
+	c.timezones = append(c.timezones, timezone)
+}
+
+// zones 返回日历需要包含的所有时区，包括默认时区、添加的时区和事件使用的时区，按照 TZID 去重
+func (c *IcsCalendar) zones(timezone *Timezone) []Timezone {
+	var result []Timezone
+	exists := map[string]bool{}
+	add := func(tz Timezone) {
+		if tz == nil || exists[tz.GetID()] {
+			return
+		}
+		exists[tz.GetID()] = true
+		result = append(result, tz)
+	}
+	if timezone != nil {
+		add(*timezone)
+	}
+	for _, tz := range c.timezones {
+		add(tz)
+	}
+	for _, e := range c.events {
+		if event, ok := e.(*IcsEvent); ok {
+			add(event.timezone)
+		}
+	}
+	return result
+}
+
+// span 返回所有事件覆盖的时间范围
+func (c *IcsCalendar) span() (start, end time.Time) {
+	for _, e := range c.events {
+		event, ok := e.(*IcsEvent)
+		if !ok || event.start.IsZero() {
+			continue
+		}
+		s, t := event.span()
+		if start.IsZero() || s.Before(start) {
+			start = s
+		}
+		if t.After(end) {
+			end = t
+		}
+	}
+	return start, end
+}
+
+func (c *IcsCalendar) SetRefreshInterval(interval time.Duration) {
+	c.refreshInterval = interval
+}
+
+func (c *IcsCalendar) SetName(name string) {
+	c.name = name
+}
+
+func (c *IcsCalendar) SetColor(color string) {
+	c.color = color
+}
+
+func (c *IcsCalendar) GetEvents() []Event {
+	return c.events
+}
+
+// Merge 将另一个日历中的事件合并到当前日历，UID 已经存在的事件会被跳过
+func (c *IcsCalendar) Merge(other Calendar) {
+	exists := map[string]bool{}
+	for _, e := range c.events {
+		exists[e.GetUID()] = true
+	}
+	for _, e := range other.GetEvents() {
+		if exists[e.GetUID()] {
+			continue
+		}
+		exists[e.GetUID()] = true
+		c.AddEvent(e)
+	}
+}
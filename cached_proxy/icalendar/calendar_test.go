@@ -3,6 +3,7 @@ package icalendar
 import (
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 )
@@ -11,7 +12,7 @@ func TestIcsCalendar_ToIcs(t *testing.T) {
 	calendar := &IcsCalendar{}
 	calendar.SetProductID("productID")
 	result := calendar.ToIcs(nil)
-	expected := "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:productID\nEND:VCALENDAR\n"
+	expected := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:productID\r\nEND:VCALENDAR\r\n"
 	if result != expected {
 		t.Errorf("IcsCalendar.ToIcs() = %v, want %v", result, expected)
 	}
@@ -20,6 +21,7 @@ func TestIcsCalendar_ToIcs(t *testing.T) {
 func TestIcsCalendar_ToIcs2(t *testing.T) {
 	calendar := &IcsCalendar{}
 	calendar.SetProductID("productID")
+	shanghai := GetDefaultTimezone().Location()
 	alarms := []Alarm{
 		&IcsAlarm{
 			action:      "DISPLAY",
@@ -44,8 +46,8 @@ func TestIcsCalendar_ToIcs2(t *testing.T) {
 			location: &IcsLocation{
 				name: "Beijing",
 			},
-			start:      time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
-			end:        time.Date(2025, 2, 1, 1, 0, 0, 0, time.UTC),
+			start:      time.Date(2025, 2, 1, 0, 0, 0, 0, shanghai),
+			end:        time.Date(2025, 2, 1, 1, 0, 0, 0, shanghai),
 			dtStamp:    time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
 			alarms:     alarms,
 			repeatRule: rrule,
@@ -56,29 +58,72 @@ func TestIcsCalendar_ToIcs2(t *testing.T) {
 			location: &IcsLocation{
 				name: "Beijing",
 			},
-			start:      time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
-			end:        time.Date(2025, 2, 3, 1, 0, 0, 0, time.UTC),
+			start:      time.Date(2025, 2, 3, 0, 0, 0, 0, shanghai),
+			end:        time.Date(2025, 2, 3, 1, 0, 0, 0, shanghai),
 			dtStamp:    time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC),
 			alarms:     alarms,
 			repeatRule: rrule,
 		},
 	}
 	calendar.events = events
-	calendar.SetTimezone(&IcsTimezone{
-		id:         "Asia/Shanghai",
-		name:       "Asia/Shanghai",
-		offsetFrom: 8 * time.Hour,
-		offsetTo:   8 * time.Hour,
-		start:      time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)})
+	calendar.SetTimezone(GetDefaultTimezone())
 	result := calendar.ToIcs(nil)
 	expectedFilePath := path.Join("testdata", "test_calendar_01.ics")
 	bytes, err := os.ReadFile(expectedFilePath)
 	if err != nil {
 		t.Errorf("IcsCalendar.ToIcs() = %v, want %v", err, nil)
 	}
-	print(result)
-	expected := string(bytes)
+	// 测试数据使用 LF 换行，便于编辑，比较前转换为 CRLF
+	expected := strings.ReplaceAll(string(bytes), "\n", "\r\n")
 	if result != expected {
 		t.Errorf("IcsCalendar.ToIcs() = %v, want %v", result, expected)
 	}
 }
+
+func TestIcsCalendar_Merge(t *testing.T) {
+	first := &IcsEvent{summary: "first", start: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}
+	second := &IcsEvent{summary: "second", start: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}
+	calendar := &IcsCalendar{}
+	calendar.AddEvent(first)
+	other := &IcsCalendar{}
+	other.AddEvent(&IcsEvent{summary: "first", start: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)})
+	other.AddEvent(second)
+	calendar.Merge(other)
+	if len(calendar.GetEvents()) != 2 {
+		t.Errorf("expected 2 events after merge, got %d", len(calendar.GetEvents()))
+	}
+}
+
+func TestIcsCalendar_RefreshInterval(t *testing.T) {
+	calendar := &IcsCalendar{}
+	calendar.SetProductID("productID")
+	calendar.SetRefreshInterval(4 * time.Hour)
+	result := calendar.ToIcs(nil)
+	expected := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:productID\r\n" +
+		"REFRESH-INTERVAL;VALUE=DURATION:PT4H\r\nX-PUBLISHED-TTL:PT4H\r\nEND:VCALENDAR\r\n"
+	if result != expected {
+		t.Errorf("IcsCalendar.ToIcs() = %v, want %v", result, expected)
+	}
+	parsed, err := ParseString(result)
+	if err != nil || parsed.refreshInterval != 4*time.Hour {
+		t.Errorf("ParseString() = %v, %v", parsed, err)
+	}
+}
+
+func TestIcsCalendar_NameAndColor(t *testing.T) {
+	calendar := &IcsCalendar{}
+	calendar.SetProductID("productID")
+	calendar.SetName("拱拱日历")
+	calendar.SetColor("steelblue")
+	calendar.SetTimezone(GetDefaultTimezone())
+	result := calendar.ToIcs(nil)
+	for _, want := range []string{"NAME:拱拱日历\r\n", "X-WR-CALNAME:拱拱日历\r\n", "COLOR:steelblue\r\n", "X-WR-TIMEZONE:Asia/Shanghai\r\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("IcsCalendar.ToIcs() missing %q in %v", want, result)
+		}
+	}
+	parsed, err := ParseString(result)
+	if err != nil || parsed.name != "拱拱日历" || parsed.color != "steelblue" {
+		t.Errorf("ParseString() = %v, %v", parsed, err)
+	}
+}
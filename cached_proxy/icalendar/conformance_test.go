@@ -0,0 +1,173 @@
+package icalendar
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// contentLinePattern 对应 RFC 5545 3.1 中 contentline 的语法
+var contentLinePattern = regexp.MustCompile(`^([A-Za-z0-9-]+)((?:;[A-Za-z0-9-]+=(?:"[^"]*"|[^";:,]*)(?:,(?:"[^"]*"|[^";:,]*))*)*):(.*)$`)
+
+// textProperties 是值类型为 TEXT 的属性
+var textProperties = map[string]bool{
+	"SUMMARY": true, "DESCRIPTION": true, "LOCATION": true, "TZNAME": true,
+}
+
+// validateIcs 按照 RFC 5545 的规则校验 ICS 内容，返回发现的所有问题
+func validateIcs(ics string) []string {
+	var problems []string
+	if !strings.HasSuffix(ics, CRLF) {
+		problems = append(problems, "content does not end with CRLF")
+	}
+	if strings.Contains(strings.ReplaceAll(ics, CRLF, ""), "\n") {
+		problems = append(problems, "content contains bare LF")
+	}
+	physical := strings.Split(strings.TrimSuffix(ics, CRLF), CRLF)
+	var lines []string
+	for i, line := range physical {
+		if len(line) > MaxLineOctets {
+			problems = append(problems, fmt.Sprintf("line %d is longer than 75 octets", i+1))
+		}
+		if !utf8.ValidString(line) {
+			problems = append(problems, fmt.Sprintf("line %d is not valid UTF-8", i+1))
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if len(lines) == 0 {
+				problems = append(problems, "content starts with a continuation line")
+				continue
+			}
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var stack []string
+	properties := map[string]map[string]int{}
+	for _, line := range lines {
+		match := contentLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			problems = append(problems, fmt.Sprintf("malformed content line %q", line))
+			continue
+		}
+		name, value := strings.ToUpper(match[1]), match[3]
+		switch name {
+		case "BEGIN":
+			stack = append(stack, value)
+			properties[value] = map[string]int{}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != value {
+				problems = append(problems, fmt.Sprintf("unexpected END:%s", value))
+				continue
+			}
+			problems = append(problems, checkRequired(value, properties[value])...)
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if len(stack) == 0 {
+			problems = append(problems, fmt.Sprintf("property %s outside of a component", name))
+			continue
+		}
+		properties[stack[len(stack)-1]][name]++
+		if textProperties[name] && unescapedText(value) {
+			problems = append(problems, fmt.Sprintf("text value of %s is not escaped: %q", name, value))
+		}
+	}
+	if len(stack) != 0 {
+		problems = append(problems, fmt.Sprintf("unclosed components %v", stack))
+	}
+	return problems
+}
+
+// unescapedText 判断 TEXT 类型的值中是否存在未转义的字符
+func unescapedText(value string) bool {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if i+1 >= len(value) || !strings.ContainsRune(`\;,nN`, rune(value[i+1])) {
+				return true
+			}
+			i++
+		case ';', ',':
+			return true
+		}
+	}
+	return false
+}
+
+func checkRequired(component string, properties map[string]int) []string {
+	required := map[string][]string{
+		"VCALENDAR": {"VERSION", "PRODID"},
+		"VEVENT":    {"UID", "DTSTAMP"},
+		"VALARM":    {"ACTION", "TRIGGER"},
+		"VTIMEZONE": {"TZID"},
+	}
+	var problems []string
+	for _, name := range required[component] {
+		if properties[name] != 1 {
+			problems = append(problems, fmt.Sprintf("%s must contain exactly one %s", component, name))
+		}
+	}
+	if component == "VEVENT" && properties["DTEND"] > 0 && properties["DURATION"] > 0 {
+		problems = append(problems, "VEVENT must not contain both DTEND and DURATION")
+	}
+	return problems
+}
+
+func TestIcsCalendar_Conformance(t *testing.T) {
+	calendar := &IcsCalendar{}
+	calendar.SetProductID("-//sky31studio//GongGong//CN")
+	calendar.SetTimezone(GetDefaultTimezone())
+	event := &IcsEvent{}
+	event.SetSummary("【课程】高等数学（上）, 习题课; 第二讲")
+	event.SetDescription("授课教师：张三  2节课\n周次：1-16\n" + strings.Repeat("数据来自【拱拱】", 10))
+	location := &IcsLocation{}
+	location.SetName("逸夫楼,301;A\\B")
+	event.SetLocation(location)
+	event.SetStart(time.Date(2025, 2, 17, 8, 0, 0, 0, time.UTC))
+	event.SetEnd(time.Date(2025, 2, 17, 9, 40, 0, 0, time.UTC))
+	rule := &IcsRepeatRule{}
+	rule.SetFrequency("WEEKLY")
+	rule.SetInterval(2)
+	rule.SetCount(8)
+	event.SetRepeatRule(rule)
+	event.AddExceptionDate(time.Date(2025, 4, 4, 8, 0, 0, 0, time.UTC))
+	event.AddAlarm(NewIcsAlarm(DISPLAY, -28*time.Minute, "距离上课仅剩28分钟"))
+	calendar.AddEvent(event)
+
+	ics := calendar.ToIcs(nil)
+	for _, problem := range validateIcs(ics) {
+		t.Error(problem)
+	}
+	if !strings.Contains(ics, "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=8"+CRLF) {
+		t.Errorf("RRULE should render INTERVAL as a number:\n%s", ics)
+	}
+	if !strings.Contains(ics, `SUMMARY:【课程】高等数学（上）\, 习题课\; 第二讲`) {
+		t.Errorf("SUMMARY should be escaped:\n%s", ics)
+	}
+}
+
+func TestValidateIcs(t *testing.T) {
+	tests := []struct {
+		name string
+		ics  string
+	}{
+		{name: "LF line ending", ics: "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:p\nEND:VCALENDAR\n"},
+		{name: "Missing PRODID", ics: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nEND:VCALENDAR\r\n"},
+		{name: "Unescaped text", ics: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:p\r\nBEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20250101T000000Z\r\nSUMMARY:a,b\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"},
+		{name: "Long line", ics: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:" + strings.Repeat("p", 80) + "\r\nEND:VCALENDAR\r\n"},
+		{name: "Unbalanced", ics: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:p\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problems := validateIcs(tt.ics); len(problems) == 0 {
+				t.Errorf("validateIcs() should report problems for %q", tt.ics)
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package icalendar
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type IcsEvent struct {
+	id          string
 	summary     string
 	description string
 	location    Location
@@ -13,42 +16,139 @@ type IcsEvent struct {
 	end         time.Time
 	alarms      []Alarm
 	repeatRule  RepeatRule
+	exDates     []time.Time
 	dtStamp     time.Time
+	sequence    int
+	modified    time.Time
+	categories  []string
+	color       string
+	allDay      bool
+	timezone    Timezone
+	floating    bool
+	duration    time.Duration
+	status      Status
+	transparent Transparency
+	url         string
+	organizer   *Person
+	attendees   []Person
+	rDates      []time.Time
 }
 
 func (e *IcsEvent) uid() string {
+	if e.id != "" {
+		return e.id
+	}
 	return e.summary + e.start.Format("20060102T150405")
 }
 
 func (e *IcsEvent) ToIcs(timezone *Timezone) string {
-	result := strings.Builder{}
-	result.WriteString("BEGIN:VEVENT\n")
+	if e.timezone != nil {
+		timezone = &e.timezone
+	}
+	w := ContentWriter{}
+	w.Begin("VEVENT")
 	if e.dtStamp.IsZero() {
 		e.dtStamp = time.Now()
 	}
-	result.WriteString("DTSTAMP" + TimeToIcs(e.dtStamp, timezone, ":") + "\n")
-	result.WriteString("SUMMARY:" + e.summary + "\n")
+	// DTSTAMP 必须使用 UTC 时间
+	w.WriteTime("DTSTAMP", e.dtStamp, nil)
+	w.WriteText("SUMMARY", e.summary)
 	if e.description != "" {
-		result.WriteString("DESCRIPTION:" + e.description + "\n")
+		w.WriteText("DESCRIPTION", e.description)
 	}
 	if e.location != nil {
-		result.WriteString(e.location.ToIcs(timezone) + "\n")
+		w.WriteComponent(e.location, timezone)
+	}
+	if len(e.categories) > 0 {
+		categories := make([]string, len(e.categories))
+		for i, category := range e.categories {
+			categories[i] = EscapeText(category)
+		}
+		w.WriteLine("CATEGORIES", strings.Join(categories, ","))
+	}
+	if e.color != "" {
+		w.WriteLine("COLOR", e.color)
+	}
+	if e.status != "" {
+		w.WriteLine("STATUS", string(e.status))
+	}
+	if e.transparent != "" {
+		w.WriteLine("TRANSP", string(e.transparent))
+	}
+	if e.url != "" {
+		w.WriteLine("URL", e.url, "VALUE=URI")
+	}
+	if e.organizer != nil {
+		w.WriteLine("ORGANIZER", "mailto:"+e.organizer.Email, personParams(*e.organizer)...)
+	}
+	for _, attendee := range e.attendees {
+		w.WriteLine("ATTENDEE", "mailto:"+attendee.Email, personParams(attendee)...)
 	}
-	if !e.start.IsZero() {
-		result.WriteString("DTSTART" + TimeToIcs(e.start, timezone, ":") + "\n")
+	e.writeTime(&w, "DTSTART", e.start, timezone)
+	// DTEND 和 DURATION 不能同时出现
+	if e.duration > 0 {
+		w.WriteLine("DURATION", DurationToIcs(e.duration))
+	} else {
+		e.writeTime(&w, "DTEND", e.end, timezone)
 	}
-	if !e.end.IsZero() {
-		result.WriteString("DTEND" + TimeToIcs(e.end, timezone, ":") + "\n")
+	if rule, ok := e.repeatRule.(*IcsRepeatRule); ok && (e.allDay || e.floating) {
+		// 全天事件和浮动时间的事件，UNTIL 需要使用与 DTSTART 相同的类型
+		w.WriteLine("RRULE", rule.value(func(until time.Time) string {
+			if e.allDay {
+				return DateToIcs(until)
+			}
+			return until.Format("20060102T150405")
+		}))
+	} else if e.repeatRule != nil {
+		w.WriteComponent(e.repeatRule, timezone)
 	}
+	for _, rDate := range e.rDates {
+		e.writeTime(&w, "RDATE", rDate, timezone)
+	}
+	for _, exDate := range e.exDates {
+		e.writeTime(&w, "EXDATE", exDate, timezone)
+	}
+	w.WriteText("UID", e.uid())
+	if e.sequence > 0 {
+		w.WriteLine("SEQUENCE", strconv.Itoa(e.sequence))
+	}
+	if !e.modified.IsZero() {
+		w.WriteTime("LAST-MODIFIED", e.modified, nil)
+	}
+	// VALARM 子组件必须位于所有属性之后
 	for _, alarm := range e.alarms {
-		result.WriteString(alarm.ToIcs(timezone))
+		w.WriteComponent(alarm, timezone)
 	}
-	if e.repeatRule != nil {
-		result.WriteString(e.repeatRule.ToIcs(timezone) + "\n")
+	w.End("VEVENT")
+	return w.String()
+}
+
+// writeTime 写入事件的时间属性，全天事件使用 DATE 类型，浮动时间不带时区和 Z 后缀
+func (e *IcsEvent) writeTime(w *ContentWriter, name string, t time.Time, timezone *Timezone) {
+	if t.IsZero() {
+		return
+	}
+	if e.allDay {
+		w.WriteLine(name, DateToIcs(t), "VALUE=DATE")
+		return
+	}
+	if e.floating {
+		w.WriteLine(name, t.Format("20060102T150405"))
+		return
 	}
-	result.WriteString("UID:" + e.uid() + "\n")
-	result.WriteString("END:VEVENT\n")
-	return result.String()
+	w.WriteTime(name, t, timezone)
+}
+
+// personParams 生成 ORGANIZER 和 ATTENDEE 的参数
+func personParams(person Person) []string {
+	var params []string
+	if person.Name != "" {
+		params = append(params, "CN="+ParamValue(person.Name))
+	}
+	if person.Role != "" {
+		params = append(params, "ROLE="+person.Role)
+	}
+	return params
 }
 
 func (e *IcsEvent) SetSummary(summary string) {
@@ -78,3 +178,151 @@ func (e *IcsEvent) AddAlarm(alarm Alarm) {
 func (e *IcsEvent) SetRepeatRule(rule RepeatRule) {
 	e.repeatRule = rule
 }
+
+func (e *IcsEvent) AddExceptionDate(date time.Time) {
+	e.exDates = append(e.exDates, date)
+}
+
+func (e *IcsEvent) SetUID(uid string) {
+	e.id = uid
+}
+
+func (e *IcsEvent) GetUID() string {
+	return e.uid()
+}
+
+func (e *IcsEvent) SetSequence(sequence int) {
+	e.sequence = sequence
+}
+
+func (e *IcsEvent) SetLastModified(modified time.Time) {
+	e.modified = modified
+}
+
+func (e *IcsEvent) SetDtStamp(dtStamp time.Time) {
+	e.dtStamp = dtStamp
+}
+
+func (e *IcsEvent) SetCategories(categories ...string) {
+	e.categories = categories
+}
+
+func (e *IcsEvent) SetColor(color string) {
+	e.color = color
+}
+
+func (e *IcsEvent) SetAllDay(allDay bool) {
+	e.allDay = allDay
+}
+
+func (e *IcsEvent) SetTimezone(timezone Timezone) {
+	e.timezone = timezone
+}
+
+// span 返回事件所有重复覆盖的大致时间范围，用于确定 VTIMEZONE 需要包含的时区转换
+func (e *IcsEvent) span() (start, end time.Time) {
+	start, end = e.start, e.end
+	if e.duration > 0 {
+		end = start.Add(e.duration)
+	}
+	if end.Before(start) {
+		end = start
+	}
+	for _, rDate := range e.rDates {
+		if rDate.Before(start) {
+			start = rDate
+		}
+		if rDate.After(end) {
+			end = rDate
+		}
+	}
+	rule, ok := e.repeatRule.(*IcsRepeatRule)
+	if !ok || rule == nil {
+		return start, end
+	}
+	length := end.Sub(start)
+	switch {
+	case !rule.until.IsZero():
+		if rule.until.After(end) {
+			end = rule.until.Add(length)
+		}
+	case rule.count > 0:
+		occurrences := rule.Occurrences(e.start, e.start, e.start.AddDate(100, 0, 0))
+		if len(occurrences) > 0 && occurrences[len(occurrences)-1].Add(length).After(end) {
+			end = occurrences[len(occurrences)-1].Add(length)
+		}
+	default:
+		// 没有结束的重复规则只覆盖之后的一年
+		end = end.AddDate(1, 0, 0)
+	}
+	return start, end
+}
+
+func (e *IcsEvent) SetFloating(floating bool) {
+	e.floating = floating
+}
+
+func (e *IcsEvent) SetDuration(duration time.Duration) {
+	e.duration = duration
+}
+
+func (e *IcsEvent) SetStatus(status Status) {
+	e.status = status
+}
+
+func (e *IcsEvent) SetTransparency(transparency Transparency) {
+	e.transparent = transparency
+}
+
+func (e *IcsEvent) SetURL(url string) {
+	e.url = url
+}
+
+func (e *IcsEvent) SetOrganizer(organizer Person) {
+	e.organizer = &organizer
+}
+
+func (e *IcsEvent) AddAttendee(attendee Person) {
+	e.attendees = append(e.attendees, attendee)
+}
+
+func (e *IcsEvent) AddRecurrenceDate(date time.Time) {
+	e.rDates = append(e.rDates, date)
+}
+
+func (e *IcsEvent) Occurrences(from, to time.Time) []time.Time {
+	if e.start.IsZero() {
+		return nil
+	}
+	var result []time.Time
+	if e.repeatRule != nil {
+		result = e.repeatRule.Occurrences(e.start, from, to)
+	} else if !e.start.Before(from) && e.start.Before(to) {
+		result = append(result, e.start)
+	}
+	for _, rDate := range e.rDates {
+		if !rDate.Before(from) && rDate.Before(to) {
+			result = append(result, rDate)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Before(result[j])
+	})
+	occurrences := result[:0]
+	for i, t := range result {
+		if i > 0 && t.Equal(result[i-1]) {
+			continue
+		}
+		excluded := false
+		for _, exDate := range e.exDates {
+			if t.Equal(exDate) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences
+}
@@ -1,6 +1,7 @@
 package icalendar
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -35,7 +36,7 @@ func TestIcsEvent_ToIcs(t *testing.T) {
 				dtStamp:     time.Date(2025, 2, 3, 12, 34, 32, 0, time.UTC),
 			},
 			args: args{},
-			want: "BEGIN:VEVENT\nDTSTAMP:20250203T123432Z\nSUMMARY:summary\nDESCRIPTION:description\nDTSTART:20210101T000000Z\nDTEND:20210101T010000Z\nUID:summary20210101T000000\nEND:VEVENT\n",
+			want: "BEGIN:VEVENT\r\nDTSTAMP:20250203T123432Z\r\nSUMMARY:summary\r\nDESCRIPTION:description\r\nDTSTART:20210101T000000Z\r\nDTEND:20210101T010000Z\r\nUID:summary20210101T000000\r\nEND:VEVENT\r\n",
 		},
 	}
 	for _, tt := range tests {
@@ -56,3 +57,95 @@ func TestIcsEvent_ToIcs(t *testing.T) {
 		})
 	}
 }
+
+func TestIcsEvent_AllDayCategoriesColor(t *testing.T) {
+	event := &IcsEvent{dtStamp: time.Date(2025, 2, 3, 12, 34, 32, 0, time.UTC)}
+	event.SetSummary("第1周")
+	event.SetCategories("教学周", "课程,考试")
+	event.SetColor("gray")
+	event.SetAllDay(true)
+	event.SetStart(time.Date(2025, 2, 17, 0, 0, 0, 0, time.UTC))
+	event.SetEnd(time.Date(2025, 2, 24, 0, 0, 0, 0, time.UTC))
+	event.SetUID("week-1")
+	want := "BEGIN:VEVENT\r\nDTSTAMP:20250203T123432Z\r\nSUMMARY:第1周\r\nCATEGORIES:教学周,课程\\,考试\r\nCOLOR:gray\r\n" +
+		"DTSTART;VALUE=DATE:20250217\r\nDTEND;VALUE=DATE:20250224\r\nUID:week-1\r\nEND:VEVENT\r\n"
+	timezone := Timezone(GetDefaultTimezone())
+	if got := event.ToIcs(&timezone); got != want {
+		t.Errorf("ToIcs() = %v, want %v", got, want)
+	}
+	calendar := &IcsCalendar{}
+	calendar.AddEvent(event)
+	parsed, err := ParseString(calendar.ToIcs(nil))
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+	got := parsed.GetEvents()[0].(*IcsEvent)
+	if !got.allDay || got.color != "gray" || len(got.categories) != 2 || got.categories[1] != "课程,考试" {
+		t.Errorf("ParseString() = %+v", got)
+	}
+}
+
+func TestIcsEvent_Extensions(t *testing.T) {
+	dtStamp := time.Date(2025, 2, 3, 12, 34, 32, 0, time.UTC)
+	tests := []struct {
+		name  string
+		setup func(e *IcsEvent)
+		want  string
+	}{
+		{
+			name: "floating with duration",
+			setup: func(e *IcsEvent) {
+				e.SetFloating(true)
+				e.SetStart(time.Date(2025, 2, 17, 8, 0, 0, 0, time.UTC))
+				e.SetEnd(time.Date(2025, 2, 17, 9, 0, 0, 0, time.UTC))
+				e.SetDuration(95 * time.Minute)
+				e.AddRecurrenceDate(time.Date(2025, 2, 20, 8, 0, 0, 0, time.UTC))
+				e.AddExceptionDate(time.Date(2025, 2, 24, 8, 0, 0, 0, time.UTC))
+			},
+			want: "DTSTART:20250217T080000\r\nDURATION:PT1H35M\r\nRDATE:20250220T080000\r\nEXDATE:20250224T080000\r\n",
+		},
+		{
+			name: "status, transparency and url",
+			setup: func(e *IcsEvent) {
+				e.SetStatus(CANCELLED)
+				e.SetTransparency(TRANSPARENT)
+				e.SetURL("https://example.com/course?id=1")
+			},
+			want: "STATUS:CANCELLED\r\nTRANSP:TRANSPARENT\r\nURL;VALUE=URI:https://example.com/course?id=1\r\n",
+		},
+		{
+			name: "organizer and attendees",
+			setup: func(e *IcsEvent) {
+				e.SetOrganizer(Person{Name: "张三, 教授", Email: "zhangsan@example.com"})
+				e.AddAttendee(Person{Name: "李四", Email: "lisi@example.com", Role: "REQ-PARTICIPANT"})
+			},
+			want: "ORGANIZER;CN=\"张三, 教授\":mailto:zhangsan@example.com\r\nATTENDEE;CN=李四;ROLE=REQ-PARTICIPANT:mailto:lisi@example.com\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &IcsEvent{dtStamp: dtStamp}
+			event.SetSummary("summary")
+			event.SetUID("uid")
+			tt.setup(event)
+			got := event.ToIcs(nil)
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("ToIcs() = %v, want it to contain %v", got, tt.want)
+			}
+			calendar := &IcsCalendar{}
+			calendar.SetProductID("productID")
+			calendar.AddEvent(event)
+			ics := calendar.ToIcs(nil)
+			for _, problem := range validateIcs(ics) {
+				t.Error(problem)
+			}
+			parsed, err := ParseString(ics)
+			if err != nil {
+				t.Fatalf("ParseString() error = %v", err)
+			}
+			if roundTrip := parsed.ToIcs(nil); roundTrip != ics {
+				t.Errorf("round trip mismatch:\n%s\nwant:\n%s", roundTrip, ics)
+			}
+		})
+	}
+}
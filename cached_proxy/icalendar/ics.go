@@ -18,8 +18,12 @@ type Timezone interface {
 	SetName(name string)
 	// SetStart 设置时区的开始时间
 	SetStart(start time.Time)
+	// SetRange 设置需要覆盖的时间范围，生成的规则会包含该范围内的所有时区转换
+	SetRange(start, end time.Time)
 	// GetID 获取时区的 ID
 	GetID() string
+	// Location 获取时区对应的 time.Location
+	Location() *time.Location
 }
 
 // Component 是 ICS 文件中的组件。
@@ -34,6 +38,30 @@ const (
 	AUDIO   = "AUDIO"
 )
 
+// Status 是事件的状态
+type Status string
+
+const (
+	TENTATIVE = "TENTATIVE"
+	CONFIRMED = "CONFIRMED"
+	CANCELLED = "CANCELLED"
+)
+
+// Transparency 表示事件是否占用时间，TRANSPARENT 的事件在忙闲查询中不算作忙碌
+type Transparency string
+
+const (
+	OPAQUE      = "OPAQUE"
+	TRANSPARENT = "TRANSPARENT"
+)
+
+// Person 是事件的组织者或参与者
+type Person struct {
+	Name  string // 显示的名称，写入 CN 参数
+	Email string // 邮箱地址，写入 mailto: URI
+	Role  string // 参与者的角色，例如 REQ-PARTICIPANT，仅用于 ATTENDEE
+}
+
 // Alarm 是 ICS 文件中的提醒。
 type Alarm interface {
 	Component
@@ -54,8 +82,22 @@ type RepeatRule interface {
 	SetInterval(interval int)
 	// SetCount 设置重复规则的次数
 	SetCount(count int)
-	// SetUntil 设置重复规则的结束时间
+	// SetUntil 设置重复规则的结束时间（包含）
 	SetUntil(until time.Time)
+	// SetByMonth 设置 BYMONTH，取值 1 到 12
+	SetByMonth(months ...int)
+	// SetByWeekNo 设置 BYWEEKNO，取值 1 到 53 或 -53 到 -1，仅用于 YEARLY
+	SetByWeekNo(weeks ...int)
+	// SetByMonthDay 设置 BYMONTHDAY，取值 1 到 31 或 -31 到 -1
+	SetByMonthDay(days ...int)
+	// SetByDay 设置 BYDAY
+	SetByDay(days ...WeekdayNum)
+	// SetBySetPos 设置 BYSETPOS，从每个周期的候选中按位置选取
+	SetBySetPos(positions ...int)
+	// SetWeekStart 设置 WKST，即每周的第一天
+	SetWeekStart(day time.Weekday)
+	// Occurrences 返回以 start 为 DTSTART 时，在 [from, to) 范围内的所有重复时间
+	Occurrences(start, from, to time.Time) []time.Time
 }
 
 type Location interface {
@@ -81,6 +123,46 @@ type Event interface {
 	AddAlarm(alarm Alarm)
 	// SetRepeatRule 设置事件的重复规则
 	SetRepeatRule(rule RepeatRule)
+	// AddExceptionDate 添加一个重复规则的例外日期，该日期的事件将不会出现
+	AddExceptionDate(date time.Time)
+	// SetUID 设置事件的唯一标识，未设置时根据摘要和开始时间生成
+	SetUID(uid string)
+	// GetUID 获取事件的唯一标识
+	GetUID() string
+	// SetSequence 设置事件的修订次数，事件内容变化后需要递增
+	SetSequence(sequence int)
+	// SetLastModified 设置事件的最后修改时间
+	SetLastModified(modified time.Time)
+	// SetDtStamp 设置事件的时间戳，未设置时使用生成日历的时间
+	SetDtStamp(dtStamp time.Time)
+	// SetCategories 设置事件的分类
+	SetCategories(categories ...string)
+	// SetColor 设置事件的颜色，使用 CSS3 颜色名称，参见 RFC 7986
+	SetColor(color string)
+	// SetAllDay 设置事件是否为全天事件，全天事件的开始和结束时间只保留日期
+	SetAllDay(allDay bool)
+	// SetTimezone 设置事件使用的时区，未设置时使用日历的时区
+	SetTimezone(timezone Timezone)
+	// SetFloating 设置事件是否为浮动时间，浮动时间不带时区，在任何时区都显示为相同的本地时间
+	SetFloating(floating bool)
+	// SetDuration 设置事件的持续时间，设置后使用 DURATION 代替 DTEND
+	SetDuration(duration time.Duration)
+	// SetStatus 设置事件的状态
+	SetStatus(status Status)
+	// SetTransparency 设置事件是否占用时间
+	SetTransparency(transparency Transparency)
+	// SetURL 设置事件的链接
+	SetURL(url string)
+	// SetOrganizer 设置事件的组织者
+	SetOrganizer(organizer Person)
+	// AddAttendee 添加一个参与者
+	AddAttendee(attendee Person)
+	// AddRecurrenceDate 添加一个额外的重复日期
+	AddRecurrenceDate(date time.Time)
+	// Occurrences 返回事件在 [from, to) 范围内的所有开始时间，包含 RRULE 和 RDATE 并排除 EXDATE
+	Occurrences(from, to time.Time) []time.Time
+	// Overlaps 判断事件是否有某次重复与 [from, to) 相交
+	Overlaps(from, to time.Time) bool
 }
 
 type Calendar interface {
@@ -89,16 +171,32 @@ type Calendar interface {
 	AddEvent(event Event)
 	// SetProductID 设置日历的产品 ID
 	SetProductID(productID string)
-	// SetTimezone 设置日历的时区
+	// SetTimezone 设置日历的默认时区
 	SetTimezone(timezone Timezone)
+	// AddTimezone 添加一个时区，事件使用的时区会自动添加
+	AddTimezone(timezone Timezone)
+	// GetEvents 获取日历中的所有事件
+	GetEvents() []Event
+	// SetRefreshInterval 设置订阅日历的建议刷新间隔
+	SetRefreshInterval(interval time.Duration)
+	// SetName 设置日历的名称
+	SetName(name string)
+	// SetColor 设置日历的颜色，使用 CSS3 颜色名称，参见 RFC 7986
+	SetColor(color string)
 }
 
+// TimeToIcs 将时间转换为 DATE-TIME 类型的值，timezone 为 nil 时使用 UTC 时间，否则转换为该时区的本地时间
 func TimeToIcs(t time.Time, timezone *Timezone, sep string) string {
-	ts := t.Format("20060102T150405")
 	if timezone == nil {
-		return fmt.Sprintf("%s%sZ", sep, ts)
+		return fmt.Sprintf("%s%sZ", sep, t.UTC().Format("20060102T150405"))
 	}
-	return fmt.Sprintf(";TZID=%s%s%s", (*timezone).GetID(), sep, ts)
+	t = t.In((*timezone).Location())
+	return fmt.Sprintf(";TZID=%s%s%s", (*timezone).GetID(), sep, t.Format("20060102T150405"))
+}
+
+// DateToIcs 将时间转换为 DATE 类型的值，例如 20250217
+func DateToIcs(t time.Time) string {
+	return t.Format("20060102")
 }
 
 func DurationToIcs(d time.Duration) string {
@@ -133,9 +231,17 @@ func DurationToIcs(d time.Duration) string {
 	return result.String()
 }
 
+// OffsetToIcs 将 UTC 偏移转换为 UTC-OFFSET 类型的值，例如 +0800、-0330、+054500
 func OffsetToIcs(d time.Duration) string {
-	if d == 0 {
-		return "Z"
+	sign := "+"
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	seconds := int(d / time.Second)
+	hours, minutes, seconds := seconds/3600, seconds%3600/60, seconds%60
+	if seconds != 0 {
+		return fmt.Sprintf("%s%02d%02d%02d", sign, hours, minutes, seconds)
 	}
-	return fmt.Sprintf("+%02d00", int(d.Hours()))
+	return fmt.Sprintf("%s%02d%02d", sign, hours, minutes)
 }
@@ -5,7 +5,9 @@ type IcsLocation struct {
 }
 
 func (l *IcsLocation) ToIcs(_ *Timezone) string {
-	return "LOCATION:" + l.name
+	w := ContentWriter{}
+	w.WriteText("LOCATION", l.name)
+	return w.String()
 }
 
 func (l *IcsLocation) SetName(name string) {
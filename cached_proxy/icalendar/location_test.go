@@ -23,7 +23,7 @@ func TestIcsLocation_ToIcs(t *testing.T) {
 			args: args{
 				in0: nil,
 			},
-			want: "LOCATION:name",
+			want: "LOCATION:name\r\n",
 		},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,644 @@
+package icalendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// property 是解析后的一行内容
+type property struct {
+	name   string
+	params map[string][]string // 参数的值，列表参数有多个值
+	value  string
+}
+
+// param 返回参数的第一个值，参数不存在时返回空字符串
+func (p property) param(key string) string {
+	if values := p.params[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// rawComponent 是解析后尚未转换的组件
+type rawComponent struct {
+	name       string
+	properties []property
+	children   []*rawComponent
+}
+
+// get 返回第一个名称匹配的属性
+func (c *rawComponent) get(name string) (property, bool) {
+	for _, p := range c.properties {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return property{}, false
+}
+
+// ParseString 解析 ICS 格式的字符串
+func ParseString(ics string) (*IcsCalendar, error) {
+	return Parse(strings.NewReader(ics))
+}
+
+// Parse 解析 ICS 内容，支持 VCALENDAR、VEVENT、VALARM、VTIMEZONE 和 RRULE，未知的属性和组件会被忽略
+func Parse(reader io.Reader) (*IcsCalendar, error) {
+	lines, err := unfoldLines(reader)
+	if err != nil {
+		return nil, err
+	}
+	root, err := buildComponents(lines)
+	if err != nil {
+		return nil, err
+	}
+	if root.name != "VCALENDAR" {
+		return nil, fmt.Errorf("expected VCALENDAR, got %s", root.name)
+	}
+	return convertCalendar(root)
+}
+
+// unfoldLines 读取所有内容行并展开折行，同时兼容 LF 换行
+func unfoldLines(reader io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(lines) == 0 {
+				return nil, fmt.Errorf("unexpected continuation line: %q", line)
+			}
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty 按照 RFC 5545 3.1 解析一行内容，参数值可以是以逗号分隔的列表，每一项可以带引号
+func parseProperty(line string) (property, error) {
+	p := property{params: map[string][]string{}}
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return p, fmt.Errorf("malformed content line: %q", line)
+	}
+	p.name = strings.ToUpper(line[:i])
+	line = line[i:]
+	for line[0] == ';' {
+		line = line[1:]
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return p, fmt.Errorf("malformed parameter in %s", p.name)
+		}
+		key := strings.ToUpper(line[:eq])
+		line = line[eq+1:]
+		var values []string
+		for {
+			var value string
+			if strings.HasPrefix(line, `"`) {
+				end := strings.IndexByte(line[1:], '"')
+				if end < 0 {
+					return p, fmt.Errorf("unterminated quoted parameter in %s", p.name)
+				}
+				value, line = line[1:end+1], line[end+2:]
+				if line != "" && !strings.ContainsRune(",;:", rune(line[0])) {
+					return p, fmt.Errorf("unexpected %q after quoted parameter %s in %s", line[0], key, p.name)
+				}
+			} else {
+				end := strings.IndexAny(line, ",;:")
+				if end < 0 {
+					return p, fmt.Errorf("malformed parameter in %s", p.name)
+				}
+				value, line = line[:end], line[end:]
+			}
+			values = append(values, value)
+			if len(line) == 0 {
+				return p, fmt.Errorf("missing value in %s", p.name)
+			}
+			if line[0] != ',' {
+				break
+			}
+			line = line[1:]
+		}
+		p.params[key] = values
+	}
+	p.value = line[1:]
+	return p, nil
+}
+
+// buildComponents 根据 BEGIN 和 END 将内容行组装为组件树
+func buildComponents(lines []string) (*rawComponent, error) {
+	var root *rawComponent
+	var stack []*rawComponent
+	for _, line := range lines {
+		p, err := parseProperty(line)
+		if err != nil {
+			return nil, err
+		}
+		switch p.name {
+		case "BEGIN":
+			component := &rawComponent{name: strings.ToUpper(p.value)}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("multiple top-level components")
+				}
+				root = component
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, component)
+			}
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("unexpected END:%s", p.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("property %s outside of a component", p.name)
+			}
+			current := stack[len(stack)-1]
+			current.properties = append(current.properties, p)
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("empty calendar")
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("unclosed component %s", stack[len(stack)-1].name)
+	}
+	return root, nil
+}
+
+// parseContext 保存解析过程中的时区信息
+type parseContext struct {
+	timezones map[string]Timezone
+	defaultID string // 日历默认时区的 TZID
+}
+
+// location 返回 TZID 对应的时区，优先使用日历中的 VTIMEZONE，其次使用 IANA 时区数据库
+func (ctx *parseContext) location(tzid string) *time.Location {
+	if tz, found := ctx.timezones[tzid]; found {
+		return tz.Location()
+	}
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+func convertCalendar(root *rawComponent) (*IcsCalendar, error) {
+	calendar := &IcsCalendar{}
+	ctx := &parseContext{timezones: map[string]Timezone{}}
+	if p, found := root.get("PRODID"); found {
+		calendar.SetProductID(UnescapeText(p.value))
+	}
+	if p, found := root.get("NAME"); found {
+		calendar.SetName(UnescapeText(p.value))
+	} else if p, found := root.get("X-WR-CALNAME"); found {
+		calendar.SetName(UnescapeText(p.value))
+	}
+	if p, found := root.get("COLOR"); found {
+		calendar.SetColor(p.value)
+	}
+	if p, found := root.get("REFRESH-INTERVAL"); found {
+		interval, err := ParseIcsDuration(p.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse REFRESH-INTERVAL: %w", err)
+		}
+		calendar.SetRefreshInterval(interval)
+	}
+	for _, child := range root.children {
+		if child.name != "VTIMEZONE" {
+			continue
+		}
+		timezone, err := convertTimezone(child)
+		if err != nil {
+			return nil, err
+		}
+		ctx.timezones[timezone.GetID()] = timezone
+		if calendar.timezone == nil {
+			calendar.SetTimezone(timezone)
+			ctx.defaultID = timezone.GetID()
+		} else {
+			calendar.AddTimezone(timezone)
+		}
+	}
+	for _, child := range root.children {
+		if child.name != "VEVENT" {
+			continue
+		}
+		event, err := convertEvent(ctx, child)
+		if err != nil {
+			return nil, err
+		}
+		calendar.AddEvent(event)
+	}
+	return calendar, nil
+}
+
+// convertTimezone 转换 VTIMEZONE，TZID 存在于时区数据库时使用数据库中的规则，否则使用第一条规则的固定偏移
+func convertTimezone(component *rawComponent) (*IcsTimezone, error) {
+	timezone := &IcsTimezone{}
+	if p, found := component.get("TZID"); found {
+		if tz, err := LoadTimezone(p.value); err == nil {
+			return tz, nil
+		}
+		timezone.SetID(p.value)
+		timezone.SetName(p.value)
+	}
+	for _, child := range component.children {
+		if child.name != "STANDARD" && child.name != "DAYLIGHT" {
+			continue
+		}
+		if p, found := child.get("DTSTART"); found {
+			start, err := ParseIcsTime(p.value, time.UTC)
+			if err != nil {
+				return nil, err
+			}
+			timezone.SetStart(start)
+		}
+		if p, found := child.get("TZOFFSETFROM"); found {
+			offset, err := ParseIcsOffset(p.value)
+			if err != nil {
+				return nil, err
+			}
+			timezone.SetOffsetFrom(offset)
+		}
+		if p, found := child.get("TZOFFSETTO"); found {
+			offset, err := ParseIcsOffset(p.value)
+			if err != nil {
+				return nil, err
+			}
+			timezone.SetOffsetTo(offset)
+		}
+		if p, found := child.get("TZNAME"); found {
+			timezone.SetName(UnescapeText(p.value))
+		}
+		break
+	}
+	return timezone, nil
+}
+
+func convertEvent(ctx *parseContext, component *rawComponent) (*IcsEvent, error) {
+	event := &IcsEvent{}
+	// RRULE 中不带 Z 的 UNTIL 使用 DTSTART 的时区，RRULE 可能出现在 DTSTART 之前，因此预先确定
+	startLocation := time.UTC
+	if p, found := component.get("DTSTART"); found {
+		startLocation = ctx.location(p.param("TZID"))
+	}
+	for _, p := range component.properties {
+		var err error
+		switch p.name {
+		case "UID":
+			event.SetUID(UnescapeText(p.value))
+		case "SUMMARY":
+			event.SetSummary(UnescapeText(p.value))
+		case "DESCRIPTION":
+			event.SetDescription(UnescapeText(p.value))
+		case "LOCATION":
+			location := &IcsLocation{}
+			location.SetName(UnescapeText(p.value))
+			event.SetLocation(location)
+		case "SEQUENCE":
+			event.sequence, err = strconv.Atoi(p.value)
+		case "LAST-MODIFIED":
+			event.modified, err = ParseIcsTime(p.value, time.UTC)
+		case "DTSTAMP":
+			event.dtStamp, err = ParseIcsTime(p.value, ctx.location(p.param("TZID")))
+		case "CATEGORIES":
+			for _, category := range splitText(p.value) {
+				event.categories = append(event.categories, UnescapeText(category))
+			}
+		case "COLOR":
+			event.SetColor(p.value)
+		case "DTSTART":
+			event.allDay = strings.EqualFold(p.param("VALUE"), "DATE")
+			// 既没有 TZID 也不是 UTC 时间的 DATE-TIME 是浮动时间
+			event.floating = !event.allDay && p.param("TZID") == "" && !strings.HasSuffix(p.value, "Z")
+			if tzid := p.param("TZID"); tzid != "" && tzid != ctx.defaultID {
+				if tz, found := ctx.timezones[tzid]; found {
+					event.SetTimezone(tz)
+				}
+			}
+			event.start, err = ParseIcsTime(p.value, ctx.location(p.param("TZID")))
+		case "DTEND":
+			event.end, err = ParseIcsTime(p.value, ctx.location(p.param("TZID")))
+		case "RRULE":
+			var rule *IcsRepeatRule
+			rule, err = parseRepeatRule(p.value, startLocation)
+			if err == nil {
+				event.SetRepeatRule(rule)
+			}
+		case "DURATION":
+			event.duration, err = ParseIcsDuration(p.value)
+		case "STATUS":
+			event.SetStatus(Status(strings.ToUpper(p.value)))
+		case "TRANSP":
+			event.SetTransparency(Transparency(strings.ToUpper(p.value)))
+		case "URL":
+			event.SetURL(p.value)
+		case "ORGANIZER":
+			event.SetOrganizer(parsePerson(p))
+		case "ATTENDEE":
+			event.AddAttendee(parsePerson(p))
+		case "RDATE":
+			for _, value := range strings.Split(p.value, ",") {
+				var rDate time.Time
+				if rDate, err = ParseIcsTime(value, ctx.location(p.param("TZID"))); err != nil {
+					break
+				}
+				event.AddRecurrenceDate(rDate)
+			}
+		case "EXDATE":
+			for _, value := range strings.Split(p.value, ",") {
+				var exDate time.Time
+				if exDate, err = ParseIcsTime(value, ctx.location(p.param("TZID"))); err != nil {
+					break
+				}
+				event.AddExceptionDate(exDate)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p.name, err)
+		}
+	}
+	for _, child := range component.children {
+		if child.name != "VALARM" {
+			continue
+		}
+		alarm, err := convertAlarm(child)
+		if err != nil {
+			return nil, err
+		}
+		event.AddAlarm(alarm)
+	}
+	return event, nil
+}
+
+// parsePerson 解析 ORGANIZER 和 ATTENDEE
+func parsePerson(p property) Person {
+	email := p.value
+	if len(email) >= len("mailto:") && strings.EqualFold(email[:len("mailto:")], "mailto:") {
+		email = email[len("mailto:"):]
+	}
+	return Person{Name: p.param("CN"), Email: email, Role: p.param("ROLE")}
+}
+
+func convertAlarm(component *rawComponent) (*IcsAlarm, error) {
+	alarm := &IcsAlarm{}
+	if p, found := component.get("ACTION"); found {
+		alarm.SetAction(Action(strings.ToUpper(p.value)))
+	}
+	if p, found := component.get("TRIGGER"); found {
+		trigger, err := ParseIcsDuration(p.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TRIGGER: %w", err)
+		}
+		alarm.SetTrigger(trigger)
+	}
+	if p, found := component.get("DESCRIPTION"); found {
+		alarm.SetDescription(UnescapeText(p.value))
+	}
+	return alarm, nil
+}
+
+// splitText 按照未转义的逗号拆分多值的 TEXT 属性
+func splitText(value string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// ParseRepeatRule 解析 RRULE 的值，例如 FREQ=WEEKLY;INTERVAL=2;COUNT=8，不带 Z 的 UNTIL 按 UTC 解析
+func ParseRepeatRule(value string) (*IcsRepeatRule, error) {
+	return parseRepeatRule(value, time.UTC)
+}
+
+// parseRepeatRule 解析 RRULE 的值，不带 Z 的 UNTIL 使用 DTSTART 的时区 loc
+func parseRepeatRule(value string, loc *time.Location) (*IcsRepeatRule, error) {
+	rule := &IcsRepeatRule{}
+	for _, part := range strings.Split(value, ";") {
+		key, val, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("malformed rule part %q", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.SetFrequency(strings.ToUpper(val))
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(val)
+		case "COUNT":
+			rule.count, err = strconv.Atoi(val)
+		case "UNTIL":
+			rule.until, err = ParseIcsTime(val, loc)
+		case "BYMONTH":
+			rule.byMonth, err = parseInts(val, 1, 12)
+		case "BYWEEKNO":
+			rule.byWeekNo, err = parseInts(val, 1, 53)
+		case "BYMONTHDAY":
+			rule.byMonthDay, err = parseInts(val, 1, 31)
+		case "BYSETPOS":
+			rule.bySetPos, err = parseInts(val, 1, 366)
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				var day WeekdayNum
+				if day, err = parseWeekdayNum(item); err != nil {
+					break
+				}
+				rule.byDay = append(rule.byDay, day)
+			}
+		case "WKST":
+			var day WeekdayNum
+			if day, err = parseWeekdayNum(val); err == nil {
+				rule.SetWeekStart(day.Day)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed rule part %q: %w", part, err)
+		}
+	}
+	if rule.frequency == "" {
+		return nil, fmt.Errorf("missing FREQ in %q", value)
+	}
+	return rule, nil
+}
+
+// parseInts 解析以逗号分隔的整数，绝对值需要在 [min, max] 之间，允许负数
+func parseInts(value string, min, max int) ([]int, error) {
+	var result []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			n = -n
+			if n < min || n > max {
+				return nil, fmt.Errorf("%d out of range", -n)
+			}
+			n = -n
+		} else if n < min || n > max {
+			return nil, fmt.Errorf("%d out of range", n)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// parseWeekdayNum 解析 BYDAY 中的一项，例如 MO、-1FR、+2TU
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("malformed weekday %q", value)
+	}
+	name := value[len(value)-2:]
+	for i, weekday := range weekdayNames {
+		if weekday != name {
+			continue
+		}
+		result := WeekdayNum{Day: time.Weekday(i)}
+		if prefix := value[:len(value)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return WeekdayNum{}, fmt.Errorf("malformed weekday %q", value)
+			}
+			result.N = n
+		}
+		return result, nil
+	}
+	return WeekdayNum{}, fmt.Errorf("malformed weekday %q", value)
+}
+
+// ParseIcsTime 解析 DATE-TIME 或 DATE 类型的值，以 Z 结尾的值为 UTC 时间，否则使用 loc 时区
+func ParseIcsTime(value string, loc *time.Location) (time.Time, error) {
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	if len(value) == len("20060102") {
+		return time.ParseInLocation("20060102", value, loc)
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+// ParseIcsDuration 解析 DURATION 类型的值，例如 -P1DT1H20S
+func ParseIcsDuration(value string) (time.Duration, error) {
+	s := value
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+		s = s[1:]
+	} else {
+		s = strings.TrimPrefix(s, "+")
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 2 {
+		return 0, fmt.Errorf("malformed duration %q", value)
+	}
+	s = s[1:]
+	var result time.Duration
+	inTime := false
+	number := 0
+	hasNumber := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+			hasNumber = true
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+		if !hasNumber {
+			return 0, fmt.Errorf("malformed duration %q", value)
+		}
+		unit := time.Duration(0)
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("malformed duration %q", value)
+		}
+		result += time.Duration(number) * unit
+		number, hasNumber = 0, false
+	}
+	if hasNumber {
+		return 0, fmt.Errorf("malformed duration %q", value)
+	}
+	return sign * result, nil
+}
+
+// ParseIcsOffset 解析 UTC-OFFSET 类型的值，例如 +0800、-0330 或 +053045
+func ParseIcsOffset(value string) (time.Duration, error) {
+	if value == "Z" {
+		return 0, nil
+	}
+	if len(value) != 5 && len(value) != 7 || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("malformed utc offset %q", value)
+	}
+	var parts []int
+	for i := 1; i < len(value); i += 2 {
+		n, err := strconv.Atoi(value[i : i+2])
+		if err != nil {
+			return 0, fmt.Errorf("malformed utc offset %q", value)
+		}
+		parts = append(parts, n)
+	}
+	offset := time.Duration(parts[0])*time.Hour + time.Duration(parts[1])*time.Minute
+	if len(parts) == 3 {
+		offset += time.Duration(parts[2]) * time.Second
+	}
+	if value[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// UnescapeText 是 EscapeText 的逆操作
+func UnescapeText(text string) string {
+	if !strings.Contains(text, `\`) {
+		return text
+	}
+	result := strings.Builder{}
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			result.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n', 'N':
+			result.WriteByte('\n')
+		default:
+			result.WriteByte(text[i])
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,180 @@
+package icalendar
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	bytes, err := os.ReadFile(path.Join("testdata", "test_calendar_01.ics"))
+	if err != nil {
+		t.Fatalf("failed to read test data: %v", err)
+	}
+	expected := strings.ReplaceAll(string(bytes), "\n", "\r\n")
+	calendar, err := ParseString(expected)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(calendar.GetEvents()) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(calendar.GetEvents()))
+	}
+	if got := calendar.ToIcs(nil); got != expected {
+		t.Errorf("round trip mismatch:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestParse_FoldedAndEscaped(t *testing.T) {
+	event := &IcsEvent{}
+	event.SetSummary("【课程】高等数学, 第一讲; 习题")
+	event.SetDescription("授课教师：张三\n" + strings.Repeat("数据来自【拱拱】", 10))
+	event.SetStart(time.Date(2025, 2, 17, 8, 0, 0, 0, time.UTC))
+	event.dtStamp = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	event.SetUID("course-1@gonggong")
+	event.AddAlarm(NewIcsAlarm(DISPLAY, -28*time.Minute, "距离上课仅剩28分钟"))
+	calendar := &IcsCalendar{}
+	calendar.SetProductID("productID")
+	calendar.AddEvent(event)
+
+	parsed, err := ParseString(calendar.ToIcs(nil))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	got := parsed.GetEvents()[0].(*IcsEvent)
+	if got.summary != event.summary || got.description != event.description || got.GetUID() != event.GetUID() {
+		t.Errorf("Parse() = %+v, want %+v", got, event)
+	}
+	if !got.start.Equal(event.start) {
+		t.Errorf("start = %v, want %v", got.start, event.start)
+	}
+	if alarm := got.alarms[0].(*IcsAlarm); alarm.trigger != -28*time.Minute || alarm.description != "距离上课仅剩28分钟" {
+		t.Errorf("alarm = %+v", alarm)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		ics  string
+	}{
+		{name: "Empty", ics: ""},
+		{name: "Not a calendar", ics: "BEGIN:VEVENT\r\nEND:VEVENT\r\n"},
+		{name: "Unclosed", ics: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VEVENT\r\n"},
+		{name: "Mismatched", ics: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n"},
+		{name: "Malformed line", ics: "BEGIN:VCALENDAR\r\nnonsense\r\nEND:VCALENDAR\r\n"},
+		{name: "Bad rule", ics: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nRRULE:COUNT=2\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseString(tt.ics); err == nil {
+				t.Errorf("Parse() should fail for %q", tt.ics)
+			}
+		})
+	}
+}
+
+func TestParseProperty(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		params  map[string][]string
+		value   string
+		wantErr bool
+	}{
+		{
+			name:   "Quoted",
+			line:   `ATTENDEE;CN="Zhang, San";ROLE=REQ-PARTICIPANT:mailto:zs@example.com`,
+			params: map[string][]string{"CN": {"Zhang, San"}, "ROLE": {"REQ-PARTICIPANT"}},
+			value:  "mailto:zs@example.com",
+		},
+		{
+			name:   "Quoted list",
+			line:   `ATTENDEE;MEMBER="mailto:a@x","mailto:b@x";CN=Bob:mailto:c@x`,
+			params: map[string][]string{"MEMBER": {"mailto:a@x", "mailto:b@x"}, "CN": {"Bob"}},
+			value:  "mailto:c@x",
+		},
+		{
+			name:   "Unquoted list",
+			line:   `ATTENDEE;DELEGATED-TO=a,"mailto:b@x",c:mailto:d@x`,
+			params: map[string][]string{"DELEGATED-TO": {"a", "mailto:b@x", "c"}},
+			value:  "mailto:d@x",
+		},
+		{name: "No parameters", line: "SUMMARY:a;b:c", params: map[string][]string{}, value: "a;b:c"},
+		{name: "Stray after quote", line: `ATTENDEE;CN="Bob"x:mailto:c@x`, wantErr: true},
+		{name: "Unterminated quote", line: `ATTENDEE;CN="Bob:mailto:c@x`, wantErr: true},
+		{name: "Missing value", line: `ATTENDEE;CN="Bob"`, wantErr: true},
+		{name: "Trailing comma", line: `ATTENDEE;MEMBER="a",`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseProperty(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseProperty() should fail, got %+v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProperty() error = %v", err)
+			}
+			if !reflect.DeepEqual(p.params, tt.params) || p.value != tt.value {
+				t.Errorf("parseProperty() = %+v, want params %v value %q", p, tt.params, tt.value)
+			}
+		})
+	}
+}
+
+func TestParseIcsDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "PT0S", want: 0},
+		{value: "-PT30M", want: -30 * time.Minute},
+		{value: "P1DT1H20S", want: 25*time.Hour + 20*time.Second},
+		{value: "P1W", want: 7 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := ParseIcsDuration(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseIcsDuration(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+		if tt.want != 0 && DurationToIcs(got) != strings.Replace(tt.value, "P1W", "P7D", 1) {
+			t.Errorf("DurationToIcs(%v) = %v", got, DurationToIcs(got))
+		}
+	}
+	for _, value := range []string{"", "P", "1D", "PT1D", "P1H"} {
+		if _, err := ParseIcsDuration(value); err == nil {
+			t.Errorf("ParseIcsDuration(%q) should fail", value)
+		}
+	}
+}
+
+func TestParseIcsOffset(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "+0800", want: 8 * time.Hour},
+		{value: "-0330", want: -(3*time.Hour + 30*time.Minute)},
+		{value: "+053045", want: 5*time.Hour + 30*time.Minute + 45*time.Second},
+	}
+	for _, tt := range tests {
+		if got, err := ParseIcsOffset(tt.value); err != nil || got != tt.want {
+			t.Errorf("ParseIcsOffset(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseRepeatRule(t *testing.T) {
+	rule, err := ParseRepeatRule("FREQ=WEEKLY;INTERVAL=2;UNTIL=20250601T000000Z")
+	if err != nil {
+		t.Fatalf("ParseRepeatRule() error = %v", err)
+	}
+	if rule.frequency != "WEEKLY" || rule.interval != 2 || !rule.until.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseRepeatRule() = %+v", rule)
+	}
+}
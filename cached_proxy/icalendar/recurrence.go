@@ -0,0 +1,317 @@
+package icalendar
+
+import (
+	"sort"
+	"time"
+)
+
+// maxPeriods 限制展开的周期数，避免规则始终没有匹配的日期时无限循环
+const maxPeriods = 100000
+
+// Occurrences 按照 RFC 5545 3.3.10 展开重复规则，DTSTART 总是第一次重复。
+// 支持 DAILY、WEEKLY、MONTHLY 和 YEARLY，以及 BYMONTH、BYWEEKNO、BYMONTHDAY、BYDAY、BYSETPOS 和 WKST，
+// 每次重复的时刻与 start 相同，并在 start 所在的时区中计算。
+func (r *IcsRepeatRule) Occurrences(start, from, to time.Time) []time.Time {
+	var result []time.Time
+	r.expand(start, to, func(t time.Time) {
+		if !t.Before(from) {
+			result = append(result, t)
+		}
+	})
+	return result
+}
+
+// Overlaps 按照 RFC 4791 9.9 判断事件是否有某次重复与 [from, to) 相交，持续时间为 0 的事件在开始时间落在范围内时相交
+func (e *IcsEvent) Overlaps(from, to time.Time) bool {
+	length := e.length()
+	for _, t := range e.Occurrences(from.Add(-length), to) {
+		if (length == 0 && !t.Before(from)) || t.Add(length).After(from) {
+			return true
+		}
+	}
+	return false
+}
+
+// length 返回事件每次重复的持续时间，没有结束时间的全天事件持续一天
+func (e *IcsEvent) length() time.Duration {
+	switch {
+	case e.duration > 0:
+		return e.duration
+	case e.end.After(e.start):
+		return e.end.Sub(e.start)
+	case e.allDay:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// expand 按时间顺序依次生成 to 之前的重复时间
+func (r *IcsRepeatRule) expand(start, to time.Time, yield func(time.Time)) {
+	interval := r.interval
+	if interval < 1 {
+		interval = 1
+	}
+	count := 0
+	// emit 返回 false 表示已经达到 COUNT、UNTIL 或 to
+	emit := func(t time.Time) bool {
+		if !t.Before(to) || (!r.until.IsZero() && t.After(r.until)) || (r.count > 0 && count >= r.count) {
+			return false
+		}
+		count++
+		yield(t)
+		return true
+	}
+	if !emit(start) {
+		return
+	}
+	for n := 0; n < maxPeriods; n++ {
+		periodStart, candidates := r.period(start, n*interval)
+		if periodStart.IsZero() || !periodStart.Before(to) {
+			return
+		}
+		for _, t := range r.setPos(candidates) {
+			if !t.After(start) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// period 返回第 n 个周期的开始时间和周期内所有满足规则的候选时间，候选时间已经排序
+func (r *IcsRepeatRule) period(start time.Time, n int) (time.Time, []time.Time) {
+	y, m, d := start.Date()
+	loc := start.Location()
+	at := func(date time.Time) time.Time {
+		return time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), start.Second(), 0, loc)
+	}
+	var days []time.Time
+	var periodStart time.Time
+	switch r.frequency {
+	case "DAILY":
+		day := time.Date(y, m, d+n, 0, 0, 0, 0, time.UTC)
+		periodStart = day
+		if r.matchMonth(day) && r.matchMonthDay(day) && r.matchWeekday(day) {
+			days = append(days, day)
+		}
+	case "WEEKLY":
+		offset := (int(start.Weekday()) - int(r.wkst()) + 7) % 7
+		periodStart = time.Date(y, m, d-offset+7*n, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 7; i++ {
+			day := periodStart.AddDate(0, 0, i)
+			if len(r.byDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchMonth(day) && r.matchWeekday(day) {
+				days = append(days, day)
+			}
+		}
+	case "MONTHLY":
+		periodStart = time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchMonth(periodStart) {
+			days = r.monthDays(periodStart.Year(), periodStart.Month(), d)
+		}
+	case "YEARLY":
+		periodStart = time.Date(y+n, 1, 1, 0, 0, 0, 0, time.UTC)
+		days = r.yearDays(y+n, m, d, start.Weekday())
+	default:
+		return time.Time{}, nil
+	}
+	result := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		result = append(result, at(day))
+	}
+	return time.Date(periodStart.Year(), periodStart.Month(), periodStart.Day(), 0, 0, 0, 0, loc), result
+}
+
+// monthDays 返回某个月中满足 BYMONTHDAY 和 BYDAY 的日期，两者都未设置时返回与 DTSTART 同一天的日期
+func (r *IcsRepeatRule) monthDays(year int, month time.Month, startDay int) []time.Time {
+	total := daysIn(year, month)
+	var days []time.Time
+	if len(r.byMonthDay) == 0 && len(r.byDay) == 0 {
+		if startDay <= total {
+			days = append(days, time.Date(year, month, startDay, 0, 0, 0, 0, time.UTC))
+		}
+		return days
+	}
+	for i := 1; i <= total; i++ {
+		day := time.Date(year, month, i, 0, 0, 0, 0, time.UTC)
+		if r.matchMonthDay(day) && matchByDay(r.byDay, day.Weekday(), i, total) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// yearDays 返回某一年中满足规则的日期
+func (r *IcsRepeatRule) yearDays(year int, startMonth time.Month, startDay int, startWeekday time.Weekday) []time.Time {
+	var days []time.Time
+	switch {
+	case len(r.byWeekNo) > 0:
+		for _, day := range r.weekNoDays(year) {
+			if !r.matchMonth(day) || !r.matchMonthDay(day) {
+				continue
+			}
+			if len(r.byDay) == 0 && len(r.byMonthDay) == 0 && day.Weekday() != startWeekday {
+				continue
+			}
+			if r.matchWeekday(day) {
+				days = append(days, day)
+			}
+		}
+	case len(r.byMonth) > 0 || len(r.byMonthDay) > 0:
+		months := r.byMonth
+		if len(months) == 0 {
+			if len(r.byMonthDay) > 0 {
+				months = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+			} else {
+				months = []int{int(startMonth)}
+			}
+		}
+		for _, month := range sortedInts(months) {
+			days = append(days, r.monthDays(year, time.Month(month), startDay)...)
+		}
+	case len(r.byDay) > 0:
+		total := daysInYear(year)
+		for i := 1; i <= total; i++ {
+			day := time.Date(year, 1, i, 0, 0, 0, 0, time.UTC)
+			if matchByDay(r.byDay, day.Weekday(), i, total) {
+				days = append(days, day)
+			}
+		}
+	default:
+		if startDay <= daysIn(year, startMonth) {
+			days = append(days, time.Date(year, startMonth, startDay, 0, 0, 0, 0, time.UTC))
+		}
+	}
+	return days
+}
+
+// weekNoDays 返回 BYWEEKNO 指定的周中的所有日期，第 1 周是一年中第一个至少包含 4 天的周
+func (r *IcsRepeatRule) weekNoDays(year int) []time.Time {
+	first := firstWeekStart(year, r.wkst())
+	weeks := int(firstWeekStart(year+1, r.wkst()).Sub(first).Hours() / 24 / 7)
+	var days []time.Time
+	for _, week := range r.byWeekNo {
+		if week < 0 {
+			week = weeks + week + 1
+		}
+		if week < 1 || week > weeks {
+			continue
+		}
+		for i := 0; i < 7; i++ {
+			days = append(days, first.AddDate(0, 0, (week-1)*7+i))
+		}
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+	return days
+}
+
+// setPos 按照 BYSETPOS 从周期内的候选中选取
+func (r *IcsRepeatRule) setPos(candidates []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 {
+		return candidates
+	}
+	var result []time.Time
+	for _, pos := range r.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(candidates) + pos
+		}
+		if i >= 0 && i < len(candidates) {
+			result = append(result, candidates[i])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Before(result[j])
+	})
+	return result
+}
+
+func (r *IcsRepeatRule) wkst() time.Weekday {
+	if r.weekStart == nil {
+		return time.Monday
+	}
+	return *r.weekStart
+}
+
+func (r *IcsRepeatRule) matchMonth(day time.Time) bool {
+	if len(r.byMonth) == 0 {
+		return true
+	}
+	for _, month := range r.byMonth {
+		if time.Month(month) == day.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *IcsRepeatRule) matchMonthDay(day time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+	total := daysIn(day.Year(), day.Month())
+	for _, monthDay := range r.byMonthDay {
+		if monthDay == day.Day() || (monthDay < 0 && total+monthDay+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday 只比较星期，用于 DAILY、WEEKLY 和 BYWEEKNO 中的 BYDAY
+func (r *IcsRepeatRule) matchWeekday(day time.Time) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+	for _, byDay := range r.byDay {
+		if byDay.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchByDay 判断范围中第 pos 天（共 total 天）是否满足 BYDAY，带序号的 BYDAY 表示范围内的第几个该星期
+func matchByDay(byDay []WeekdayNum, weekday time.Weekday, pos, total int) bool {
+	if len(byDay) == 0 {
+		return true
+	}
+	nth, last := (pos-1)/7+1, -((total-pos)/7 + 1)
+	for _, d := range byDay {
+		if d.Day == weekday && (d.N == 0 || d.N == nth || d.N == last) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstWeekStart 返回一年中第 1 周的第一天
+func firstWeekStart(year int, weekStart time.Weekday) time.Time {
+	jan1 := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan1.Weekday()) - int(weekStart) + 7) % 7
+	start := jan1.AddDate(0, 0, -offset)
+	if 7-offset < 4 {
+		start = start.AddDate(0, 0, 7)
+	}
+	return start
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysInYear(year int) int {
+	return time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+func sortedInts(values []int) []int {
+	result := append([]int(nil), values...)
+	sort.Ints(result)
+	return result
+}
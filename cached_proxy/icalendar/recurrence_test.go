@@ -0,0 +1,180 @@
+package icalendar
+
+import (
+	"testing"
+	"time"
+)
+
+// 测试用例来自 RFC 5545 3.8.5.3 中的示例
+func TestIcsRepeatRule_Occurrences(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 9, 0, 0, 0, newYork)
+	}
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		to    time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "daily for 3 occurrences",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: date(1997, 9, 2),
+			want:  []time.Time{date(1997, 9, 2), date(1997, 9, 3), date(1997, 9, 4)},
+		},
+		{
+			name:  "weekly on Tuesday and Thursday until a local UNTIL",
+			rule:  "FREQ=WEEKLY;UNTIL=19970918T000000;WKST=SU;BYDAY=TU,TH",
+			start: date(1997, 9, 2),
+			want:  []time.Time{date(1997, 9, 2), date(1997, 9, 4), date(1997, 9, 9), date(1997, 9, 11), date(1997, 9, 16)},
+		},
+		{
+			name:  "every other week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;COUNT=3;WKST=SU",
+			start: date(1997, 9, 2),
+			want:  []time.Time{date(1997, 9, 2), date(1997, 9, 16), date(1997, 9, 30)},
+		},
+		{
+			name:  "monthly on the first Friday",
+			rule:  "FREQ=MONTHLY;COUNT=4;BYDAY=1FR",
+			start: date(1997, 9, 5),
+			want:  []time.Time{date(1997, 9, 5), date(1997, 10, 3), date(1997, 11, 7), date(1997, 12, 5)},
+		},
+		{
+			name:  "monthly on the second-to-last Monday",
+			rule:  "FREQ=MONTHLY;COUNT=3;BYDAY=-2MO",
+			start: date(1997, 9, 22),
+			want:  []time.Time{date(1997, 9, 22), date(1997, 10, 20), date(1997, 11, 17)},
+		},
+		{
+			name:  "monthly on the third-to-last day",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-3;COUNT=4",
+			start: date(1997, 9, 28),
+			want:  []time.Time{date(1997, 9, 28), date(1997, 10, 29), date(1997, 11, 28), date(1997, 12, 29)},
+		},
+		{
+			name:  "last work day of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3",
+			start: date(1997, 9, 30),
+			want:  []time.Time{date(1997, 9, 30), date(1997, 10, 31), date(1997, 11, 28)},
+		},
+		{
+			name:  "every Friday the 13th",
+			rule:  "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=4",
+			start: date(1997, 9, 2),
+			want:  []time.Time{date(1997, 9, 2), date(1998, 2, 13), date(1998, 3, 13), date(1998, 11, 13)},
+		},
+		{
+			name:  "yearly in June and July",
+			rule:  "FREQ=YEARLY;COUNT=4;BYMONTH=6,7",
+			start: date(1997, 6, 10),
+			want:  []time.Time{date(1997, 6, 10), date(1997, 7, 10), date(1998, 6, 10), date(1998, 7, 10)},
+		},
+		{
+			name:  "Monday of week number 20",
+			rule:  "FREQ=YEARLY;BYWEEKNO=20;BYDAY=MO;COUNT=3",
+			start: date(1997, 5, 12),
+			want:  []time.Time{date(1997, 5, 12), date(1998, 5, 11), date(1999, 5, 17)},
+		},
+		{
+			name:  "unbounded rule stops at the end of the range",
+			rule:  "FREQ=WEEKLY;BYDAY=MO",
+			start: date(2025, 3, 3),
+			to:    date(2025, 3, 24),
+			want:  []time.Time{date(2025, 3, 3), date(2025, 3, 10), date(2025, 3, 17)},
+		},
+		{
+			name:  "daylight saving time keeps the local time",
+			rule:  "FREQ=DAILY;COUNT=2",
+			start: date(2025, 3, 8),
+			want:  []time.Time{date(2025, 3, 8), date(2025, 3, 9)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRepeatRule(tt.rule, newYork)
+			if err != nil {
+				t.Fatalf("parseRepeatRule() error = %v", err)
+			}
+			to := tt.to
+			if to.IsZero() {
+				to = tt.start.AddDate(10, 0, 0)
+			}
+			got := rule.Occurrences(tt.start, tt.start, to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Occurrences() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("Occurrences()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIcsEvent_Occurrences(t *testing.T) {
+	shanghai := GetDefaultTimezone().Location()
+	date := func(m time.Month, d int) time.Time {
+		return time.Date(2025, m, d, 8, 0, 0, 0, shanghai)
+	}
+	rule := &IcsRepeatRule{}
+	rule.SetFrequency("WEEKLY")
+	rule.SetCount(4)
+	event := &IcsEvent{}
+	event.SetStart(date(3, 3))
+	event.SetRepeatRule(rule)
+	event.AddExceptionDate(date(3, 10))
+	event.AddRecurrenceDate(date(3, 12))
+	got := event.Occurrences(date(3, 5), date(4, 1))
+	want := []time.Time{date(3, 12), date(3, 17), date(3, 24)}
+	if len(got) != len(want) {
+		t.Fatalf("Occurrences() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("Occurrences()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestIcsEvent_Overlaps(t *testing.T) {
+	shanghai := GetDefaultTimezone().Location()
+	at := func(d, h, m int) time.Time {
+		return time.Date(2025, 3, d, h, m, 0, 0, shanghai)
+	}
+	rule := &IcsRepeatRule{}
+	rule.SetFrequency("WEEKLY")
+	rule.SetCount(2)
+	event := &IcsEvent{}
+	event.SetStart(at(3, 8, 0))
+	event.SetEnd(at(3, 9, 40))
+	event.SetRepeatRule(rule)
+	allDay := &IcsEvent{}
+	allDay.SetAllDay(true)
+	allDay.SetStart(at(5, 0, 0))
+	tests := []struct {
+		name     string
+		event    *IcsEvent
+		from, to time.Time
+		want     bool
+	}{
+		{"during the first occurrence", event, at(3, 9, 0), at(3, 10, 0), true},
+		{"ends when the range starts", event, at(3, 9, 40), at(3, 12, 0), false},
+		{"starts when the range ends", event, at(3, 7, 0), at(3, 8, 0), false},
+		{"second occurrence", event, at(10, 0, 0), at(11, 0, 0), true},
+		{"between occurrences", event, at(4, 0, 0), at(10, 0, 0), false},
+		{"after the last occurrence", event, at(11, 0, 0), at(31, 0, 0), false},
+		{"all-day event lasts one day", allDay, at(5, 23, 0), at(6, 1, 0), true},
+		{"after the all-day event", allDay, at(6, 0, 0), at(7, 0, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.Overlaps(tt.from, tt.to); got != tt.want {
+				t.Errorf("Overlaps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,30 +2,90 @@ package icalendar
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// WeekdayNum 是 BYDAY 中的一项，N 为 0 表示每一个，正数表示第 N 个，负数表示倒数第 N 个，例如 -1FR
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// weekdayNames 是 RFC 5545 中星期的缩写
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func (d WeekdayNum) String() string {
+	if d.N == 0 {
+		return weekdayNames[d.Day]
+	}
+	return strconv.Itoa(d.N) + weekdayNames[d.Day]
+}
+
 type IcsRepeatRule struct {
-	frequency string
-	interval  int
-	count     int
-	until     time.Time
+	frequency  string
+	interval   int
+	count      int
+	until      time.Time
+	byMonth    []int
+	byWeekNo   []int
+	byMonthDay []int
+	byDay      []WeekdayNum
+	bySetPos   []int
+	weekStart  *time.Weekday // 为 nil 时使用默认的周一
 }
 
 func (r *IcsRepeatRule) ToIcs(_ *Timezone) string {
-	result := "RRULE:FREQ=" + r.frequency
+	w := ContentWriter{}
+	// UNTIL 必须使用 UTC 时间
+	w.WriteLine("RRULE", r.value(func(until time.Time) string {
+		return TimeToIcs(until, nil, "")
+	}))
+	return w.String()
+}
+
+// value 生成 RRULE 的值，until 用于格式化 UNTIL，全天事件和浮动时间的事件需要使用与 DTSTART 相同的类型
+func (r *IcsRepeatRule) value(until func(time.Time) string) string {
+	result := "FREQ=" + r.frequency
 	if r.interval > 1 {
-		result += ";INTERVAL=" + string(rune(r.interval))
+		result += ";INTERVAL=" + strconv.Itoa(r.interval)
 	}
 	if r.count > 0 {
 		result += fmt.Sprintf(";COUNT=%d", r.count)
 	}
 	if !r.until.IsZero() {
-		result += ";UNTIL" + TimeToIcs(r.until, nil, "=")
+		result += ";UNTIL=" + until(r.until)
+	}
+	result += joinInts(";BYMONTH=", r.byMonth)
+	result += joinInts(";BYWEEKNO=", r.byWeekNo)
+	result += joinInts(";BYMONTHDAY=", r.byMonthDay)
+	if len(r.byDay) > 0 {
+		days := make([]string, len(r.byDay))
+		for i, day := range r.byDay {
+			days[i] = day.String()
+		}
+		result += ";BYDAY=" + strings.Join(days, ",")
+	}
+	result += joinInts(";BYSETPOS=", r.bySetPos)
+	if r.weekStart != nil {
+		result += ";WKST=" + weekdayNames[*r.weekStart]
 	}
 	return result
 }
 
+// joinInts 以逗号连接整数并加上前缀，列表为空时返回空字符串
+func joinInts(prefix string, values []int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return prefix + strings.Join(parts, ",")
+}
+
 func (r *IcsRepeatRule) SetFrequency(frequency string) {
 	r.frequency = frequency
 }
@@ -41,3 +101,27 @@ func (r *IcsRepeatRule) SetCount(count int) {
 func (r *IcsRepeatRule) SetUntil(until time.Time) {
 	r.until = until
 }
+
+func (r *IcsRepeatRule) SetByMonth(months ...int) {
+	r.byMonth = months
+}
+
+func (r *IcsRepeatRule) SetByWeekNo(weeks ...int) {
+	r.byWeekNo = weeks
+}
+
+func (r *IcsRepeatRule) SetByMonthDay(days ...int) {
+	r.byMonthDay = days
+}
+
+func (r *IcsRepeatRule) SetByDay(days ...WeekdayNum) {
+	r.byDay = days
+}
+
+func (r *IcsRepeatRule) SetBySetPos(positions ...int) {
+	r.bySetPos = positions
+}
+
+func (r *IcsRepeatRule) SetWeekStart(day time.Weekday) {
+	r.weekStart = &day
+}
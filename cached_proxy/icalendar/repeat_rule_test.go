@@ -1,6 +1,7 @@
 package icalendar
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -32,7 +33,7 @@ func TestIcsRepeatRule_ToIcs(t *testing.T) {
 			args: args{
 				timezone: nil,
 			},
-			want: "RRULE:FREQ=DAILY;UNTIL=20210101T000000Z",
+			want: "RRULE:FREQ=DAILY;UNTIL=20210101T000000Z\r\n",
 		},
 	}
 	for _, tt := range tests {
@@ -49,3 +50,35 @@ func TestIcsRepeatRule_ToIcs(t *testing.T) {
 		})
 	}
 }
+
+func TestIcsRepeatRule_ByRules(t *testing.T) {
+	rule := &IcsRepeatRule{}
+	rule.SetFrequency("MONTHLY")
+	rule.SetUntil(time.Date(2025, 6, 30, 16, 0, 0, 0, time.UTC))
+	rule.SetByMonth(3, 4, 5)
+	rule.SetByMonthDay(-1)
+	rule.SetByDay(WeekdayNum{Day: time.Monday}, WeekdayNum{N: -1, Day: time.Friday})
+	rule.SetBySetPos(1, -1)
+	rule.SetWeekStart(time.Sunday)
+	value := "FREQ=MONTHLY;UNTIL=20250630T160000Z;BYMONTH=3,4,5;BYMONTHDAY=-1;BYDAY=MO,-1FR;BYSETPOS=1,-1;WKST=SU"
+	want := FoldLine("RRULE:" + value)
+	if got := rule.ToIcs(nil); got != want {
+		t.Errorf("ToIcs() = %v, want %v", got, want)
+	}
+	parsed, err := ParseRepeatRule(value)
+	if err != nil {
+		t.Fatalf("ParseRepeatRule() error = %v", err)
+	}
+	if got := parsed.ToIcs(nil); got != want {
+		t.Errorf("ParseRepeatRule() round trip = %v, want %v", got, want)
+	}
+
+	// 全天事件的 UNTIL 使用 DATE 类型
+	event := &IcsEvent{}
+	event.SetAllDay(true)
+	event.SetStart(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC))
+	event.SetRepeatRule(rule)
+	if got := event.ToIcs(nil); !strings.Contains(got, "RRULE:FREQ=MONTHLY;UNTIL=20250630;") {
+		t.Errorf("ToIcs() = %v, want a DATE UNTIL", got)
+	}
+}
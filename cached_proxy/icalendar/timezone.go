@@ -1,44 +1,197 @@
 package icalendar
 
 import (
-	"strings"
 	"time"
+	// 内置 IANA 时区数据库，避免运行环境中缺少 zoneinfo 时无法生成 VTIMEZONE
+	_ "time/tzdata"
 )
 
+// DefaultTimezoneID 是默认时区的 ID
+const DefaultTimezoneID = "Asia/Shanghai"
+
+// IcsTimezone 是 ICS 文件中的时区。
+// 通过 NewTimezone 或 LoadTimezone 创建的时区会根据 Go 的时区数据库生成 STANDARD 和 DAYLIGHT 规则，
+// 否则使用固定的偏移。
 type IcsTimezone struct {
 	id         string
+	location   *time.Location // 时区数据库中的时区，为 nil 时使用下面的固定偏移
 	offsetFrom time.Duration
 	offsetTo   time.Duration
 	start      time.Time
 	name       string
+	rangeStart time.Time // 需要覆盖的时间范围
+	rangeEnd   time.Time
 }
 
-var (
-	defaultTimezone = IcsTimezone{
-		id:         "Asia/Shanghai",
-		offsetFrom: 8,
-		offsetTo:   8,
-		start:      time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
-		name:       "Asia/Shanghai",
-	}
+// observance 是 VTIMEZONE 中的一条 STANDARD 或 DAYLIGHT 规则
+type observance struct {
+	daylight   bool
+	start      time.Time // 转换发生时转换前的本地时间
+	offsetFrom time.Duration
+	offsetTo   time.Duration
+	name       string
+	rule       *IcsRepeatRule // 每年重复的转换，为 nil 时只在 start 发生一次
+}
+
+const (
+	// explicitYears 是逐个写出时区转换的年数，范围更长时最后一年的转换改为每年重复的规则
+	explicitYears = 2
+	// verifyYears 是确认转换每年按同一规则发生时检查的年数
+	verifyYears = 4
 )
 
+// NewTimezone 根据 Go 的时区创建时区
+func NewTimezone(location *time.Location) *IcsTimezone {
+	return &IcsTimezone{
+		id:       location.String(),
+		location: location,
+		name:     location.String(),
+	}
+}
+
+// LoadTimezone 根据 IANA 时区名称创建时区，例如 Asia/Shanghai
+func LoadTimezone(id string) (*IcsTimezone, error) {
+	location, err := time.LoadLocation(id)
+	if err != nil {
+		return nil, err
+	}
+	return NewTimezone(location), nil
+}
+
+// GetDefaultTimezone 返回默认时区 Asia/Shanghai，每次调用都返回新的实例
 func GetDefaultTimezone() *IcsTimezone {
-	return &defaultTimezone
+	if tz, err := LoadTimezone(DefaultTimezoneID); err == nil {
+		return tz
+	}
+	return &IcsTimezone{
+		id:         DefaultTimezoneID,
+		offsetFrom: 8 * time.Hour,
+		offsetTo:   8 * time.Hour,
+		start:      time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		name:       "CST",
+	}
 }
 
 func (tz *IcsTimezone) ToIcs(_ *Timezone) string {
-	result := strings.Builder{}
-	result.WriteString("BEGIN:VTIMEZONE\n")
-	result.WriteString("TZID:" + tz.id + "\n")
-	result.WriteString("BEGIN:STANDARD\n")
-	result.WriteString("DTSTART" + TimeToIcs(tz.start, nil, ":") + "\n")
-	result.WriteString("TZOFFSETFROM:" + OffsetToIcs(tz.offsetFrom) + "\n")
-	result.WriteString("TZOFFSETTO:" + OffsetToIcs(tz.offsetTo) + "\n")
-	result.WriteString("TZNAME:" + tz.name + "\n")
-	result.WriteString("END:STANDARD\n")
-	result.WriteString("END:VTIMEZONE\n")
-	return result.String()
+	w := ContentWriter{}
+	w.Begin("VTIMEZONE")
+	w.WriteLine("TZID", tz.id)
+	for _, o := range tz.observances() {
+		component := "STANDARD"
+		if o.daylight {
+			component = "DAYLIGHT"
+		}
+		w.Begin(component)
+		// DTSTART 是转换前的本地时间，不能带 Z 后缀
+		w.WriteLine("DTSTART", o.start.Format("20060102T150405"))
+		w.WriteLine("TZOFFSETFROM", OffsetToIcs(o.offsetFrom))
+		w.WriteLine("TZOFFSETTO", OffsetToIcs(o.offsetTo))
+		if o.name != "" {
+			w.WriteText("TZNAME", o.name)
+		}
+		if o.rule != nil {
+			w.WriteLine("RRULE", o.rule.value(nil))
+		}
+		w.End(component)
+	}
+	w.End("VTIMEZONE")
+	return w.String()
+}
+
+// observances 计算覆盖时间范围所需的规则。
+// 第一条规则描述范围开始前一年时的偏移，之后每次时区转换对应一条规则。
+// 范围超过 explicitYears 年时只逐个写出前 explicitYears 年的转换，最后一年中每年按同一规则发生的转换改为 RRULE，
+// 避免重复到 9999 年的事件生成上万条规则。
+func (tz *IcsTimezone) observances() []observance {
+	if tz.location == nil {
+		return []observance{{
+			start:      tz.start,
+			offsetFrom: tz.offsetFrom,
+			offsetTo:   tz.offsetTo,
+			name:       tz.name,
+		}}
+	}
+	start, end := tz.rangeStart, tz.rangeEnd
+	if start.IsZero() || end.IsZero() {
+		now := time.Now()
+		start, end = now, now.AddDate(1, 0, 0)
+	}
+	recurring := end.After(start.AddDate(explicitYears, 0, 0))
+	if recurring {
+		end = start.AddDate(explicitYears, 0, 0)
+	}
+	// 从范围开始前一年查找，保证范围开始时生效的规则也包含在内
+	t := start.AddDate(-1, 0, 0).Unix()
+	name, offset := time.Unix(t, 0).In(tz.location).Zone()
+	result := []observance{{
+		daylight:   time.Unix(t, 0).In(tz.location).IsDST(),
+		start:      time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		offsetFrom: time.Duration(offset) * time.Second,
+		offsetTo:   time.Duration(offset) * time.Second,
+		name:       name,
+	}}
+	const day = 24 * 60 * 60
+	for ; t < end.Unix(); t += day {
+		nextName, nextOffset := time.Unix(t+day, 0).In(tz.location).Zone()
+		if nextName == name && nextOffset == offset {
+			continue
+		}
+		// 二分查找转换发生的时刻，转换总是发生在整秒
+		lo, hi := t, t+day
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if n, o := time.Unix(mid, 0).In(tz.location).Zone(); n == name && o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transition := time.Unix(hi, 0).In(tz.location)
+		nextName, nextOffset = transition.Zone()
+		result = append(result, observance{
+			daylight:   transition.IsDST(),
+			start:      time.Unix(hi, 0).In(time.FixedZone("", offset)),
+			offsetFrom: time.Duration(offset) * time.Second,
+			offsetTo:   time.Duration(nextOffset) * time.Second,
+			name:       nextName,
+		})
+		name, offset = nextName, nextOffset
+	}
+	if recurring {
+		for i := range result[1:] {
+			if o := &result[1+i]; o.start.After(end.AddDate(-1, 0, 0)) {
+				o.rule = tz.yearlyRule(o)
+			}
+		}
+	}
+	return result
+}
+
+// yearlyRule 返回与转换每年在同一个月的第几个星期几发生的规则，之后几年不满足时返回 nil
+func (tz *IcsTimezone) yearlyRule(o *observance) *IcsRepeatRule {
+	day := o.start.Day()
+	n := (day-1)/7 + 1
+	if day+7 > time.Date(o.start.Year(), o.start.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		n = -1
+	}
+	rule := &IcsRepeatRule{
+		frequency: "YEARLY",
+		byMonth:   []int{int(o.start.Month())},
+		byDay:     []WeekdayNum{{N: n, Day: o.start.Weekday()}},
+	}
+	from := time.Date(o.start.Year()+1, 1, 1, 0, 0, 0, 0, o.start.Location())
+	occurrences := rule.Occurrences(o.start, from, from.AddDate(verifyYears, 0, 0))
+	if len(occurrences) != verifyYears {
+		return nil
+	}
+	for _, t := range occurrences {
+		_, before := t.Add(-time.Second).In(tz.location).Zone()
+		afterName, after := t.In(tz.location).Zone()
+		if time.Duration(before)*time.Second != o.offsetFrom || time.Duration(after)*time.Second != o.offsetTo || afterName != o.name {
+			return nil
+		}
+	}
+	return rule
 }
 
 func (tz *IcsTimezone) SetID(id string) {
@@ -61,6 +214,18 @@ func (tz *IcsTimezone) SetStart(start time.Time) {
 	tz.start = start
 }
 
+func (tz *IcsTimezone) SetRange(start, end time.Time) {
+	tz.rangeStart = start
+	tz.rangeEnd = end
+}
+
 func (tz *IcsTimezone) GetID() string {
 	return tz.id
 }
+
+func (tz *IcsTimezone) Location() *time.Location {
+	if tz.location != nil {
+		return tz.location
+	}
+	return time.FixedZone(tz.name, int(tz.offsetTo.Seconds()))
+}
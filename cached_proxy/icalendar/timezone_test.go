@@ -1,6 +1,7 @@
 package icalendar
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -34,7 +35,7 @@ func TestIcsTimezone_ToIcs(t *testing.T) {
 			args: args{
 				timezone: nil,
 			},
-			want: "BEGIN:VTIMEZONE\nTZID:id\nBEGIN:STANDARD\nDTSTART:20210101T000000Z\nTZOFFSETFROM:+0100\nTZOFFSETTO:+0100\nTZNAME:name\nEND:STANDARD\nEND:VTIMEZONE\n",
+			want: "BEGIN:VTIMEZONE\r\nTZID:id\r\nBEGIN:STANDARD\r\nDTSTART:20210101T000000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0100\r\nTZNAME:name\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n",
 		},
 	}
 	for _, tt := range tests {
@@ -52,3 +53,145 @@ func TestIcsTimezone_ToIcs(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadTimezone_ToIcs(t *testing.T) {
+	tests := []struct {
+		id    string
+		start time.Time
+		end   time.Time
+		want  []string
+	}{
+		{
+			id:    "Asia/Shanghai",
+			start: time.Date(2025, 2, 17, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"BEGIN:STANDARD", "DTSTART:19700101T000000", "TZOFFSETFROM:+0800", "TZOFFSETTO:+0800", "TZNAME:CST", "END:STANDARD",
+			},
+		},
+		{
+			id:    "America/New_York",
+			start: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				// 范围开始前一年的规则
+				"BEGIN:DAYLIGHT", "DTSTART:19700101T000000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0400", "TZNAME:EDT", "END:DAYLIGHT",
+				"BEGIN:STANDARD", "DTSTART:20241103T020000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0500", "TZNAME:EST", "END:STANDARD",
+				"BEGIN:DAYLIGHT", "DTSTART:20250309T020000", "TZOFFSETFROM:-0500", "TZOFFSETTO:-0400", "TZNAME:EDT", "END:DAYLIGHT",
+				"BEGIN:STANDARD", "DTSTART:20251102T020000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0500", "TZNAME:EST", "END:STANDARD",
+			},
+		},
+		{
+			// 重复到 9999 年的事件只写出前两年的转换，最后一年的转换每年重复
+			id:    "America/New_York",
+			start: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"BEGIN:DAYLIGHT", "DTSTART:19700101T000000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0400", "TZNAME:EDT", "END:DAYLIGHT",
+				"BEGIN:STANDARD", "DTSTART:20241103T020000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0500", "TZNAME:EST", "END:STANDARD",
+				"BEGIN:DAYLIGHT", "DTSTART:20250309T020000", "TZOFFSETFROM:-0500", "TZOFFSETTO:-0400", "TZNAME:EDT", "END:DAYLIGHT",
+				"BEGIN:STANDARD", "DTSTART:20251102T020000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0500", "TZNAME:EST", "END:STANDARD",
+				"BEGIN:DAYLIGHT", "DTSTART:20260308T020000", "TZOFFSETFROM:-0500", "TZOFFSETTO:-0400", "TZNAME:EDT", "END:DAYLIGHT",
+				"BEGIN:STANDARD", "DTSTART:20261101T020000", "TZOFFSETFROM:-0400", "TZOFFSETTO:-0500", "TZNAME:EST",
+				"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU", "END:STANDARD",
+				"BEGIN:DAYLIGHT", "DTSTART:20270314T020000", "TZOFFSETFROM:-0500", "TZOFFSETTO:-0400", "TZNAME:EDT",
+				"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU", "END:DAYLIGHT",
+			},
+		},
+		{
+			id:    "Europe/Berlin",
+			start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"BEGIN:STANDARD", "DTSTART:19700101T000000", "TZOFFSETFROM:+0100", "TZOFFSETTO:+0100", "TZNAME:CET", "END:STANDARD",
+				"BEGIN:DAYLIGHT", "DTSTART:20240331T020000", "TZOFFSETFROM:+0100", "TZOFFSETTO:+0200", "TZNAME:CEST", "END:DAYLIGHT",
+				"BEGIN:STANDARD", "DTSTART:20241027T030000", "TZOFFSETFROM:+0200", "TZOFFSETTO:+0100", "TZNAME:CET", "END:STANDARD",
+				"BEGIN:DAYLIGHT", "DTSTART:20250330T020000", "TZOFFSETFROM:+0100", "TZOFFSETTO:+0200", "TZNAME:CEST", "END:DAYLIGHT",
+				"BEGIN:STANDARD", "DTSTART:20251026T030000", "TZOFFSETFROM:+0200", "TZOFFSETTO:+0100", "TZNAME:CET", "END:STANDARD",
+				"BEGIN:DAYLIGHT", "DTSTART:20260329T020000", "TZOFFSETFROM:+0100", "TZOFFSETTO:+0200", "TZNAME:CEST",
+				"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU", "END:DAYLIGHT",
+				"BEGIN:STANDARD", "DTSTART:20261025T030000", "TZOFFSETFROM:+0200", "TZOFFSETTO:+0100", "TZNAME:CET",
+				"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU", "END:STANDARD",
+			},
+		},
+		{
+			id:    "Asia/Kathmandu",
+			start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"BEGIN:STANDARD", "DTSTART:19700101T000000", "TZOFFSETFROM:+0545", "TZOFFSETTO:+0545", "TZNAME:+0545", "END:STANDARD",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id+"/"+tt.end.Format("2006"), func(t *testing.T) {
+			tz, err := LoadTimezone(tt.id)
+			if err != nil {
+				t.Fatalf("LoadTimezone() error = %v", err)
+			}
+			tz.SetRange(tt.start, tt.end)
+			want := "BEGIN:VTIMEZONE\r\nTZID:" + tt.id + "\r\n" + strings.Join(tt.want, "\r\n") + "\r\nEND:VTIMEZONE\r\n"
+			if got := tz.ToIcs(nil); got != want {
+				t.Errorf("ToIcs() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestOffsetToIcs(t *testing.T) {
+	tests := []struct {
+		offset time.Duration
+		want   string
+	}{
+		{0, "+0000"},
+		{8 * time.Hour, "+0800"},
+		{-5 * time.Hour, "-0500"},
+		{-(3*time.Hour + 30*time.Minute), "-0330"},
+		{5*time.Hour + 45*time.Minute, "+0545"},
+		{-(44*time.Minute + 30*time.Second), "-004430"},
+	}
+	for _, tt := range tests {
+		got := OffsetToIcs(tt.offset)
+		if got != tt.want {
+			t.Errorf("OffsetToIcs(%v) = %v, want %v", tt.offset, got, tt.want)
+		}
+		if parsed, err := ParseIcsOffset(got); err != nil || parsed != tt.offset {
+			t.Errorf("ParseIcsOffset(%v) = %v, %v", got, parsed, err)
+		}
+	}
+}
+
+func TestIcsCalendar_MultipleTimezones(t *testing.T) {
+	newYork, _ := LoadTimezone("America/New_York")
+	calendar := &IcsCalendar{}
+	calendar.SetProductID("productID")
+	calendar.SetTimezone(GetDefaultTimezone())
+	local := &IcsEvent{}
+	local.SetSummary("local")
+	local.SetStart(time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC))
+	remote := &IcsEvent{}
+	remote.SetSummary("remote")
+	remote.SetTimezone(newYork)
+	remote.SetStart(time.Date(2025, 3, 3, 14, 0, 0, 0, time.UTC))
+	calendar.AddEvent(local)
+	calendar.AddEvent(remote)
+	ics := calendar.ToIcs(nil)
+	for _, want := range []string{
+		"TZID:Asia/Shanghai\r\n", "TZID:America/New_York\r\n", "DTSTART:20241103T020000\r\n",
+		"DTSTART;TZID=Asia/Shanghai:20250303T160000\r\n", "DTSTART;TZID=America/New_York:20250303T090000\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("ToIcs() missing %q in\n%s", want, ics)
+		}
+	}
+	for _, problem := range validateIcs(ics) {
+		t.Error(problem)
+	}
+	parsed, err := ParseString(ics)
+	if err != nil {
+		t.Fatalf("ParseString() error = %v", err)
+	}
+	if got := parsed.ToIcs(nil); got != ics {
+		t.Errorf("round trip mismatch:\n%s\nwant:\n%s", got, ics)
+	}
+}
@@ -0,0 +1,69 @@
+package icalendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// EventVersion 记录事件上一次下发时的版本
+type EventVersion struct {
+	Fingerprint  string    // 事件内容的指纹
+	Sequence     int       // 事件的修订次数
+	LastModified time.Time // 事件的最后修改时间
+}
+
+// scheduleProperties 是参与指纹计算的属性，只包括事件的时间和地点
+//
+// 标题、描述和提醒会随日历导出选项变化，同一个事件用不同的选项导出时不应该被当作修改。
+var scheduleProperties = []string{"DTSTART", "DTEND", "DURATION", "RRULE", "RDATE", "EXDATE", "LOCATION"}
+
+// Fingerprint 计算事件时间和地点的指纹，其他属性和 VALARM 等子组件不参与计算
+func Fingerprint(event Event) string {
+	hash := sha256.New()
+	lines, _ := unfoldLines(strings.NewReader(event.ToIcs(nil)))
+	depth := 0
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "BEGIN:"):
+			depth++
+			continue
+		case strings.HasPrefix(line, "END:"):
+			depth--
+			continue
+		case depth != 1:
+			// 只计算 VEVENT 自身的属性
+			continue
+		}
+		for _, name := range scheduleProperties {
+			if strings.HasPrefix(line, name+":") || strings.HasPrefix(line, name+";") {
+				hash.Write([]byte(line + CRLF))
+				break
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ApplyVersions 与上一次下发的版本对比，内容发生变化的事件递增 SEQUENCE 并更新 LAST-MODIFIED，
+// DTSTAMP 同样使用最后修改时间，保证内容不变时输出也不变。
+// 返回本次下发的版本记录，previous 中已经不存在的事件不会被保留
+func ApplyVersions(calendar Calendar, previous map[string]EventVersion, now time.Time) map[string]EventVersion {
+	current := map[string]EventVersion{}
+	for _, event := range calendar.GetEvents() {
+		fingerprint := Fingerprint(event)
+		version, found := previous[event.GetUID()]
+		switch {
+		case !found:
+			version = EventVersion{Fingerprint: fingerprint, LastModified: now}
+		case version.Fingerprint != fingerprint:
+			version = EventVersion{Fingerprint: fingerprint, Sequence: version.Sequence + 1, LastModified: now}
+		}
+		event.SetSequence(version.Sequence)
+		event.SetLastModified(version.LastModified)
+		event.SetDtStamp(version.LastModified)
+		current[event.GetUID()] = version
+	}
+	return current
+}
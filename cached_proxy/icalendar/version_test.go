@@ -0,0 +1,57 @@
+package icalendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newVersionTestCalendar(location string) *IcsCalendar {
+	event := &IcsEvent{}
+	event.SetUID("course-1@gonggong")
+	event.SetSummary("summary")
+	event.SetStart(time.Date(2025, 2, 17, 8, 0, 0, 0, time.UTC))
+	l := &IcsLocation{}
+	l.SetName(location)
+	event.SetLocation(l)
+	calendar := &IcsCalendar{}
+	calendar.AddEvent(event)
+	return calendar
+}
+
+func TestApplyVersions(t *testing.T) {
+	first := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	calendar := newVersionTestCalendar("A101")
+	versions := ApplyVersions(calendar, nil, first)
+	if v := versions["course-1@gonggong"]; v.Sequence != 0 || !v.LastModified.Equal(first) {
+		t.Fatalf("unexpected initial version: %+v", v)
+	}
+
+	// 内容没有变化，版本保持不变
+	unchanged := newVersionTestCalendar("A101")
+	versions = ApplyVersions(unchanged, versions, second)
+	if v := versions["course-1@gonggong"]; v.Sequence != 0 || !v.LastModified.Equal(first) {
+		t.Errorf("unchanged event should keep its version: %+v", v)
+	}
+
+	// 标题和提醒随导出选项变化，不算修改
+	renamed := newVersionTestCalendar("A101")
+	renamed.GetEvents()[0].SetSummary("other summary")
+	versions = ApplyVersions(renamed, versions, second)
+	if v := versions["course-1@gonggong"]; v.Sequence != 0 || !v.LastModified.Equal(first) {
+		t.Errorf("summary change should keep the version: %+v", v)
+	}
+
+	// 教室变化后，SEQUENCE 递增
+	changed := newVersionTestCalendar("B202")
+	versions = ApplyVersions(changed, versions, second)
+	if v := versions["course-1@gonggong"]; v.Sequence != 1 || !v.LastModified.Equal(second) {
+		t.Errorf("changed event should bump its version: %+v", v)
+	}
+	ics := changed.ToIcs(nil)
+	if !strings.Contains(ics, "SEQUENCE:1\r\n") || !strings.Contains(ics, "LAST-MODIFIED:20250201T010000Z\r\n") {
+		t.Errorf("ToIcs() should contain SEQUENCE and LAST-MODIFIED:\n%s", ics)
+	}
+}
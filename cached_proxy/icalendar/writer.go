@@ -0,0 +1,104 @@
+package icalendar
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// CRLF 是 ICS 文件的换行符
+	CRLF = "\r\n"
+	// MaxLineOctets 是每一行的最大字节数（不包含换行符）
+	MaxLineOctets = 75
+)
+
+// ContentWriter 用于按照 RFC 5545 的要求写入内容行，负责转义、折行和 CRLF 换行。
+type ContentWriter struct {
+	builder strings.Builder
+}
+
+// Begin 写入组件的开始行，例如 BEGIN:VEVENT
+func (w *ContentWriter) Begin(component string) {
+	w.WriteLine("BEGIN", component)
+}
+
+// End 写入组件的结束行，例如 END:VEVENT
+func (w *ContentWriter) End(component string) {
+	w.WriteLine("END", component)
+}
+
+// WriteLine 写入一行属性，value 不会被转义，params 形如 "TZID=Asia/Shanghai"
+func (w *ContentWriter) WriteLine(name string, value string, params ...string) {
+	line := strings.Builder{}
+	line.WriteString(name)
+	for _, param := range params {
+		line.WriteString(";" + param)
+	}
+	line.WriteString(":" + value)
+	w.builder.WriteString(FoldLine(line.String()))
+}
+
+// WriteText 写入一行文本类型的属性，value 会按照 TEXT 类型的规则转义
+func (w *ContentWriter) WriteText(name string, value string, params ...string) {
+	w.WriteLine(name, EscapeText(value), params...)
+}
+
+// WriteTime 写入一行日期时间类型的属性
+func (w *ContentWriter) WriteTime(name string, t time.Time, timezone *Timezone) {
+	w.builder.WriteString(FoldLine(name + TimeToIcs(t, timezone, ":")))
+}
+
+// WriteComponent 写入一个子组件，子组件的内容已经是完整的内容行
+func (w *ContentWriter) WriteComponent(component Component, timezone *Timezone) {
+	w.builder.WriteString(component.ToIcs(timezone))
+}
+
+// String 返回已写入的内容
+func (w *ContentWriter) String() string {
+	return w.builder.String()
+}
+
+// EscapeText 按照 RFC 5545 3.3.11 转义文本
+func EscapeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return textEscaper.Replace(text)
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\n", `\n`,
+)
+
+// ParamValue 按照 RFC 5545 3.2 生成参数值，包含冒号、分号或逗号时使用双引号包围，参数值中不允许出现双引号
+func ParamValue(value string) string {
+	value = strings.ReplaceAll(value, `"`, "'")
+	if strings.ContainsAny(value, ":;,") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// FoldLine 按照 RFC 5545 3.1 将超过 75 字节的内容行折行，折行不会拆开 UTF-8 字符，返回结果以 CRLF 结尾
+func FoldLine(line string) string {
+	result := strings.Builder{}
+	limit := MaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		// 回退到 UTF-8 字符的边界
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		result.WriteString(line[:cut])
+		result.WriteString(CRLF + " ")
+		line = line[cut:]
+		// 续行以一个空格开头，因此可用的字节数少一个
+		limit = MaxLineOctets - 1
+	}
+	result.WriteString(line)
+	result.WriteString(CRLF)
+	return result.String()
+}
@@ -0,0 +1,62 @@
+package icalendar
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "Plain", text: "summary", want: "summary"},
+		{name: "Comma and semicolon", text: "a,b;c", want: `a\,b\;c`},
+		{name: "Backslash", text: `a\b`, want: `a\\b`},
+		{name: "Newline", text: "a\nb\r\nc", want: `a\nb\nc`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EscapeText(tt.text); got != tt.want {
+				t.Errorf("EscapeText() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "Short line", line: "SUMMARY:summary"},
+		{name: "Exactly 75 octets", line: "DESCRIPTION:" + strings.Repeat("a", 63)},
+		{name: "Long ascii line", line: "DESCRIPTION:" + strings.Repeat("abcdefghij", 20)},
+		{name: "Long chinese line", line: "DESCRIPTION:" + strings.Repeat("湘潭大学拱拱", 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := FoldLine(tt.line)
+			if !strings.HasSuffix(folded, CRLF) {
+				t.Fatalf("FoldLine() should end with CRLF: %q", folded)
+			}
+			lines := strings.Split(strings.TrimSuffix(folded, CRLF), CRLF)
+			for i, line := range lines {
+				if len(line) > MaxLineOctets {
+					t.Errorf("line %d has %d octets: %q", i, len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a UTF-8 character: %q", i, line)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d should start with a space: %q", i, line)
+				}
+			}
+			if got := strings.ReplaceAll(strings.TrimSuffix(folded, CRLF), CRLF+" ", ""); got != tt.line {
+				t.Errorf("unfolded line = %q, want %q", got, tt.line)
+			}
+		})
+	}
+}
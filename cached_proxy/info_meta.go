@@ -0,0 +1,59 @@
+package main
+
+import (
+	"cached_proxy/cache"
+	"cached_proxy/feign"
+	"time"
+)
+
+// 响应的 Code，客户端可以据此区分数据是否为最新
+const (
+	// CodeSuccess 数据有效
+	CodeSuccess = 1
+	// CodeExpired 数据已过期，已触发更新，返回的是上一次的数据
+	CodeExpired = 2
+	// CodeUpdating 数据正在更新，返回的是上一次的数据
+	CodeUpdating = 3
+	// CodeNotFound 从未获取过数据，已触发更新，data 为 null
+	CodeNotFound = 4
+)
+
+// InfoMeta 是数据的新鲜度信息，用于显示 "3 小时前同步" 等提示
+type InfoMeta struct {
+	Status      cache.ItemStatus `json:"status"`
+	UpdatedAt   *time.Time       `json:"updated_at"`
+	LastError   string           `json:"last_error,omitempty"` // 最近一次更新失败的错误码，与 ErrorResponse.Error 相同
+	NextRefresh *time.Time       `json:"next_refresh"`
+}
+
+// InfoResponse 是带有新鲜度信息的响应
+type InfoResponse struct {
+	feign.CommonResponse[any]
+	Meta InfoMeta `json:"meta"`
+}
+
+// newInfoResponse 根据缓存状态生成响应，从未成功更新过的数据按照未找到处理
+func newInfoResponse[V any](data *V, meta cache.Meta) InfoResponse {
+	resp := InfoResponse{Meta: InfoMeta{Status: meta.Status, LastError: errorCode(meta.LastError)}}
+	if !meta.UpdatedAt.IsZero() {
+		updatedAt := meta.UpdatedAt
+		resp.Meta.UpdatedAt = &updatedAt
+	}
+	if !meta.NextRefresh.IsZero() {
+		nextRefresh := meta.NextRefresh
+		resp.Meta.NextRefresh = &nextRefresh
+	}
+	switch {
+	case meta.Status == cache.NotFound || meta.UpdatedAt.IsZero():
+		resp.Code, resp.Message = CodeNotFound, "not found"
+		return resp
+	case meta.Status == cache.Expired:
+		resp.Code, resp.Message = CodeExpired, "expired"
+	case meta.Status == cache.Updating:
+		resp.Code, resp.Message = CodeUpdating, "updating"
+	default:
+		resp.Code, resp.Message = CodeSuccess, "success"
+	}
+	resp.Data = data
+	return resp
+}
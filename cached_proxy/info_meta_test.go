@@ -0,0 +1,59 @@
+package main
+
+import (
+	"cached_proxy/cache"
+	"cached_proxy/feign"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewInfoResponse(t *testing.T) {
+	updatedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	data := "info"
+	// 上游的错误包含爬虫服务的地址等内部信息，只返回错误码
+	dialErr := fmt.Errorf("发送请求失败: %w", errors.New(`Get "http://spider:8000/info": dial tcp 10.0.0.5:8000: connect: connection refused`))
+	tests := []struct {
+		name      string
+		meta      cache.Meta
+		code      int
+		data      bool
+		lastError string
+	}{
+		{"Valid", cache.Meta{Status: cache.Valid, UpdatedAt: updatedAt, NextRefresh: updatedAt.Add(time.Hour)}, CodeSuccess, true, ""},
+		{"Expired", cache.Meta{Status: cache.Expired, UpdatedAt: updatedAt, LastError: fmt.Errorf("exceeded retry attempts: %w", feign.ErrUpstreamUnavailable)}, CodeExpired, true, "upstream_unavailable"},
+		{"Updating", cache.Meta{Status: cache.Updating, UpdatedAt: updatedAt, LastError: dialErr}, CodeUpdating, true, "internal_error"},
+		{"NotFound", cache.Meta{Status: cache.NotFound}, CodeNotFound, false, ""},
+		// 第一次更新尚未完成时缓存中只有零值
+		{"NeverUpdated", cache.Meta{Status: cache.Updating, LastError: feign.ErrUnauthorized}, CodeNotFound, false, "unauthorized"},
+		{"RateLimited", cache.Meta{Status: cache.Expired, UpdatedAt: updatedAt, LastError: &feign.RateLimitError{Scope: "account"}}, CodeExpired, true, "rate_limited"},
+		{"Captcha", cache.Meta{Status: cache.Expired, UpdatedAt: updatedAt, LastError: feign.ErrCaptcha}, CodeExpired, true, "captcha_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := newInfoResponse(&data, tt.meta)
+			if resp.Code != tt.code || (resp.Data != nil) != tt.data {
+				t.Errorf("unexpected response %+v", resp)
+			}
+			if resp.Meta.Status != tt.meta.Status || resp.Meta.LastError != tt.lastError {
+				t.Errorf("unexpected meta %+v", resp.Meta)
+			}
+		})
+	}
+
+	content, err := json.Marshal(newInfoResponse(&data, tests[0].meta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"code":1,"message":"success","data":"info","meta":{"status":"valid","updated_at":"2025-03-01T08:00:00Z","next_refresh":"2025-03-01T09:00:00Z"}}`
+	if string(content) != want {
+		t.Errorf("unexpected JSON %s", content)
+	}
+	content, _ = json.Marshal(newInfoResponse[string](nil, cache.Meta{Status: cache.NotFound, LastError: feign.ErrUnauthorized}))
+	if !strings.Contains(string(content), `"data":null`) || !strings.Contains(string(content), `"updated_at":null,"last_error":"unauthorized","next_refresh":null`) {
+		t.Errorf("unexpected JSON %s", content)
+	}
+}
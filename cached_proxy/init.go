@@ -5,7 +5,11 @@ import (
 	"cached_proxy/cache"
 	"cached_proxy/executor"
 	"cached_proxy/feign"
-	"log"
+	"cached_proxy/repo"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -13,49 +17,73 @@ import (
 // 这里是初始化代码， 用于初始化各种服务
 
 var (
-	// Client 是爬虫服务的客户端
-	Client feign.SpiderClient = feign.NewSpiderClientImpl(SpiderUrl, // SpiderUrl 是爬虫服务的地址， 通过环境变量 SPIDER_URL 设置
-		http.Client{})
+	// SpiderBreaker 为爬虫服务的每个接口添加熔断器，爬虫服务不可用时快速失败，使用缓存的数据
+	SpiderBreaker = feign.NewBreakerClient(feign.NewSpiderClientImpl(SpiderUrl, // SpiderUrl 是爬虫服务的地址， 通过环境变量 SPIDER_URL 设置
+		http.Client{}), feign.DefaultBreakerConfig)
+	// Client 是爬虫服务的客户端，请求先经过限流再经过熔断器，被限流的请求不计入熔断器的失败
+	Client feign.SpiderClient = feign.NewLimitedClient(SpiderBreaker, SpiderLimits)
 	// StudentService 是学生服务
 	StudentService feign.StudentService = feign.NewStudentServiceImpl(&Client)
 )
 
+var (
+	// HolidayService 是节假日和调休安排服务
+	HolidayService feign.HolidayService = feign.NewFileHolidayService(HolidayDir)
+)
+
+var (
+	// CalendarVersionService 记录下发过的日历事件版本，记录中包含日历的修改时间，与只记录事件版本的 calendar_versions.gob 格式不同
+	CalendarVersionService = NewCalendarVersions(
+		repo.NewFileRepos[string, CalendarVersion]("./_data/calendar_versions_v2.gob"))
+	// CalendarOptionsRepository 保存每个账户的日历导出选项
+	CalendarOptionsRepository repo.KVRepo[string, CalendarOptions] = repo.NewFileRepos[string, CalendarOptions]("./_data/calendar_options.gob")
+)
+
 var (
 	// AccountRepository 是账户的数据仓库
 	AccountRepository = account.NewFileRepository("./_data")
 	// AccountService 是账户的服务
 	AccountService account.Service = account.NewServiceImpl(AccountRepository)
+	// FeedService 是订阅日历密钥的服务
+	FeedService account.FeedService = account.NewFeedServiceImpl(account.NewFeedFileRepository("./_data"), AccountRepository)
 )
 
 // updateTask 是一个通用的更新任务， 用于更新学生信息， 同时也会根据返回的错误信息进行账户锁定
-func updateTask[V any](update func(*feign.Student) (*V, error)) func(string) (*V, bool) {
-	return func(studentID string) (*V, bool) {
+// 协程池限制每次更新最多进行 SpiderTimeout，包括登录和重试，退出时等待超时也会取消更新
+func updateTask[V any](update func(context.Context, *feign.Student) (*V, error)) func(context.Context, string) (*V, error) {
+	return func(ctx context.Context, studentID string) (*V, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		defer context.AfterFunc(taskCtx, cancel)()
 		student, err := StudentService.GetStudent(studentID)
 		if err != nil {
 			a, err := AccountService.GetAccountByAccountID(studentID)
 			if err != nil {
-				return nil, false
+				return nil, err
 			}
-			err = StudentService.SetStudent(a.AccountID(), a.GetPassword(), false)
+			err = StudentService.SetStudent(ctx, a.AccountID(), a.GetPassword(), false)
 			// fix 设置后需要重新获取一次学生账户
 			student, _ = StudentService.GetStudent(studentID)
 			if err != nil {
-				log.Printf("account %s is locked", studentID)
+				slog.WarnContext(ctx, "account is locked", "student", studentID, "error", err)
+				if student == nil {
+					return nil, err
+				}
 			}
 		}
 		if student == nil {
-			return nil, false
+			return nil, fmt.Errorf("student %s not found", studentID)
 		}
-		value, err := update(student)
-		if err != nil && err.Error() == "unauthorized" {
-			log.Print("unauthorized: ", studentID)
+		value, err := update(ctx, student)
+		if errors.Is(err, feign.ErrUnauthorized) {
+			slog.WarnContext(ctx, "unauthorized, locking account", "student", studentID)
 			// 如果是未授权， 锁定账户
 			err := AccountService.LockAccount(studentID)
 			if err != nil {
-				log.Print("failed to lock account: ", err)
+				slog.ErrorContext(ctx, "failed to lock account", "student", studentID, "error", err)
 			}
 		}
-		return value, err == nil
+		return value, err
 	}
 
 }
@@ -74,65 +102,71 @@ var (
 )
 
 var (
-	TodayClassroomUpdater = updateTask[feign.ClassroomStatusTable](func(student *feign.Student) (*feign.ClassroomStatusTable, error) {
-		value, err := (*student).GetClassroomStatus(0)
+	TodayClassroomUpdater = updateTask[feign.ClassroomStatusTable](func(ctx context.Context, student *feign.Student) (*feign.ClassroomStatusTable, error) {
+		value, err := (*student).GetClassroomStatus(ctx, 0)
 		return value, err
 	})
-	TomorrowClassroomUpdater = updateTask[feign.ClassroomStatusTable](func(student *feign.Student) (*feign.ClassroomStatusTable, error) {
-		value, err := (*student).GetClassroomStatus(1)
+	TomorrowClassroomUpdater = updateTask[feign.ClassroomStatusTable](func(ctx context.Context, student *feign.Student) (*feign.ClassroomStatusTable, error) {
+		value, err := (*student).GetClassroomStatus(ctx, 1)
 		return value, err
 	})
-	CalendarUpdater = updateTask[feign.TeachingCalendar](func(student *feign.Student) (*feign.TeachingCalendar, error) {
-		value, err := (*student).GetTeachingCalendar()
+	CalendarUpdater = updateTask[feign.TeachingCalendar](func(ctx context.Context, student *feign.Student) (*feign.TeachingCalendar, error) {
+		value, err := (*student).GetTeachingCalendar(ctx)
 		return value, err
 	})
 )
 
 var (
-	StudentInfoUpdater = updateTask[feign.StudentInfo](func(student *feign.Student) (*feign.StudentInfo, error) {
-		value, err := (*student).GetInfo()
+	StudentInfoUpdater = updateTask[feign.StudentInfo](func(ctx context.Context, student *feign.Student) (*feign.StudentInfo, error) {
+		value, err := (*student).GetInfo(ctx)
 		return value, err
 	})
-	StudentMajorScoreUpdater = updateTask[feign.ScoreBoard](func(student *feign.Student) (*feign.ScoreBoard, error) {
-		value, err := (*student).GetStudentScore(true)
+	StudentMajorScoreUpdater = updateTask[feign.ScoreBoard](func(ctx context.Context, student *feign.Student) (*feign.ScoreBoard, error) {
+		value, err := (*student).GetStudentScore(ctx, true)
 		return value, err
 	})
-	StudentMinorScoreUpdater = updateTask[feign.ScoreBoard](func(student *feign.Student) (*feign.ScoreBoard, error) {
-		value, err := (*student).GetStudentScore(false)
+	StudentMinorScoreUpdater = updateTask[feign.ScoreBoard](func(ctx context.Context, student *feign.Student) (*feign.ScoreBoard, error) {
+		value, err := (*student).GetStudentScore(ctx, false)
 		return value, err
 	})
-	StudentTotalRankUpdater = updateTask[feign.Rank](func(student *feign.Student) (*feign.Rank, error) {
-		value, err := (*student).GetStudentRank(false)
+	StudentTotalRankUpdater = updateTask[feign.Rank](func(ctx context.Context, student *feign.Student) (*feign.Rank, error) {
+		value, err := (*student).GetStudentRank(ctx, false)
 		return value, err
 	})
-	StudentRequiredRankUpdater = updateTask[feign.Rank](func(student *feign.Student) (*feign.Rank, error) {
-		value, err := (*student).GetStudentRank(true)
+	StudentRequiredRankUpdater = updateTask[feign.Rank](func(ctx context.Context, student *feign.Student) (*feign.Rank, error) {
+		value, err := (*student).GetStudentRank(ctx, true)
 		return value, err
 	})
-	StudentExamUpdater = updateTask[feign.ExamList](func(student *feign.Student) (*feign.ExamList, error) {
-		value, err := (*student).GetStudentExams()
+	StudentExamUpdater = updateTask[feign.ExamList](func(ctx context.Context, student *feign.Student) (*feign.ExamList, error) {
+		value, err := (*student).GetStudentExams(ctx)
 		return value, err
 	})
-	StudentCourseUpdater = updateTask[feign.CourseList](func(student *feign.Student) (*feign.CourseList, error) {
-		value, err := (*student).GetStudentCourses()
+	StudentCourseUpdater = updateTask[feign.CourseList](func(ctx context.Context, student *feign.Student) (*feign.CourseList, error) {
+		value, err := (*student).GetStudentCourses(ctx)
 		return value, err
 	})
 )
 
-var exec = executor.NewWorkerPool(10)
+// exec 是更新缓存的协程池，缓存未命中的更新优先于过期数据的后台刷新
+var exec = executor.NewPriorityPool(executor.PoolConfig{
+	Workers:     RefreshWorkers,
+	QueueSize:   RefreshQueueSize,
+	Policy:      RefreshQueuePolicy,
+	TaskTimeout: SpiderTimeout,
+})
 
 var (
-	TodayClassroomService    = cache.NewPublicInformationService[feign.ClassroomStatusTable](exec, ClassroomChecker, TodayClassroomUpdater)
-	TomorrowClassroomService = cache.NewPublicInformationService[feign.ClassroomStatusTable](exec, ClassroomChecker, TomorrowClassroomUpdater)
-	CalendarService          = cache.NewPublicInformationService[feign.TeachingCalendar](exec, CalendarChecker, CalendarUpdater)
+	TodayClassroomService    = cache.NewPublicInformationService[feign.ClassroomStatusTable]("classroom_today", exec, ClassroomChecker, TodayClassroomUpdater)
+	TomorrowClassroomService = cache.NewPublicInformationService[feign.ClassroomStatusTable]("classroom_tomorrow", exec, ClassroomChecker, TomorrowClassroomUpdater)
+	CalendarService          = cache.NewPublicInformationService[feign.TeachingCalendar]("calendar", exec, CalendarChecker, CalendarUpdater)
 )
 
 var (
-	StudentInfoService         = cache.NewPersonalInformationService[feign.StudentInfo](exec, InfoChecker, StudentInfoUpdater)
-	StudentMajorScoreService   = cache.NewPersonalInformationService[feign.ScoreBoard](exec, ScoreChecker, StudentMajorScoreUpdater)
-	StudentMinorScoreService   = cache.NewPersonalInformationService[feign.ScoreBoard](exec, ScoreChecker, StudentMinorScoreUpdater)
-	StudentTotalRankService    = cache.NewPersonalInformationService[feign.Rank](exec, RankChecker, StudentTotalRankUpdater)
-	StudentRequiredRankService = cache.NewPersonalInformationService[feign.Rank](exec, RankChecker, StudentRequiredRankUpdater)
-	StudentExamService         = cache.NewPersonalInformationService[feign.ExamList](exec, ExamChecker, StudentExamUpdater)
-	StudentCourseService       = cache.NewPersonalInformationService[feign.CourseList](exec, CourseChecker, StudentCourseUpdater)
+	StudentInfoService         = cache.NewPersonalInformationService[feign.StudentInfo]("info", exec, InfoChecker, StudentInfoUpdater)
+	StudentMajorScoreService   = cache.NewPersonalInformationService[feign.ScoreBoard]("major_scores", exec, ScoreChecker, StudentMajorScoreUpdater)
+	StudentMinorScoreService   = cache.NewPersonalInformationService[feign.ScoreBoard]("minor_scores", exec, ScoreChecker, StudentMinorScoreUpdater)
+	StudentTotalRankService    = cache.NewPersonalInformationService[feign.Rank]("total_rank", exec, RankChecker, StudentTotalRankUpdater)
+	StudentRequiredRankService = cache.NewPersonalInformationService[feign.Rank]("required_rank", exec, RankChecker, StudentRequiredRankUpdater)
+	StudentExamService         = cache.NewPersonalInformationService[feign.ExamList]("exams", exec, ExamChecker, StudentExamUpdater)
+	StudentCourseService       = cache.NewPersonalInformationService[feign.CourseList]("courses", exec, CourseChecker, StudentCourseUpdater)
 )
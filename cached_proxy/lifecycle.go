@@ -0,0 +1,59 @@
+package main
+
+import (
+	"cached_proxy/executor"
+	"cached_proxy/repo"
+	"cached_proxy/tracing"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// 这里是退出流程，收到退出信号后依次停止接受请求、等待后台任务、写回文件存储和导出剩余的追踪
+
+// shutdownGrace 超时后取消后台任务，再等待任务退出的时间，也用于导出剩余的追踪
+const shutdownGrace = 5 * time.Second
+
+// taskCtx 是后台更新任务的上下文，退出时等待超时后被取消，让正在请求爬虫服务的任务尽快结束
+var taskCtx, cancelTasks = context.WithCancel(context.Background())
+
+// drainer 是可以等待任务完成后停止的协程池
+type drainer interface {
+	Shutdown(ctx context.Context) error
+	Stats() executor.PoolStats
+}
+
+// shutdown 在 ctx 结束前完成正在处理的请求和后台任务，之后的步骤不受 ctx 限制
+func shutdown(ctx context.Context, server *http.Server, pool drainer, processor *tracing.BatchProcessor) {
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("requests did not finish in time, closing connections", "error", err)
+		_ = server.Close()
+	}
+	drainPool(ctx, pool, cancelTasks)
+	if err := repo.FlushAll(); err != nil {
+		slog.Error("failed to flush repositories", "error", err)
+	}
+	if processor != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			slog.Warn("failed to flush traces", "error", err)
+		}
+	}
+	slog.Info("shutdown complete")
+}
+
+// drainPool 等待协程池中的任务完成，超时后调用 cancel 取消后台任务并再等待 shutdownGrace
+func drainPool(ctx context.Context, pool drainer, cancel context.CancelFunc) {
+	if err := pool.Shutdown(ctx); err == nil {
+		return
+	}
+	slog.Warn("background tasks did not finish in time, canceling", "stats", pool.Stats())
+	cancel()
+	ctx, cancelGrace := context.WithTimeout(context.Background(), shutdownGrace)
+	defer cancelGrace()
+	if err := pool.Shutdown(ctx); err != nil {
+		slog.Error("exiting with running background tasks", "stats", pool.Stats())
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"cached_proxy/executor"
+	"cached_proxy/tracing"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrainPool(t *testing.T) {
+	tests := []struct {
+		name     string
+		blocking bool
+	}{
+		{"tasks finish in time", false},
+		{"tasks canceled after timeout", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := executor.NewWorkerPool(2)
+			tasks, cancelTasks := context.WithCancel(context.Background())
+			defer cancelTasks()
+			var finished atomic.Int32
+			for i := 0; i < 2; i++ {
+				pool.Submit(func() {
+					if tt.blocking {
+						<-tasks.Done()
+					}
+					finished.Add(1)
+				})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			drainPool(ctx, pool, cancelTasks)
+			if finished.Load() != 2 || pool.Stats().Busy != 0 {
+				t.Errorf("all tasks should finish, got %d finished, stats %+v", finished.Load(), pool.Stats())
+			}
+			if canceled := tasks.Err() != nil; canceled != tt.blocking {
+				t.Errorf("tasks canceled = %v, want %v", canceled, tt.blocking)
+			}
+		})
+	}
+}
+
+// exporterFunc 将函数作为 tracing.Exporter
+type exporterFunc func(ctx context.Context, spans []tracing.SpanData) error
+
+func (f exporterFunc) Export(ctx context.Context, spans []tracing.SpanData) error {
+	return f(ctx, spans)
+}
+
+func TestShutdown(t *testing.T) {
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(listener) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	var exported atomic.Int32
+	processor := tracing.NewBatchProcessor(exporterFunc(func(_ context.Context, spans []tracing.SpanData) error {
+		exported.Add(int32(len(spans)))
+		return nil
+	}), 10, 10, time.Hour)
+	_, span := tracing.NewTracer(processor).Start(context.Background(), "pending")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	shutdown(ctx, server, executor.NewWorkerPool(1), processor)
+	if got := <-body; got != "done" {
+		t.Errorf("in-flight request should complete, got %q", got)
+	}
+	if exported.Load() != 1 {
+		t.Errorf("pending spans should be exported, got %d", exported.Load())
+	}
+	if _, err := http.Get("http://" + listener.Addr().String()); err == nil {
+		t.Error("server should not accept new connections")
+	}
+}
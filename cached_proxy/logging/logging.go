@@ -0,0 +1,125 @@
+// Package logging 提供基于 log/slog 的结构化日志，输出 JSON，并为日志添加请求 ID、隐藏密码和令牌
+package logging
+
+import (
+	"cached_proxy/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Redacted 替换敏感字段的值
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 字段名包含这些词时隐藏字段的值，不区分大小写
+var sensitiveKeys = []string{"password", "passwd", "token", "authorization", "secret", "cookie"}
+
+// IsSensitive 判断字段名是否为敏感字段
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactURL 返回隐藏了敏感查询参数的 URL，用户信息中的密码由 url.URL.Redacted 隐藏
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if IsSensitive(key) {
+				query[key] = []string{Redacted}
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.Redacted()
+}
+
+// redact 是 slog 的 ReplaceAttr，隐藏敏感字段的值和 URL 中的敏感信息
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	if u, ok := a.Value.Any().(*url.URL); ok {
+		return slog.String(a.Key, RedactURL(u))
+	}
+	return a
+}
+
+type requestIDKey struct{}
+
+// WithRequestID 返回携带请求 ID 的上下文，通过该上下文输出的日志都带有请求 ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 返回上下文中的请求 ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID 生成随机的请求 ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// contextHandler 为日志添加上下文中的请求 ID 和追踪 ID
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID.String()), slog.String("span_id", sc.SpanID.String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// New 创建输出 JSON 到 w 的日志
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redact})})
+}
+
+// ParseLevel 解析日志级别 debug、info、warn 和 error，无法解析时使用 info
+func ParseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Setup 将默认的日志设置为输出到标准错误的 JSON 日志，标准库 log 的输出也会转为 info 级别的 JSON 日志
+func Setup(level slog.Leveler) {
+	slog.SetDefault(New(os.Stderr, level))
+}
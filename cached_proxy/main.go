@@ -1,17 +1,32 @@
 package main
 
 import (
+	"cached_proxy/logging"
+	"cached_proxy/metrics"
+	"cached_proxy/tracing"
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func StartApiServer(port int) {
+// NewApiServer 创建 API 服务器，注册所有路由
+func NewApiServer(port int) *http.Server {
 	server := http.NewServeMux()
 	server.HandleFunc("/login", Login)
+	server.HandleFunc("/health", HealthHandler.GetInfo)
+	server.Handle("/metrics", metrics.Default.Handler())
 	server.HandleFunc("/courses", CourseHandler.GetInfo)
+	server.HandleFunc("/schedule", ScheduleHandler.GetInfo)
 	server.HandleFunc("/exams", ExamHandler.GetInfo)
+	server.HandleFunc("/exams/upcoming", UpcomingExamsHandler.GetInfo)
 	server.HandleFunc("/info", InfoHandler.GetInfo)
 	server.HandleFunc("/scores", MajorScoreHandler.GetInfo)
+	server.HandleFunc("/scores/export", ScoreExportHandler.GetInfo)
 	server.HandleFunc("/minor/scores", MinorScoreHandler.GetInfo)
 	server.HandleFunc("/rank", TotalRankHandler.GetInfo)
 	server.HandleFunc("/compulsory/rank", RequiredRankHandler.GetInfo)
@@ -21,16 +36,62 @@ func StartApiServer(port int) {
 	server.HandleFunc("/oauth/introspect", AccountHandler.GetInfo)
 	server.HandleFunc("/icalendar/courses", CoursesCalendarHandler.GetInfo)
 	server.HandleFunc("/icalendar/exams", ExamCalendarHandler.GetInfo)
+	server.HandleFunc("/icalendar/all", AllCalendarHandler.GetInfo)
+	server.HandleFunc("/icalendar/options", CalendarOptionsHandler.GetInfo)
 	server.HandleFunc("/icalendar", CalPage)
-	fmt.Printf("Proxy Server URL: %s\n", SpiderUrl)
-	fmt.Printf("Starting server on :%d\n", port)
-	err := http.ListenAndServe(fmt.Sprintf(":%d", port), server)
-	if err != nil {
-		fmt.Printf("failed to start server: %v\n", err)
-		return
+	server.HandleFunc("/feeds", FeedHandler.GetInfo)
+	server.HandleFunc("/feeds/", FeedHandler.ServeFeed)
+	server.HandleFunc("/.well-known/caldav", CalDAVHandler.WellKnown)
+	server.HandleFunc(CalDAVRoot, CalDAVHandler.ServeDAV)
+	return &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: withRequestID(instrument(server, ApiThrottle.Wrap(server))),
 	}
 }
 
+// setupTracing 根据 TracesExporter 设置默认的 Tracer，不导出追踪时返回 nil
+func setupTracing() *tracing.BatchProcessor {
+	var exporter tracing.Exporter
+	switch TracesExporter {
+	case "otlp":
+		exporter = tracing.NewOTLPExporter(ServiceName, OTLPEndpoint, &http.Client{Timeout: 10 * time.Second})
+	case "console", "stdout":
+		exporter = tracing.NewStdoutExporter(ServiceName, os.Stdout)
+	case "none", "":
+		return nil
+	default:
+		slog.Warn("unknown traces exporter, tracing disabled", "exporter", TracesExporter)
+		return nil
+	}
+	processor := tracing.NewBatchProcessor(exporter, 2048, 512, 5*time.Second)
+	tracing.SetDefault(tracing.NewTracer(processor))
+	slog.Info("tracing enabled", "exporter", TracesExporter, "service", ServiceName)
+	return processor
+}
+
 func main() {
-	StartApiServer(ApiPort)
+	logging.Setup(logging.ParseLevel(LogLevel))
+	logConfigWarnings()
+	processor := setupTracing()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go watchReload(ctx, RefreshWorkersFile, exec)
+
+	server := NewApiServer(ApiPort)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	slog.Info("starting server", "port", ApiPort, "spider", SpiderUrl)
+	select {
+	case err := <-serveErr:
+		slog.Error("failed to start server", "error", err)
+	case <-ctx.Done():
+		slog.Info("received signal, shutting down", "timeout", ShutdownTimeout)
+	}
+	// 再次收到信号时直接退出
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	shutdown(shutdownCtx, server, exec, processor)
 }
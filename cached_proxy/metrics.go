@@ -0,0 +1,189 @@
+package main
+
+import (
+	account2 "cached_proxy/account"
+	"cached_proxy/executor"
+	"cached_proxy/feign"
+	"cached_proxy/metrics"
+	"cached_proxy/tracing"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// httpRequestsMetric 按路由、方法和状态码统计入站请求，路由使用注册的模式，避免路径参数导致标签过多
+	httpRequestsMetric = metrics.NewCounterVec("proxy_http_requests_total",
+		"HTTP requests by route, method and status code.", "route", "method", "code")
+	// httpLatencyMetric 统计入站请求的耗时
+	httpLatencyMetric = metrics.NewHistogramVec("proxy_http_request_duration_seconds",
+		"HTTP request latency by route and method.", nil, "route", "method")
+	// loginsMetric 统计登录的结果
+	loginsMetric = metrics.NewCounterVec("proxy_logins_total",
+		"Logins by result (success, unauthorized, error).", "result")
+)
+
+func init() {
+	metrics.Default.Register(httpRequestsMetric, httpLatencyMetric, loginsMetric)
+	metrics.Default.Register(breakerCollectors(SpiderBreaker)...)
+	metrics.Default.Register(poolCollectors(exec)...)
+	metrics.Default.Register(accountCollector(AccountService))
+}
+
+// metricMethods 是作为标签的请求方法，其他方法记为 OTHER
+var metricMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodDelete: true, http.MethodOptions: true, "PROPFIND": true, "REPORT": true,
+}
+
+// statusRecorder 记录响应的状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// instrument 记录经过 next 的请求的指标、访问日志和追踪的 span，路由由 mux 匹配得到，没有匹配的请求记为 unmatched
+//
+// 请求携带 traceparent 时 span 作为上游 span 的子 span。
+// 访问日志只记录路由而不记录路径和查询参数，订阅日历的路径中包含密钥。
+func instrument(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := "unmatched"
+		if _, pattern := mux.Handler(r); pattern != "" {
+			route = pattern
+		}
+		method := r.Method
+		if !metricMethods[method] {
+			method = "OTHER"
+		}
+		ctx := r.Context()
+		if parent, ok := tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader)); ok {
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, parent)
+		}
+		ctx, span := tracing.Start(ctx, r.Method+" "+route, tracing.WithKind(tracing.KindServer),
+			tracing.WithAttributes(
+				tracing.Attribute{Key: "http.request.method", Value: r.Method},
+				tracing.Attribute{Key: "http.route", Value: route},
+			))
+		defer span.End()
+		r = r.WithContext(ctx)
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		span.SetAttribute("http.response.status_code", recorder.status)
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(tracing.StatusError, http.StatusText(recorder.status))
+		}
+		elapsed := time.Since(start)
+		httpLatencyMetric.Observe(elapsed.Seconds(), route, method)
+		httpRequestsMetric.Inc(route, method, strconv.Itoa(recorder.status))
+		level := slog.LevelInfo
+		if recorder.status >= http.StatusInternalServerError {
+			level = slog.LevelWarn
+		}
+		slog.Log(r.Context(), level, "request", "method", r.Method, "route", route,
+			"status", recorder.status, "duration_ms", elapsed.Milliseconds())
+	})
+}
+
+// breakerCollectors 导出熔断器的状态，状态的取值与 feign.BreakerState 相同：0 关闭，1 打开，2 半开
+func breakerCollectors(breakers breakerSnapshotter) []metrics.Collector {
+	each := func(emit func(float64, ...string), value func(feign.BreakerSnapshot) float64) {
+		snapshots := breakers.Snapshot()
+		endpoints := make([]string, 0, len(snapshots))
+		for endpoint := range snapshots {
+			endpoints = append(endpoints, endpoint)
+		}
+		sort.Strings(endpoints)
+		for _, endpoint := range endpoints {
+			emit(value(snapshots[endpoint]), endpoint)
+		}
+	}
+	return []metrics.Collector{
+		metrics.NewGaugeFunc("proxy_spider_breaker_state",
+			"Circuit breaker state by spider endpoint (0 closed, 1 open, 2 half-open).", []string{"endpoint"},
+			func(emit func(float64, ...string)) {
+				each(emit, func(s feign.BreakerSnapshot) float64 { return float64(s.State) })
+			}),
+		metrics.NewCounterFunc("proxy_spider_breaker_trips_total",
+			"Times the circuit breaker opened by spider endpoint.", []string{"endpoint"},
+			func(emit func(float64, ...string)) {
+				each(emit, func(s feign.BreakerSnapshot) float64 { return float64(s.Trips) })
+			}),
+	}
+}
+
+// poolStats 返回协程池的状态
+type poolStats interface {
+	Stats() executor.PoolStats
+}
+
+// poolCollectors 导出更新缓存的协程池的状态
+func poolCollectors(pool poolStats) []metrics.Collector {
+	gauge := func(name string, help string, value func(executor.PoolStats) int) metrics.Collector {
+		return metrics.NewGaugeFunc(name, help, nil, func(emit func(float64, ...string)) {
+			emit(float64(value(pool.Stats())))
+		})
+	}
+	counter := func(name string, help string, value func(executor.PoolStats) int) metrics.Collector {
+		return metrics.NewCounterFunc(name, help, nil, func(emit func(float64, ...string)) {
+			emit(float64(value(pool.Stats())))
+		})
+	}
+	return []metrics.Collector{
+		gauge("proxy_worker_pool_workers", "Workers in the cache refresh pool.",
+			func(s executor.PoolStats) int { return s.Workers }),
+		gauge("proxy_worker_pool_queued", "Cache refresh tasks waiting for a worker.",
+			func(s executor.PoolStats) int { return s.Queued }),
+		gauge("proxy_worker_pool_busy", "Workers currently running a cache refresh task.",
+			func(s executor.PoolStats) int { return s.Busy }),
+		metrics.NewGaugeFunc("proxy_worker_pool_queued_by_priority", "Cache refresh tasks waiting for a worker by priority.",
+			[]string{"priority"}, func(emit func(float64, ...string)) {
+				for priority, queued := range pool.Stats().QueuedByPriority {
+					emit(float64(queued), executor.Priority(priority).String())
+				}
+			}),
+		counter("proxy_worker_pool_rejected_total", "Cache refresh tasks rejected because the queue was full.",
+			func(s executor.PoolStats) int { return s.Rejected }),
+		counter("proxy_worker_pool_shed_total", "Queued cache refresh tasks dropped for newer or more urgent tasks.",
+			func(s executor.PoolStats) int { return s.Shed }),
+		counter("proxy_worker_pool_panics_total", "Cache refresh tasks that panicked.",
+			func(s executor.PoolStats) int { return s.Panics }),
+	}
+}
+
+// accountCounter 统计每种状态的账户数量
+type accountCounter interface {
+	CountByStatus() map[account2.Status]int
+}
+
+// accountCollector 导出每种状态的账户数量，没有账户的状态输出 0
+func accountCollector(accounts accountCounter) metrics.Collector {
+	return metrics.NewGaugeFunc("proxy_accounts", "Accounts by status.", []string{"status"},
+		func(emit func(float64, ...string)) {
+			counts := accounts.CountByStatus()
+			for _, status := range []account2.Status{account2.Normal, account2.Banned} {
+				emit(float64(counts[status]), strings.ToLower(status.String()))
+			}
+		})
+}
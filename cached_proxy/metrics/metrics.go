@@ -0,0 +1,390 @@
+// Package metrics 以 Prometheus 文本格式导出监控指标
+//
+// 只实现了服务需要的计数器、仪表盘和直方图，没有依赖 Prometheus 的客户端库。
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Collector 是一组同名的指标，抓取时输出所有样本
+type Collector interface {
+	// Name 返回指标的名称
+	Name() string
+	// write 按照文本格式输出 HELP、TYPE 和所有样本
+	write(w *bufio.Writer)
+}
+
+// Registry 保存所有注册的指标
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]Collector
+}
+
+// NewRegistry 创建指标的注册表
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]Collector{}}
+}
+
+// Default 是默认的注册表，/metrics 输出其中的指标
+var Default = NewRegistry()
+
+// Register 注册指标，名称重复时 panic，与 Prometheus 客户端库的 MustRegister 相同
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range collectors {
+		if _, found := r.collectors[c.Name()]; found {
+			panic("metrics: duplicate metric " + c.Name())
+		}
+		r.collectors[c.Name()] = c
+	}
+}
+
+// WriteTo 按照名称顺序输出所有指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].Name() < collectors[j].Name() })
+
+	counter := &countingWriter{w: w}
+	buf := bufio.NewWriter(counter)
+	for _, c := range collectors {
+		c.write(buf)
+	}
+	err := buf.Flush()
+	return counter.n, err
+}
+
+// Handler 返回输出 r 中所有指标的 HTTP 处理器
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// desc 是指标的名称、说明和标签
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) Name() string {
+	return d.name
+}
+
+// writeHeader 输出 HELP 和 TYPE
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ)
+}
+
+// writeSample 输出一个样本，extra 是附加在最后的标签，例如直方图的 le
+func (d *desc) writeSample(w *bufio.Writer, suffix string, values []string, value float64, extra ...string) {
+	w.WriteString(d.name + suffix)
+	if len(values) > 0 || len(extra) > 0 {
+		w.WriteByte('{')
+		for i, label := range d.labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabel(values[i]) + `"`)
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			if len(d.labels) > 0 || i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extra[i] + `="` + escapeLabel(extra[i+1]) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// vec 按照标签值保存样本，K 是样本的类型
+type vec[K any] struct {
+	desc
+	mu      sync.Mutex
+	samples map[string]*sample[K]
+	create  func() *K
+}
+
+type sample[K any] struct {
+	values []string
+	value  *K
+}
+
+// with 返回标签值对应的样本，不存在时创建，标签值的数量与标签不一致时 panic
+func (v *vec[K]) with(values []string) *K {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, found := v.samples[key]
+	if !found {
+		s = &sample[K]{values: append([]string(nil), values...), value: v.create()}
+		v.samples[key] = s
+	}
+	return s.value
+}
+
+// sorted 返回按标签值排序的样本，使输出稳定
+func (v *vec[K]) sorted() []*sample[K] {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.samples))
+	for key := range v.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	samples := make([]*sample[K], len(keys))
+	for i, key := range keys {
+		samples[i] = v.samples[key]
+	}
+	v.mu.Unlock()
+	return samples
+}
+
+func newVec[K any](name string, help string, typ string, labels []string, create func() *K) vec[K] {
+	return vec[K]{desc: desc{name: name, help: help, typ: typ, labels: labels}, samples: map[string]*sample[K]{}, create: create}
+}
+
+// value 是并发安全的浮点数
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) add(delta float64) {
+	v.mu.Lock()
+	v.v += delta
+	v.mu.Unlock()
+}
+
+func (v *value) set(x float64) {
+	v.mu.Lock()
+	v.v = x
+	v.mu.Unlock()
+}
+
+func (v *value) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+// CounterVec 是按标签区分的计数器
+type CounterVec struct {
+	vec[value]
+}
+
+// NewCounterVec 创建计数器
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	return &CounterVec{newVec(name, help, "counter", labels, func() *value { return &value{} })}
+}
+
+// Inc 将标签值对应的计数器加一
+func (c *CounterVec) Inc(values ...string) {
+	c.with(values).add(1)
+}
+
+// Add 将标签值对应的计数器加上 delta，delta 不能为负数
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.with(values).add(delta)
+}
+
+// Get 返回标签值对应的计数
+func (c *CounterVec) Get(values ...string) float64 {
+	return c.with(values).get()
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	for _, s := range c.sorted() {
+		c.writeSample(w, "", s.values, s.value.get())
+	}
+}
+
+// GaugeVec 是按标签区分的仪表盘
+type GaugeVec struct {
+	vec[value]
+}
+
+// NewGaugeVec 创建仪表盘
+func NewGaugeVec(name string, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{newVec(name, help, "gauge", labels, func() *value { return &value{} })}
+}
+
+// Set 设置标签值对应的值
+func (g *GaugeVec) Set(x float64, values ...string) {
+	g.with(values).set(x)
+}
+
+// Add 将标签值对应的值加上 delta
+func (g *GaugeVec) Add(delta float64, values ...string) {
+	g.with(values).add(delta)
+}
+
+// Get 返回标签值对应的值
+func (g *GaugeVec) Get(values ...string) float64 {
+	return g.with(values).get()
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	for _, s := range g.sorted() {
+		g.writeSample(w, "", s.values, s.value.get())
+	}
+}
+
+// DefBuckets 是默认的直方图分桶，单位为秒，覆盖从几毫秒的缓存命中到几十秒的教务系统请求
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// histogram 是单个直方图，counts[i] 是不大于 buckets[i] 的观测数，不累加
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec 是按标签区分的直方图
+type HistogramVec struct {
+	vec[histogram]
+	buckets []float64
+}
+
+// NewHistogramVec 创建直方图，buckets 为空时使用 DefBuckets
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{buckets: buckets}
+	h.vec = newVec(name, help, "histogram", labels, func() *histogram {
+		return &histogram{counts: make([]uint64, len(buckets))}
+	})
+	return h
+}
+
+// Observe 记录一次观测
+func (h *HistogramVec) Observe(x float64, values ...string) {
+	s := h.with(values)
+	i := sort.SearchFloat64s(h.buckets, x)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += x
+}
+
+// ObserveSince 记录从 start 到现在经过的秒数
+func (h *HistogramVec) ObserveSince(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+// Count 返回标签值对应的观测数
+func (h *HistogramVec) Count(values ...string) uint64 {
+	s := h.with(values)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	for _, s := range h.sorted() {
+		s.value.mu.Lock()
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += s.value.counts[i]
+			h.writeSample(w, "_bucket", s.values, float64(cumulative), "le", formatFloat(bound))
+		}
+		h.writeSample(w, "_bucket", s.values, float64(s.value.count), "le", "+Inf")
+		h.writeSample(w, "_sum", s.values, s.value.sum)
+		h.writeSample(w, "_count", s.values, float64(s.value.count))
+		s.value.mu.Unlock()
+	}
+}
+
+// Func 是抓取时才计算样本的指标，用于队列长度、熔断器状态等已经由其他组件维护的状态
+type Func struct {
+	desc
+	collect func(emit func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc 创建抓取时计算的仪表盘，collect 对每个样本调用一次 emit
+func NewGaugeFunc(name string, help string, labels []string, collect func(emit func(value float64, labelValues ...string))) *Func {
+	return &Func{desc: desc{name: name, help: help, typ: "gauge", labels: labels}, collect: collect}
+}
+
+// NewCounterFunc 创建抓取时计算的计数器
+func NewCounterFunc(name string, help string, labels []string, collect func(emit func(value float64, labelValues ...string))) *Func {
+	return &Func{desc: desc{name: name, help: help, typ: "counter", labels: labels}, collect: collect}
+}
+
+func (f *Func) write(w *bufio.Writer) {
+	f.writeHeader(w)
+	f.collect(func(value float64, labelValues ...string) {
+		if len(labelValues) != len(f.labels) {
+			panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+		}
+		f.writeSample(w, "", labelValues, value)
+	})
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	registry := NewRegistry()
+	requests := NewCounterVec("test_requests_total", "Requests.", "route", "code")
+	inflight := NewGaugeVec("test_inflight", "In-flight requests.")
+	latency := NewHistogramVec("test_latency_seconds", "Latency.", []float64{1, 0.1}, "route")
+	queue := NewGaugeFunc("test_queue", "Queue.\nDepth", []string{"pool"}, func(emit func(float64, ...string)) {
+		emit(3, `a"b\c`)
+	})
+	registry.Register(requests, inflight, latency, queue)
+
+	requests.Inc("/info", "200")
+	requests.Add(2, "/info", "200")
+	requests.Inc("/exams", "503")
+	inflight.Set(2)
+	inflight.Add(-1)
+	latency.Observe(0.05, "/info")
+	latency.Observe(0.5, "/info")
+	latency.Observe(5, "/info")
+
+	var buf strings.Builder
+	if _, err := registry.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_inflight In-flight requests.
+# TYPE test_inflight gauge
+test_inflight 1
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/info",le="0.1"} 1
+test_latency_seconds_bucket{route="/info",le="1"} 2
+test_latency_seconds_bucket{route="/info",le="+Inf"} 3
+test_latency_seconds_sum{route="/info"} 5.55
+test_latency_seconds_count{route="/info"} 3
+# HELP test_queue Queue.\nDepth
+# TYPE test_queue gauge
+test_queue{pool="a\"b\\c"} 3
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{route="/exams",code="503"} 1
+test_requests_total{route="/info",code="200"} 3
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+	if requests.Get("/info", "200") != 3 || latency.Count("/info") != 3 {
+		t.Error("unexpected values")
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(NewCounterVec("test_total", "Test."))
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate metric should panic")
+		}
+	}()
+	registry.Register(NewGaugeVec("test_total", "Test."))
+}
+
+func TestCounterVec_LabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("wrong number of label values should panic")
+		}
+	}()
+	NewCounterVec("test_total", "Test.", "a", "b").Inc("x")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(NewCounterVec("test_total", "Test."))
+	w := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "# TYPE test_total counter") {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	account2 "cached_proxy/account"
+	"cached_proxy/executor"
+	"cached_proxy/feign"
+	"cached_proxy/metrics"
+	"cached_proxy/tracing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics_test", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/metrics_test/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	handler := instrument(mux, mux)
+
+	tests := []struct {
+		method string
+		path   string
+		route  string
+		label  string
+		code   string
+	}{
+		{http.MethodGet, "/metrics_test", "/metrics_test", http.MethodGet, "200"},
+		{"PROPFIND", "/metrics_test/a/b", "/metrics_test/", "PROPFIND", "202"},
+		{"BREW", "/metrics_test", "/metrics_test", "OTHER", "200"},
+		{http.MethodGet, "/metrics_missing", "unmatched", http.MethodGet, "404"},
+	}
+	for _, tt := range tests {
+		before := httpRequestsMetric.Get(tt.route, tt.label, tt.code)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		if got := httpRequestsMetric.Get(tt.route, tt.label, tt.code) - before; got != 1 {
+			t.Errorf("%s %s: expected one request counted as %s %s %s, got %v", tt.method, tt.path, tt.route, tt.label, tt.code, got)
+		}
+	}
+	if httpLatencyMetric.Count("/metrics_test/", "PROPFIND") == 0 {
+		t.Error("latency should be observed")
+	}
+}
+
+func TestInstrument_Tracing(t *testing.T) {
+	recorder := &tracing.Recorder{}
+	old := tracing.Default()
+	tracing.SetDefault(tracing.NewTracer(recorder))
+	defer tracing.SetDefault(old)
+
+	var handlerSpan tracing.SpanContext
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tracing_test", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = tracing.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/tracing_test?token=secret", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	instrument(mux, mux).ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := recorder.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected one server span, got %v", spans)
+	}
+	span := spans[0]
+	if span.Name != "GET /tracing_test" || span.Kind != tracing.KindServer || span.SpanContext != handlerSpan {
+		t.Errorf("unexpected span %+v", span)
+	}
+	if span.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.String() != "00f067aa0ba902b7" {
+		t.Errorf("span should continue the incoming trace, got %+v", span.SpanContext)
+	}
+	if span.Attribute("http.route") != "/tracing_test" || span.Attribute("http.response.status_code") != http.StatusBadGateway || span.Status != tracing.StatusError {
+		t.Errorf("unexpected attributes %v status %v", span.Attributes, span.Status)
+	}
+}
+
+// fakePool 返回固定的协程池状态
+type fakePool executor.PoolStats
+
+func (f fakePool) Stats() executor.PoolStats {
+	return executor.PoolStats(f)
+}
+
+// fakeAccounts 返回固定的账户数量
+type fakeAccounts map[account2.Status]int
+
+func (f fakeAccounts) CountByStatus() map[account2.Status]int {
+	return f
+}
+
+func TestCollectors(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Register(breakerCollectors(fakeBreakers{
+		"login": {State: feign.BreakerClosed},
+		"exams": {State: feign.BreakerOpen, Trips: 3},
+	})...)
+	registry.Register(poolCollectors(fakePool{Workers: 10, Queued: 4, Busy: 10, QueuedByPriority: [2]int{3, 1}, Rejected: 2, Shed: 5})...)
+	registry.Register(accountCollector(fakeAccounts{account2.Normal: 5}))
+
+	var buf strings.Builder
+	if _, err := registry.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`proxy_spider_breaker_state{endpoint="exams"} 1`,
+		`proxy_spider_breaker_state{endpoint="login"} 0`,
+		`proxy_spider_breaker_trips_total{endpoint="exams"} 3`,
+		`proxy_worker_pool_workers 10`,
+		`proxy_worker_pool_queued 4`,
+		`proxy_worker_pool_busy 10`,
+		`proxy_worker_pool_queued_by_priority{priority="background"} 3`,
+		`proxy_worker_pool_queued_by_priority{priority="interactive"} 1`,
+		`proxy_worker_pool_rejected_total 2`,
+		`proxy_worker_pool_shed_total 5`,
+		`proxy_worker_pool_panics_total 0`,
+		`proxy_accounts{status="normal"} 5`,
+		`proxy_accounts{status="banned"} 0`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("missing %q in output:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"cached_proxy/codec"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize 小于该长度的响应不压缩，压缩节省的流量不足以抵消开销
+const compressMinSize = 1024
+
+// 支持的压缩方式，按照 Accept-Encoding 的 q 值选择，q 值相同时优先使用压缩率更高的 br
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// responseFormat 是一种响应格式，响应的 Content-Type 使用 mediaTypes 中与 Accept 匹配的一项
+type responseFormat struct {
+	mediaTypes []string
+	marshal    func(v any) ([]byte, error)
+}
+
+// responseFormats 是支持的响应格式，第一个为默认格式
+var responseFormats = []responseFormat{
+	{[]string{"application/json"}, marshalJSON},
+	{[]string{codec.MsgpackContentType, "application/x-msgpack", "application/vnd.msgpack"}, codec.MarshalMsgpack},
+	{[]string{codec.CBORContentType}, codec.MarshalCBOR},
+}
+
+func marshalJSON(v any) ([]byte, error) {
+	buf := bytes.Buffer{}
+	err := json.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+// acceptItem 是 Accept 或 Accept-Encoding 中的一项
+type acceptItem struct {
+	value string
+	q     float64
+}
+
+// parseAccept 解析 Accept 类请求头，忽略 q 以外的参数
+func parseAccept(header string) []acceptItem {
+	var items []acceptItem
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		item := acceptItem{value: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		if item.value == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					item.q = q
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// negotiateFormat 根据 Accept 选择 q 值最高的响应格式，通配符对应 JSON，没有可接受的格式时也使用 JSON
+func negotiateFormat(header string) (responseFormat, string) {
+	best, bestType, bestQ := responseFormats[0], responseFormats[0].mediaTypes[0], 0.0
+	for _, item := range parseAccept(header) {
+		if item.q <= bestQ {
+			continue
+		}
+		if item.value == "*/*" || item.value == "application/*" {
+			best, bestType, bestQ = responseFormats[0], responseFormats[0].mediaTypes[0], item.q
+			continue
+		}
+		for _, format := range responseFormats {
+			for _, mediaType := range format.mediaTypes {
+				if item.value == mediaType {
+					best, bestType, bestQ = format, mediaType, item.q
+				}
+			}
+		}
+	}
+	return best, bestType
+}
+
+// negotiateEncoding 根据 Accept-Encoding 选择 q 值最高的压缩方式，没有可接受的压缩方式时返回空字符串
+//
+// 没有单独列出的压缩方式使用通配符 * 的 q 值，q 值相同时优先使用 br。
+func negotiateEncoding(header string) string {
+	brQ, gzipQ, wildcardQ := -1.0, -1.0, 0.0
+	for _, item := range parseAccept(header) {
+		switch item.value {
+		case "br":
+			brQ = item.q
+		case "gzip", "x-gzip":
+			gzipQ = item.q
+		case "*":
+			wildcardQ = item.q
+		}
+	}
+	if brQ < 0 {
+		brQ = wildcardQ
+	}
+	if gzipQ < 0 {
+		gzipQ = wildcardQ
+	}
+	switch {
+	case brQ > 0 && brQ >= gzipQ:
+		return encodingBrotli
+	case gzipQ > 0:
+		return encodingGzip
+	}
+	return ""
+}
+
+// compress 使用指定的方式压缩 body
+func compress(encoding string, body []byte) []byte {
+	compressed := bytes.Buffer{}
+	var writer io.WriteCloser
+	switch encoding {
+	case encodingBrotli:
+		writer = brotli.NewWriter(&compressed)
+	case encodingGzip:
+		writer = gzip.NewWriter(&compressed)
+	default:
+		return body
+	}
+	// 写入内存不会失败
+	_, _ = writer.Write(body)
+	_ = writer.Close()
+	return compressed.Bytes()
+}
+
+// dataETag 根据响应的内容生成弱 ETag，不同的响应格式和压缩方式共用同一个 ETag
+func dataETag(data any) string {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(content)
+	return `W/"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// etagMatches 按照弱比较判断 If-None-Match 是否包含 etag
+func etagMatches(header string, etag string) bool {
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified 判断条件请求是否命中，If-None-Match 存在时忽略 If-Modified-Since
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return etag != "" && etagMatches(header, etag)
+	}
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !modified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// writeCached 输出缓存的数据，支持内容协商、条件请求和 br、gzip 压缩
+//
+// etag 和 modified 分别为空和零值时不输出对应的响应头。只有数据有效时才返回 304，
+// 数据过期时仍然返回完整的 203 响应，使客户端知道数据可能不是最新的。
+func writeCached(w http.ResponseWriter, r *http.Request, status int, resp any, etag string, modified time.Time) {
+	header := w.Header()
+	header.Set("Vary", "Accept, Accept-Encoding, Authorization")
+	header.Set("Cache-Control", "private, no-cache")
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if status == http.StatusOK && notModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	format, contentType := negotiateFormat(r.Header.Get("Accept"))
+	body, err := format.marshal(resp)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to encode response as %s: %w", contentType, err))
+		return
+	}
+	header.Set("Content-Type", contentType)
+	if encoding := negotiateEncoding(r.Header.Get("Accept-Encoding")); len(body) >= compressMinSize && encoding != "" {
+		body = compress(encoding, body)
+		header.Set("Content-Encoding", encoding)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if _, err = w.Write(body); err != nil {
+		slog.WarnContext(r.Context(), "failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"cached_proxy/codec"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/html", "application/json"},
+		{"application/msgpack", codec.MsgpackContentType},
+		{"application/x-msgpack", "application/x-msgpack"},
+		{"application/cbor, application/json;q=0.9", codec.CBORContentType},
+		{"application/cbor;q=0.5, application/msgpack;q=0.8", codec.MsgpackContentType},
+		{"application/cbor;q=0.5, */*", "application/json"},
+		{"application/cbor;q=0", "application/json"},
+	}
+	for _, tt := range tests {
+		if _, got := negotiateFormat(tt.accept); got != tt.want {
+			t.Errorf("negotiateFormat(%q) = %s, want %s", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"x-gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br, gzip;q=0.5", "br"},
+		{"br;q=1, gzip;q=0.1", "br"},
+		{"br;q=0, gzip;q=0.1", "gzip"},
+		{"gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.5, gzip", "gzip"},
+		{"*, br;q=0", "gzip"},
+		{"*, gzip;q=0, br;q=0", ""},
+		{"br, *;q=0", "br"},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestWriteCached(t *testing.T) {
+	data := map[string]string{"name": strings.Repeat("成绩", 300)}
+	resp := map[string]any{"code": 1, "data": data}
+	etag := dataETag(data)
+	modified := time.Date(2025, 3, 1, 8, 0, 0, 500, time.UTC)
+	request := func(headers map[string]string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/scores", nil)
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+		return r
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(nil), http.StatusOK, resp, etag, modified)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+		}
+		if w.Header().Get("ETag") != etag || w.Header().Get("Last-Modified") != "Sat, 01 Mar 2025 08:00:00 GMT" {
+			t.Errorf("unexpected validators %v", w.Header())
+		}
+		if w.Header().Get("Cache-Control") != "private, no-cache" || w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("unexpected headers %v", w.Header())
+		}
+		var got map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got["code"] != 1.0 {
+			t.Errorf("unexpected body %s", w.Body.String())
+		}
+	})
+
+	t.Run("IfNoneMatch", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"If-None-Match": `"other", ` + strings.TrimPrefix(etag, "W/")}), http.StatusOK, resp, etag, modified)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+			t.Errorf("expected 304, got %d %v", w.Code, w.Header())
+		}
+		w = httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Sat, 01 Mar 2025 09:00:00 GMT"}), http.StatusOK, resp, etag, modified)
+		if w.Code != http.StatusOK {
+			t.Errorf("If-Modified-Since should be ignored with If-None-Match, got %d", w.Code)
+		}
+	})
+
+	t.Run("IfModifiedSince", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"If-Modified-Since": "Sat, 01 Mar 2025 08:00:00 GMT"}), http.StatusOK, resp, etag, modified)
+		if w.Code != http.StatusNotModified {
+			t.Errorf("expected 304, got %d", w.Code)
+		}
+		w = httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"If-Modified-Since": "Sat, 01 Mar 2025 07:59:59 GMT"}), http.StatusOK, resp, etag, modified)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", w.Code)
+		}
+	})
+
+	t.Run("Stale", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"If-None-Match": etag}), http.StatusNonAuthoritativeInfo, resp, etag, modified)
+		if w.Code != http.StatusNonAuthoritativeInfo || w.Body.Len() == 0 {
+			t.Errorf("stale data should not be 304, got %d", w.Code)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"If-None-Match": "*"}), http.StatusNonAuthoritativeInfo, map[string]any{"data": nil}, "", time.Time{})
+		if w.Code != http.StatusNonAuthoritativeInfo || w.Header().Get("ETag") != "" || w.Header().Get("Last-Modified") != "" {
+			t.Errorf("unexpected response %d %v", w.Code, w.Header())
+		}
+	})
+
+	t.Run("Gzip", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"Accept-Encoding": "gzip, br;q=0.5"}), http.StatusOK, resp, etag, modified)
+		if w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected gzip, got %v", w.Header())
+		}
+		reader, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(reader)
+		want, _ := marshalJSON(resp)
+		if !bytes.Equal(body, want) {
+			t.Errorf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("Brotli", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"Accept-Encoding": "gzip, br"}), http.StatusOK, resp, etag, modified)
+		if w.Header().Get("Content-Encoding") != "br" || w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) {
+			t.Fatalf("expected br, got %v", w.Header())
+		}
+		body, _ := io.ReadAll(brotli.NewReader(w.Body))
+		want, _ := marshalJSON(resp)
+		if !bytes.Equal(body, want) {
+			t.Errorf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("Identity", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"Accept-Encoding": "identity"}), http.StatusOK, resp, etag, modified)
+		want, _ := marshalJSON(resp)
+		if w.Header().Get("Content-Encoding") != "" || !bytes.Equal(w.Body.Bytes(), want) {
+			t.Errorf("expected uncompressed response, got %v", w.Header())
+		}
+	})
+
+	t.Run("MessagePack", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writeCached(w, request(map[string]string{"Accept": "application/msgpack"}), http.StatusOK, resp, etag, modified)
+		want, _ := codec.MarshalMsgpack(resp)
+		if w.Header().Get("Content-Type") != codec.MsgpackContentType || !bytes.Equal(w.Body.Bytes(), want) {
+			t.Errorf("unexpected response %v", w.Header())
+		}
+	})
+}
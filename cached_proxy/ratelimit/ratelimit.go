@@ -0,0 +1,110 @@
+// Package ratelimit 提供令牌桶限流
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit 是令牌桶的速率和容量，Rate 不大于 0 时不限流
+type Limit struct {
+	Rate  float64 // 每秒产生的令牌数
+	Burst int     // 桶的容量，即允许的突发请求数
+}
+
+// Unlimited 表示不限流
+var Unlimited = Limit{}
+
+// bucket 是一个令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take 在 now 时刻取一个令牌，失败时返回需要等待的时间
+func (b *bucket) take(limit Limit, now time.Time) (bool, time.Duration) {
+	burst := math.Max(float64(limit.Burst), 1)
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// full 判断令牌桶在 now 时刻是否已经装满，装满的令牌桶与新建的没有区别，可以删除
+func (b *bucket) full(limit Limit, now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= math.Max(float64(limit.Burst), 1)
+}
+
+// Limiter 为每个键维护一个令牌桶，空键也是一个普通的键
+type Limiter struct {
+	limit   Limit
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// sweepSize 令牌桶超过该数量时，清理已经装满的令牌桶
+const sweepSize = 4096
+
+// NewLimiter 创建限流器
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{limit: limit, now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// Allow 为 key 取一个令牌，失败时返回需要等待的时间
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil || l.limit.Rate <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, found := l.buckets[key]
+	if !found {
+		if len(l.buckets) >= sweepSize {
+			l.sweep(now)
+		}
+		b = &bucket{tokens: math.Max(float64(l.limit.Burst), 1), last: now}
+		l.buckets[key] = b
+	}
+	return b.take(l.limit, now)
+}
+
+// sweep 删除已经装满的令牌桶，调用前需要持有锁
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.full(l.limit, now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ParseLimit 解析 "速率/容量" 格式的限流配置，例如 "20/40" 表示每秒 20 个请求，最多突发 40 个，
+// 省略容量时容量等于速率，"0" 和 "off" 表示不限流
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "0" || strings.EqualFold(s, "off") {
+		return Unlimited, nil
+	}
+	rateText, burstText, hasBurst := strings.Cut(s, "/")
+	rate, err := strconv.ParseFloat(strings.TrimSpace(rateText), 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		return Unlimited, fmt.Errorf("invalid rate limit %q: rate must be a positive number", s)
+	}
+	burst := int(math.Ceil(rate))
+	if hasBurst {
+		burst, err = strconv.Atoi(strings.TrimSpace(burstText))
+		if err != nil || burst <= 0 {
+			return Unlimited, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	return Limit{Rate: rate, Burst: burst}, nil
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(Limit{Rate: 2, Burst: 3})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("request %d within burst should be allowed", i)
+		}
+	}
+	ok, wait := limiter.Allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms, got %v %v", ok, wait)
+	}
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Error("keys should have separate buckets")
+	}
+
+	now = now.Add(250 * time.Millisecond)
+	if ok, wait := limiter.Allow("a"); ok || wait != 250*time.Millisecond {
+		t.Errorf("expected to wait 250ms, got %v %v", ok, wait)
+	}
+	now = now.Add(250 * time.Millisecond)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Error("token should be refilled")
+	}
+	// 令牌数不超过桶的容量
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		limiter.Allow("a")
+	}
+	if ok, _ := limiter.Allow("a"); ok {
+		t.Error("tokens should not exceed burst")
+	}
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	var nilLimiter *Limiter
+	for _, limiter := range []*Limiter{nilLimiter, NewLimiter(Unlimited)} {
+		for i := 0; i < 100; i++ {
+			if ok, _ := limiter.Allow("a"); !ok {
+				t.Fatal("unlimited limiter should allow all requests")
+			}
+		}
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(Limit{Rate: 1, Burst: 1})
+	limiter.now = func() time.Time { return now }
+	for i := 0; i < sweepSize; i++ {
+		limiter.Allow(fmt.Sprint(i))
+	}
+	limiter.Allow("0")
+	now = now.Add(time.Second)
+	limiter.Allow("new")
+	if len(limiter.buckets) != 1 {
+		t.Errorf("full buckets should be swept, got %d buckets", len(limiter.buckets))
+	}
+	if ok, _ := limiter.Allow("new"); ok {
+		t.Error("bucket of the new key should be kept")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Limit
+		wantErr bool
+	}{
+		{"20/40", Limit{Rate: 20, Burst: 40}, false},
+		{" 0.5 / 5 ", Limit{Rate: 0.5, Burst: 5}, false},
+		{"2.5", Limit{Rate: 2.5, Burst: 3}, false},
+		{"0", Unlimited, false},
+		{"off", Unlimited, false},
+		{"", Unlimited, true},
+		{"-1/5", Unlimited, true},
+		{"abc", Unlimited, true},
+		{"5/0", Unlimited, true},
+		{"5/x", Unlimited, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseLimit(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
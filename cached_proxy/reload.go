@@ -0,0 +1,54 @@
+package main
+
+import (
+	"cached_proxy/executor"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// 这里是运行时调整配置，收到 SIGHUP 时重新读取可以在不重启的情况下修改的配置
+
+// resizer 是可以在运行时调整协程数量的协程池
+type resizer interface {
+	Resize(workers int)
+	Stats() executor.PoolStats
+}
+
+// reloadWorkers 从 path 读取协程数量并调整协程池，文件的内容是一个正整数
+func reloadWorkers(path string, pool resizer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	workers, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || workers <= 0 {
+		return fmt.Errorf("invalid worker count %q", strings.TrimSpace(string(data)))
+	}
+	previous := pool.Stats().Workers
+	pool.Resize(workers)
+	slog.Info("refresh workers resized", "from", previous, "to", workers)
+	return nil
+}
+
+// watchReload 每次收到 SIGHUP 时重新读取协程数量，直到 ctx 结束
+func watchReload(ctx context.Context, path string, pool resizer) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := reloadWorkers(path, pool); err != nil {
+				slog.Warn("failed to reload refresh workers", "path", path, "error", err)
+			}
+		}
+	}
+}
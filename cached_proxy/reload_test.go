@@ -0,0 +1,39 @@
+package main
+
+import (
+	"cached_proxy/executor"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{"Valid", "20\n", 20, false},
+		{"Invalid", "many", 4, true},
+		{"Zero", "0", 4, true},
+		{"Missing", "", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "refresh_workers")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pool := executor.NewPriorityPool(executor.PoolConfig{Workers: 4, QueueSize: 10})
+			if err := reloadWorkers(path, pool); (err != nil) != tt.wantErr {
+				t.Fatalf("reloadWorkers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := pool.Stats().Workers; got != tt.want {
+				t.Errorf("workers = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,15 +1,48 @@
 package repo
 
 import (
+	"bytes"
 	"encoding/gob"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	path2 "path"
+	"sync"
+	"sync/atomic"
 )
 
+// FileRepo 是写回文件的存储，每次修改后将全部数据写回文件
 type FileRepo[K interface{ string }, V any] struct {
 	memRepository MemRepo[K, V]
 	path          string
+	writeMu       sync.Mutex  // 保证同一时间只有一次写回
+	dirty         atomic.Bool // 内存中有没有写回文件的修改
+}
+
+// Flusher 是可以将数据写回持久存储的仓库
+type Flusher interface {
+	Flush() error
+}
+
+// fileRepos 是创建过的所有 FileRepo，用于退出前统一写回
+var fileRepos struct {
+	mu    sync.Mutex
+	repos []Flusher
+}
+
+// FlushAll 写回所有 FileRepo 中没有成功写回的修改，返回所有失败的错误
+func FlushAll() error {
+	fileRepos.mu.Lock()
+	repos := append([]Flusher(nil), fileRepos.repos...)
+	fileRepos.mu.Unlock()
+	var errs []error
+	for _, repo := range repos {
+		if err := repo.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // NewFileRepos 创建文件存储
@@ -21,7 +54,8 @@ func NewFileRepos[K interface{ string }, V any](path string) *FileRepo[K, V] {
 	if _, err := os.Stat(path); !os.IsNotExist(err) {
 		err := repo.loadAll()
 		if err != nil {
-			log.Fatalf("Failed to load data from file %s: %v", path, err)
+			slog.Error("failed to load data from file", "path", path, "error", err)
+			os.Exit(1)
 		}
 	} else {
 		// 如果文件不存在，检查目录是否存在
@@ -32,32 +66,46 @@ func NewFileRepos[K interface{ string }, V any](path string) *FileRepo[K, V] {
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
 			err := os.MkdirAll(dir, 0755)
 			if err != nil {
-				log.Fatalf("Failed to create directory %s: %v", dir, err)
+				slog.Error("failed to create directory", "path", dir, "error", err)
+				os.Exit(1)
 			}
 		}
 	}
+	fileRepos.mu.Lock()
+	fileRepos.repos = append(fileRepos.repos, repo)
+	fileRepos.mu.Unlock()
 	return repo
 }
 
-// writeBack 将数据写回文件
-func (f *FileRepo[K, V]) writeBack(_ K, _ V) error {
-	log.Print("writeBack to file")
-	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+// Flush 在有修改时将数据写回文件，写回失败的修改会在下一次 Flush 时重试
+func (f *FileRepo[K, V]) Flush() error {
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if !f.dirty.Swap(false) {
+		return nil
+	}
+	if err := f.writeBack(); err != nil {
+		f.dirty.Store(true)
+		return fmt.Errorf("write back %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// writeBack 将数据写到临时文件再替换原文件，写到一半退出时不会损坏原文件
+func (f *FileRepo[K, V]) writeBack() error {
+	slog.Debug("writing back to file", "path", f.path)
+	var buf bytes.Buffer
+	f.memRepository.mu.RLock()
+	err := gob.NewEncoder(&buf).Encode(f.memRepository.items)
+	f.memRepository.mu.RUnlock()
 	if err != nil {
 		return err
 	}
-	defer func(file *os.File) {
-		log.Printf("Closing file %s", f.path)
-		err := file.Close()
-		if err != nil {
-			log.Printf("Failed to close file %s: %v", f.path, err)
-		} else {
-			log.Printf("Closed file %s", f.path)
-		}
-	}(file)
-
-	encoder := gob.NewEncoder(file)
-	return encoder.Encode(f.memRepository.items)
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
 }
 
 // loadAll 从文件加载所有数据
@@ -69,7 +117,7 @@ func (f *FileRepo[K, V]) loadAll() error {
 	defer func(file *os.File) {
 		err := file.Close()
 		if err != nil {
-			log.Printf("Failed to close file %s: %v", f.path, err)
+			slog.Error("failed to close file", "path", f.path, "error", err)
 		}
 	}(file)
 
@@ -83,19 +131,23 @@ func (f *FileRepo[K, V]) Get(key K) (value V, found bool) {
 
 func (f *FileRepo[K, V]) Set(key K, data V) {
 	f.memRepository.Set(key, data)
-	err := f.writeBack(key, data)
-	if err != nil {
-		log.Printf("Failed to write back data to file %s: %v", f.path, err)
+	f.dirty.Store(true)
+	if err := f.Flush(); err != nil {
+		slog.Error("failed to write back data to file", "path", f.path, "error", err)
 	}
 }
 
 func (f *FileRepo[K, V]) Delete(key K) bool {
 	deleted := f.memRepository.Delete(key)
 	if deleted {
-		err := f.writeBack(key, *new(V)) // Ensure consistency after deletion
-		if err != nil {
-			log.Printf("Failed to update file after deletion in %s: %v", f.path, err)
+		f.dirty.Store(true)
+		if err := f.Flush(); err != nil {
+			slog.Error("failed to update file after deletion", "path", f.path, "error", err)
 		}
 	}
 	return deleted
 }
+
+func (f *FileRepo[K, V]) Range(fn func(key K, value V) bool) {
+	f.memRepository.Range(fn)
+}
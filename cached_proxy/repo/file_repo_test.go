@@ -2,6 +2,7 @@ package repo
 
 import (
 	"os"
+	"path"
 	"testing"
 )
 
@@ -45,3 +46,38 @@ func TestFileRepo(t *testing.T) {
 		t.Fatalf("Expected key1 to be absent after deletion")
 	}
 }
+
+func TestFileRepo_Flush(t *testing.T) {
+	dir := path.Join(t.TempDir(), "data")
+	filePath := path.Join(dir, "flush.gob")
+	repo := NewFileRepos[string, string](filePath)
+
+	// 目录被删除时写回失败，修改保留到下一次写回
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	repo.Set("key1", "value1")
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Fatalf("write back should fail without the directory, got %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := FlushAll(); err != nil {
+		t.Fatalf("FlushAll failed: %v", err)
+	}
+	if value, found := NewFileRepos[string, string](filePath).Get("key1"); !found || value != "value1" {
+		t.Fatalf("Expected to get 'value1' after flush, got '%s'", value)
+	}
+
+	// 没有修改时不再写回
+	if err := os.Remove(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("clean repo should not be written back, got %v", err)
+	}
+}
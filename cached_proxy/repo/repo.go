@@ -7,6 +7,8 @@ type KVRepo[K string, V any] interface {
 	Get(key K) (value V, found bool)
 	Set(key K, data V)
 	Delete(key K) bool
+	// Range 遍历所有键值对，顺序不确定，f 返回 false 时停止
+	Range(f func(key K, value V) bool)
 }
 
 type MemRepo[K string, V any] struct {
@@ -40,8 +42,24 @@ func (m *MemRepo[K, V]) Set(key K, data V) {
 	m.items[key] = data
 }
 
+// Range 遍历某一时刻的快照，f 中可以修改仓库
+func (m *MemRepo[K, V]) Range(f func(key K, value V) bool) {
+	m.mu.RLock()
+	items := make(map[K]V, len(m.items))
+	for key, value := range m.items {
+		items[key] = value
+	}
+	m.mu.RUnlock()
+	for key, value := range items {
+		if !f(key, value) {
+			return
+		}
+	}
+}
+
 type StaticRepo[K string, V any] struct {
 	value V
+	mu    sync.RWMutex // 读写锁
 }
 
 func NewStaticRepo[K string, V any]() *StaticRepo[K, V] {
@@ -49,13 +67,26 @@ func NewStaticRepo[K string, V any]() *StaticRepo[K, V] {
 }
 
 func (s *StaticRepo[K, V]) Get(_ K) (value V, found bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.value, true
 }
 
 func (s *StaticRepo[K, V]) Set(_ K, data V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.value = data
 }
 
 func (s *StaticRepo[K, V]) Delete(_ K) bool {
 	return true
 }
+
+// Range 所有的键共享同一个值，以零值作为键遍历一次，f 中可以修改仓库
+func (s *StaticRepo[K, V]) Range(f func(key K, value V) bool) {
+	s.mu.RLock()
+	value := s.value
+	s.mu.RUnlock()
+	var key K
+	f(key, value)
+}
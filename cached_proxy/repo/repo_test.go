@@ -98,3 +98,30 @@ func TestStaticRepo(t *testing.T) {
 		}
 	})
 }
+
+func TestMemRepo_Range(t *testing.T) {
+	repo := NewMemRepo[string, int]()
+	repo.Set("key1", 1)
+	repo.Set("key2", 2)
+	sum := 0
+	repo.Range(func(key string, value int) bool {
+		sum += value
+		// 遍历时可以修改仓库
+		repo.Delete(key)
+		return true
+	})
+	if sum != 3 || len(repo.items) != 0 {
+		t.Errorf("期望遍历所有键值对，实际和为 %d，剩余 %d 项", sum, len(repo.items))
+	}
+
+	repo.Set("key1", 1)
+	repo.Set("key2", 2)
+	visited := 0
+	repo.Range(func(string, int) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("期望 f 返回 false 时停止遍历，实际遍历 %d 项", visited)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"cached_proxy/logging"
+	"net/http"
+)
+
+// RequestIDHeader 是请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 客户端提供的请求 ID 的最大长度
+const maxRequestIDLength = 64
+
+// withRequestID 为请求分配请求 ID 并放入上下文，通过该上下文输出的日志都带有请求 ID
+//
+// 客户端或反向代理提供了合法的 X-Request-ID 时沿用该 ID，便于串联上下游的日志。
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID 只接受由字母、数字和 ._- 组成的 ID，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
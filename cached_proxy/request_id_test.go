@@ -0,0 +1,43 @@
+package main
+
+import (
+	"cached_proxy/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{"Generated", "", true},
+		{"Forwarded", "abc-123_x.y", false},
+		{"TooLong", strings.Repeat("a", maxRequestIDLength+1), true},
+		{"Injection", "abc\n{\"level\":\"ERROR\"}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID string
+			handler := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = logging.RequestID(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/info", nil)
+			if tt.header != "" {
+				r.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			id := w.Header().Get(RequestIDHeader)
+			if id == "" || id != ctxID {
+				t.Fatalf("response header %q should match context %q", id, ctxID)
+			}
+			if generated := id != tt.header; generated != tt.generate {
+				t.Errorf("unexpected request ID %q", id)
+			}
+		})
+	}
+}
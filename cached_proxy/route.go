@@ -5,12 +5,17 @@ import (
 	"cached_proxy/cache"
 	"cached_proxy/feign"
 	"cached_proxy/icalendar"
+	"context"
+	"crypto/sha1"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,38 +26,40 @@ type Credentials struct {
 
 func Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, errMethodNotAllowed)
 		return
 	}
 	err := r.ParseForm()
 	var creds Credentials
 	creds.Username = r.Form.Get("username")
 	creds.Password = r.Form.Get("password")
-	err = StudentService.SetStudent(creds.Username, creds.Password, true)
-	if err == nil {
-		token, err := AccountService.Login(creds.Username, creds.Password)
-		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		resp := map[string]string{
-			"access_token": token,
-			"token_type":   "Bearer",
-			"expires_in":   "315360000",
-		}
-		// 返回 token
-		w.Header().Set("Content-Type", "application/json")
-		err = json.NewEncoder(w).Encode(resp)
-		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
+	err = StudentService.SetStudent(r.Context(), creds.Username, creds.Password, true)
+	if err != nil {
+		if errors.Is(err, feign.ErrUnauthorized) {
+			loginsMetric.Inc("unauthorized")
+		} else {
+			loginsMetric.Inc("error")
 		}
+		writeError(w, r, err)
 		return
 	}
-	if err.Error() == "unauthorized" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-	} else {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	token, err := AccountService.Login(creds.Username, creds.Password)
+	if err != nil {
+		loginsMetric.Inc("error")
+		writeError(w, r, err)
+		return
+	}
+	loginsMetric.Inc("success")
+	resp := map[string]string{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   "315360000",
+	}
+	// 返回 token
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode token", "error", err)
 	}
 }
 
@@ -63,27 +70,27 @@ type TokenService struct {
 func (t *TokenService) checkToken(w http.ResponseWriter, r *http.Request) account2.Account {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+		writeError(w, r, errMissingToken)
 		return nil
 	}
 
 	// 检查Token格式（Bearer <token>）
-	token := authHeader[len("Bearer "):]
-	if token == "" {
-		http.Error(w, "Invalid token format", http.StatusUnauthorized)
+	token, found := strings.CutPrefix(authHeader, "Bearer ")
+	if !found || token == "" {
+		writeError(w, r, errInvalidToken)
 		return nil
 	}
 	account, err := AccountService.GetAccountByToken(token)
 	if err != nil {
-		if err.Error() == "account not found" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		} else {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
 		return nil
 	}
-	if account == nil || account.Status() != account2.Normal {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	if account == nil {
+		writeError(w, r, account2.ErrAccountNotFound)
+		return nil
+	}
+	if account.Status() != account2.Normal {
+		writeError(w, r, errAccountLocked)
 		return nil
 	}
 	return account
@@ -95,30 +102,25 @@ type InfoGetter[V any] struct {
 }
 
 func (c *InfoGetter[V]) GetInfo(w http.ResponseWriter, r *http.Request) {
-	log.Printf("GetInfo %s\n", r.RequestURI)
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, errMethodNotAllowed)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
 	account := c.checkToken(w, r)
 	if account == nil {
 		return
 	}
-	info, err := c.info.GetInfo(account.AccountID())
+	status := http.StatusOK
+	info, meta, err := c.info.GetInfoWithMetaContext(r.Context(), account.AccountID())
 	if err != nil {
-		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+		status = http.StatusNonAuthoritativeInfo
 	}
-	resp := feign.CommonResponse[any]{
-		Code:    1,
-		Message: "success",
-		Data:    info,
-	}
-	err = json.NewEncoder(w).Encode(resp)
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+	resp := newInfoResponse(info, meta)
+	etag := ""
+	if resp.Data != nil {
+		etag = dataETag(resp)
 	}
+	writeCached(w, r, status, resp, etag, meta.UpdatedAt)
 }
 
 var (
@@ -146,12 +148,12 @@ type IntrospectionResponse struct {
 
 func (a *AccountGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, errMethodNotAllowed)
 		return
 	}
 	err := r.ParseForm()
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeError(w, r, badRequest(err.Error()))
 		return
 	}
 	token := r.Form.Get("token")
@@ -173,8 +175,7 @@ func (a *AccountGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewEncoder(w).Encode(resp)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+		slog.ErrorContext(r.Context(), "failed to encode introspection", "error", err)
 	}
 }
 
@@ -182,7 +183,8 @@ type CalendarGetter[V any] struct {
 	TokenService
 	info            cache.InformationService[V]
 	calendarService cache.InformationService[feign.TeachingCalendar]
-	convertFunc     func(*V, *feign.TeachingCalendar) icalendar.Calendar
+	convertFunc     func(string, *V, *feign.TeachingCalendar, *CalendarOptions) icalendar.Calendar
+	feed            string // 日历的类型，用于区分不同日历的版本记录
 }
 
 var (
@@ -190,80 +192,183 @@ var (
 		info:            StudentExamService,
 		calendarService: CalendarService,
 		convertFunc:     ExamsConvertCalendar,
+		feed:            "exams",
 	}
 	CoursesCalendarHandler = CalendarGetter[feign.CourseList]{
 		info:            StudentCourseService,
 		calendarService: CalendarService,
 		convertFunc:     CoursesConvertCalendar,
+		feed:            "courses",
 	}
 )
 
-func (c *CalendarGetter[V]) GetInfo(w http.ResponseWriter, r *http.Request) {
-	log.Printf("GetInfo %s\n", r.RequestURI)
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-		return
-	}
-	account := c.checkToken(w, r)
-	if account == nil {
-		return
+var (
+	// errCalendarUpdating 表示生成日历所需的数据尚未就绪
+	errCalendarUpdating = fmt.Errorf("calendar %w", errDataUpdating)
+	// errInvalidCalendarOptions 表示请求参数中的日历导出选项不合法
+	errInvalidCalendarOptions = errors.New("invalid calendar options")
+)
+
+// renderedCalendar 是生成好的日历
+type renderedCalendar struct {
+	ics      string             // 日历内容
+	calendar icalendar.Calendar // 生成日历内容的日历，CalDAV 需要按事件拆分
+	modified time.Time          // 日历中事件的最后修改时间
+	updating bool               // 数据是否已过期或正在更新
+	// weekErrors 是课表中无法解析的周次数量
+	weekErrors int
+}
+
+// render 生成账户的日历，并根据上一次下发的版本设置事件的 SEQUENCE
+// 日历导出选项以账户保存的选项为基础，再由 query 中的参数覆盖
+func (c *CalendarGetter[V]) render(ctx context.Context, accountID string, query url.Values) (*renderedCalendar, error) {
+	options, err := getCalendarOptions(accountID).ApplyQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCalendarOptions, err)
 	}
-	info, err := c.info.GetInfo(account.AccountID())
+	result := &renderedCalendar{}
+	info, err := c.info.GetInfoContext(ctx, accountID)
 	if err != nil {
-		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+		result.updating = true
 	}
-	calendar, err := c.calendarService.GetInfo(account.AccountID())
+	calendar, err := c.calendarService.GetInfoContext(ctx, accountID)
 	if err != nil {
-		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+		result.updating = true
 	}
 	if calendar == nil || info == nil {
-		http.Error(w, "Data Updating", http.StatusNonAuthoritativeInfo)
+		return nil, errCalendarUpdating
+	}
+	if list, ok := any(info).(*feign.CourseList); ok {
+		result.weekErrors = len(weekErrors(list, calendar))
+	}
+	return result, result.publish(c.feed, accountID, c.convertFunc(accountID, info, calendar, &options))
+}
+
+// publish 设置日历的刷新间隔和事件版本，并生成最终的 ICS 内容
+func (r *renderedCalendar) publish(feed string, accountID string, calendar icalendar.Calendar) error {
+	if calendar == nil {
+		return fmt.Errorf("failed to convert calendar")
+	}
+	calendar.SetRefreshInterval(CalendarRefreshInterval)
+	r.modified = CalendarVersionService.Apply(feed, accountID, calendar)
+	r.ics = calendar.ToIcs(nil)
+	r.calendar = calendar
+	return nil
+}
+
+// setHeaders 在响应头中提示日历生成时跳过的内容，详情可以通过课表接口查看
+func (r *renderedCalendar) setHeaders(header http.Header) {
+	if r.weekErrors > 0 {
+		header.Set(WeekErrorsHeader, strconv.Itoa(r.weekErrors))
+	}
+}
+
+func (c *CalendarGetter[V]) GetInfo(w http.ResponseWriter, r *http.Request) {
+	serveCalendar(w, r, &c.TokenService, c)
+}
+
+// serveCalendar 校验 token 并输出账户的日历，数据过期时返回 203，数据未就绪时返回 503
+func serveCalendar(w http.ResponseWriter, r *http.Request, tokens *TokenService, renderer calendarRenderer) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed)
 		return
 	}
-	resp := c.convertFunc(info, calendar)
-	if resp == nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	account := tokens.checkToken(w, r)
+	if account == nil {
+		return
+	}
+	rendered, err := renderer.render(r.Context(), account.AccountID(), r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	ics := resp.ToIcs(nil)
 	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
-	_, err = fmt.Fprint(w, ics)
+	rendered.setHeaders(w.Header())
+	if rendered.updating {
+		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+	}
+	_, err = fmt.Fprint(w, rendered.ics)
 	if err != nil {
 		return
 	}
-
 }
 
 const ExamTimeLayout = "2006-01-02 15:04:05"
 
-func ExamsConvertCalendar(exams *feign.ExamList, _ *feign.TeachingCalendar) icalendar.Calendar {
+// eventUID 根据给定的标识生成稳定的事件 UID，相同的输入总是得到相同的 UID
+func eventUID(parts ...string) string {
+	hash := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return fmt.Sprintf("%x@%s", hash[:12], CalendarDomain)
+}
+
+// identities 为每个条目生成与时间和地点无关的标识，同名条目按照出现顺序编号
+func identities[T comparable](items []T, name func(T) string) map[T]string {
+	result := map[T]string{}
+	count := map[string]int{}
+	for _, item := range items {
+		if _, found := result[item]; found {
+			continue
+		}
+		key := name(item)
+		result[item] = fmt.Sprintf("%s#%d", key, count[key])
+		count[key]++
+	}
+	return result
+}
+
+func courseIdentities(list *feign.CourseList) map[feign.Course]string {
+	return identities(list.Courses, func(course feign.Course) string {
+		return course.Name + "|" + course.Teacher
+	})
+}
+
+// parseExamTime 解析考试的开始和结束时间，开始时间为空或无法解析时表示考试时间待定，结束时间无法解析时与开始时间相同
+func parseExamTime(exam feign.Examination) (start time.Time, end time.Time, ok bool) {
+	if exam.StartTime == "" {
+		return start, end, false
+	}
+	var err error
+	if start, err = time.ParseInLocation(ExamTimeLayout, exam.StartTime, feign.Zone); err != nil {
+		return start, end, false
+	}
+	if end, err = time.ParseInLocation(ExamTimeLayout, exam.EndTime, feign.Zone); err != nil || end.Before(start) {
+		end = start
+	}
+	return start, end, true
+}
+
+func ExamsConvertCalendar(studentID string, exams *feign.ExamList, _ *feign.TeachingCalendar, options *CalendarOptions) icalendar.Calendar {
 	if exams == nil || exams.Exams == nil {
 		return nil
 	}
 	ical := &icalendar.IcsCalendar{}
 	ical.SetProductID(ProdID)
+	ical.SetName(ExamsCalendarName)
+	ical.SetColor(ExamColor)
 	ical.SetTimezone(icalendar.GetDefaultTimezone())
+	examIDs := identities(exams.Exams, func(exam feign.Examination) string {
+		return exam.Name + "|" + exam.Type
+	})
 	for _, exam := range exams.Exams {
-		if exam.StartTime == "" {
+		if options.excluded(exam.Name) {
 			continue
 		}
-		event := &icalendar.IcsEvent{}
-		var startTime, endTime time.Time
-		var err error
-		if startTime, err = time.Parse(ExamTimeLayout, exam.StartTime); err != nil {
+		startTime, endTime, ok := parseExamTime(exam)
+		if !ok {
 			continue
 		}
-		if endTime, err = time.Parse(ExamTimeLayout, exam.EndTime); err != nil {
-			endTime = startTime
-		}
+		event := &icalendar.IcsEvent{}
 		location := &icalendar.IcsLocation{}
 		location.SetName(exam.Location)
-		event.SetSummary(fmt.Sprintf("%s %s", ExamSummaryPrefix, exam.Name))
+		event.SetSummary(options.examTitle(exam))
 		event.SetLocation(location)
 		event.SetDescription(fmt.Sprintf("%s %s %s", exam.Name, exam.Location, ExamDescSuffix))
+		event.SetCategories(ExamCategory)
+		event.SetColor(ExamColor)
 		event.SetStart(startTime)
 		event.SetEnd(endTime)
-		for _, a := range DefaultExamAlarms {
+		event.SetUID(eventUID(studentID, "exam", examIDs[exam]))
+		for _, a := range options.examAlarms() {
 			event.AddAlarm(a)
 		}
 		ical.AddEvent(event)
@@ -271,88 +376,193 @@ func ExamsConvertCalendar(exams *feign.ExamList, _ *feign.TeachingCalendar) ical
 	return ical
 }
 
-func CoursesConvertCalendar(list *feign.CourseList, calendar *feign.TeachingCalendar) icalendar.Calendar {
+func CoursesConvertCalendar(studentID string, list *feign.CourseList, calendar *feign.TeachingCalendar, options *CalendarOptions) icalendar.Calendar {
 	if list == nil || list.Courses == nil || calendar == nil {
 		return nil
 	}
+	return coursesConvertCalendar(studentID, list, calendar, HolidayService.GetHolidayCalendar(calendar.TermId), options)
+}
+
+// coursesConvertCalendar 将课程转换为日历，放假和调休日的课程会通过 EXDATE 排除，调休日补上的课程会作为单独的事件添加
+// 事件的 UID 由学号、课程标识和周次范围决定，课程调整教室或时间后 UID 保持不变
+func coursesConvertCalendar(studentID string, list *feign.CourseList, calendar *feign.TeachingCalendar, holidays *feign.HolidayCalendar, options *CalendarOptions) icalendar.Calendar {
 	ical := icalendar.IcsCalendar{}
 	ical.SetProductID(ProdID)
+	ical.SetName(CoursesCalendarName)
+	ical.SetColor(CourseColor)
 	ical.SetTimezone(icalendar.GetDefaultTimezone())
 	timetable := calendar.GetTermTimeTable()
+	courseIDs := courseIdentities(list)
 	for _, course := range list.Courses {
-		if course.Weeks == "" || course.Day == "" || course.StartTime == 0 || course.Duration == 0 {
+		if options.excluded(course.Name) {
 			continue
 		}
-		weeks := strings.Split(course.Weeks, ",")
-		for _, week := range weeks {
-			week = strings.TrimSpace(week)
-			if week == "" {
-				continue
-			}
-			w := strings.Split(week, "-")
-			var start, end int
-			var err error
-			if start, err = strconv.Atoi(w[0]); err != nil {
-				log.Printf("failed to parse week: %s", week)
-				continue
+		for _, event := range courseEvents(studentID, course, courseIDs[course], calendar, holidays, options) {
+			ical.AddEvent(event)
+		}
+	}
+	if holidays != nil {
+		for _, adjustment := range holidays.Adjustments {
+			date, _ := time.Parse(feign.DateLayout, adjustment.Date)
+			follow, _ := time.Parse(feign.DateLayout, adjustment.Follow)
+			week, _ := calendar.WeekOf(date)
+			for _, course := range makeUpCourses(list, calendar, follow) {
+				if options.excluded(course.Name) {
+					continue
+				}
+				event := newCourseEvent(course, date, timetable.TimeTableOf(week), options)
+				event.SetDescription(fmt.Sprintf("调休：按%s的课表上课\n%s", adjustment.Follow, courseDescription(course, options)))
+				event.SetUID(eventUID(studentID, "make-up", courseIDs[course], adjustment.Date))
+				ical.AddEvent(event)
 			}
-			if len(w) < 2 {
-				end = start
-			} else if end, err = strconv.Atoi(w[1]); err != nil {
-				log.Printf("failed to parse week: %s", week)
+		}
+	}
+	return &ical
+}
+
+// courseEvents 将一门课程转换为按周重复的事件，跨越作息时间切换周的周次范围会拆分为两个事件，
+// 放假导致没有任何一次上课的事件会被跳过
+func courseEvents(studentID string, course feign.Course, id string, calendar *feign.TeachingCalendar, holidays *feign.HolidayCalendar, options *CalendarOptions) []*icalendar.IcsEvent {
+	if course.Weeks == "" || course.Day == "" || course.StartTime == 0 || course.Duration == 0 {
+		return nil
+	}
+	var events []*icalendar.IcsEvent
+	timetable := calendar.GetTermTimeTable()
+	add := func(start, end, step int, table feign.TimeTable) {
+		event := convertCourseToEvent(course, calendar, holidays, feign.WeekRange{Start: start, End: end, Step: step}, table, options)
+		if len(event.Occurrences(time.Time{}, calendar.DateOf(end+1, 1))) == 0 {
+			return
+		}
+		event.SetUID(eventUID(studentID, "course", id, fmt.Sprintf("%d-%d", start, end)))
+		events = append(events, event)
+	}
+	for _, weeks := range courseWeeks(course, calendar).Ranges() {
+		start, end, step := weeks.Start, weeks.End, weeks.Step
+		//	If the time was cross the sep week, separate the event into two parts
+		//  e.g. sep = 11  start = 10 end = 11
+		//  e.g. sep = 11  start = 10 end = 12
+		//  n.e.g. sep = 11  start = 11 end = 12
+		if start < timetable.SepWeeks && end >= timetable.SepWeeks && course.StartTime+course.Duration-1 > 4 {
+			// 单双周的课程需要保持间隔，分界两侧分别取最近的一周
+			last := start + (timetable.SepWeeks-1-start)/step*step
+			add(start, last, step, timetable.PreTimeTable)
+			start = last + step
+			if start > end {
 				continue
 			}
+		}
+		if end >= timetable.SepWeeks {
+			add(start, end, step, timetable.SufTimeTable)
+		} else {
+			add(start, end, step, timetable.PreTimeTable)
+		}
+	}
+	return events
+}
 
-			//	If the time was cross the sep week, separate the event into two parts
-			//  e.g. sep = 11  start = 10 end = 11
-			//  e.g. sep = 11  start = 10 end = 12
-			//  n.e.g. sep = 11  start = 11 end = 12
-			if start < timetable.SepWeeks && end >= timetable.SepWeeks && course.StartTime+course.Duration-1 > 4 {
-				event := convertCourseToEvent(course, calendar, start, timetable.SepWeeks-1, timetable.PreTimeTable)
-				ical.AddEvent(event)
-				start = timetable.SepWeeks
-			}
-			if end >= timetable.SepWeeks {
-				event := convertCourseToEvent(course, calendar, timetable.SepWeeks, end, timetable.SufTimeTable)
-				ical.AddEvent(event)
-			} else {
-				event := convertCourseToEvent(course, calendar, start, end, timetable.PreTimeTable)
-				ical.AddEvent(event)
-			}
+// loggedWeekErrors 记录已经输出过日志的周次表达式，每个表达式只记录一次
+var loggedWeekErrors sync.Map
+
+// courseWeeks 解析课程的上课周次，无法解析的部分会被跳过，超出学期的部分会被截断，每个表达式只记录一次日志
+func courseWeeks(course feign.Course, calendar *feign.TeachingCalendar) feign.WeekSet {
+	weeks, err := feign.ParseWeeks(course.Weeks, calendar.Weeks)
+	if err != nil {
+		key := fmt.Sprintf("%s/%d", course.Weeks, calendar.Weeks)
+		if _, logged := loggedWeekErrors.LoadOrStore(key, struct{}{}); !logged {
+			slog.Warn("failed to parse weeks of course", "course", course.Name, "error", err)
 		}
 	}
-	return &ical
+	return weeks
+}
+
+// weekErrors 返回课表中所有无法解析的周次，用于在响应中提示用户
+func weekErrors(list *feign.CourseList, calendar *feign.TeachingCalendar) []*feign.WeekError {
+	var result []*feign.WeekError
+	if list == nil || calendar == nil {
+		return result
+	}
+	for _, course := range list.Courses {
+		var errs feign.WeekErrors
+		if _, err := feign.ParseWeeks(course.Weeks, calendar.Weeks); errors.As(err, &errs) {
+			result = append(result, errs...)
+		}
+	}
+	return result
 }
 
-func convertCourseToEvent(course feign.Course, calendar *feign.TeachingCalendar, start int, end int, timetable feign.TimeTable) *icalendar.IcsEvent {
-	summary := fmt.Sprintf("%s %s", CourseSummaryPrefix, course.Name)
-	desc := fmt.Sprintf("授课教师：%s  %d节课\\n周次：%s\\n%s", course.Teacher, course.Duration, course.Weeks, CourseDescSummarySuffix)
+// makeUpCourses 返回调休日需要补上的课程，即 follow 日期原本的课程
+func makeUpCourses(list *feign.CourseList, calendar *feign.TeachingCalendar, follow time.Time) []feign.Course {
+	var courses []feign.Course
+	week, day := calendar.WeekOf(follow)
+	for _, course := range list.Courses {
+		if course.StartTime == 0 || course.Duration == 0 || feign.Days2Int[course.Day] != day {
+			continue
+		}
+		if courseWeeks(course, calendar).Has(week) {
+			courses = append(courses, course)
+		}
+	}
+	return courses
+}
+
+// courseDescription 生成课程事件的描述，可以根据选项隐藏授课教师和周次
+func courseDescription(course feign.Course, options *CalendarOptions) string {
+	desc := strings.Builder{}
+	if !options.HideTeacher {
+		desc.WriteString(fmt.Sprintf("授课教师：%s  ", course.Teacher))
+	}
+	desc.WriteString(fmt.Sprintf("%d节课\n", course.Duration))
+	if !options.HideWeeks {
+		desc.WriteString(fmt.Sprintf("周次：%s\n", course.Weeks))
+	}
+	desc.WriteString(CourseDescSummarySuffix)
+	return desc.String()
+}
+
+// newCourseEvent 创建课程在指定日期的单次事件
+func newCourseEvent(course feign.Course, date time.Time, timetable feign.TimeTable, options *CalendarOptions) *icalendar.IcsEvent {
+	summary := options.courseTitle(course)
 	location := &icalendar.IcsLocation{}
 	location.SetName(course.Classroom)
 	event := icalendar.IcsEvent{}
 	event.SetSummary(summary)
-	event.SetDescription(desc)
+	event.SetDescription(courseDescription(course, options))
 	event.SetLocation(location)
-	date := calendar.StartTime().AddDate(0, 0, (start-1)*7+feign.Days2Int[course.Day])
+	event.SetCategories(CourseCategory)
+	event.SetColor(CourseColor)
 	tb := timetable.EventTimes
-	startTime := tb[course.StartTime-1].StartTime.AddDate(date.Year(), int(date.Month()), date.Day())
-	endTime := tb[course.StartTime+course.Duration-2].EndTime.AddDate(date.Year(), int(date.Month()), date.Day())
+	startTime, _ := tb[course.StartTime-1].On(date)
+	_, endTime := tb[course.StartTime+course.Duration-2].On(date)
 	event.SetStart(startTime)
 	event.SetEnd(endTime)
+	for _, a := range options.courseAlarms() {
+		event.AddAlarm(a)
+	}
+	return &event
+}
+
+func convertCourseToEvent(course feign.Course, calendar *feign.TeachingCalendar, holidays *feign.HolidayCalendar, weeks feign.WeekRange, timetable feign.TimeTable, options *CalendarOptions) *icalendar.IcsEvent {
+	day := feign.Days2Int[course.Day]
+	event := newCourseEvent(course, calendar.DateOf(weeks.Start, day), timetable, options)
 	rrule := &icalendar.IcsRepeatRule{}
 	rrule.SetFrequency("WEEKLY")
-	rrule.SetInterval(1)
-	rrule.SetCount(end - start + 1)
+	rrule.SetInterval(weeks.Step)
+	rrule.SetCount(weeks.Count())
 	event.SetRepeatRule(rrule)
-	for _, a := range DefaultCourseAlarms {
-		event.AddAlarm(a)
+	// 放假日和调休日原本的课程不再上课
+	for week := weeks.Start; week <= weeks.End; week += weeks.Step {
+		date := calendar.DateOf(week, day)
+		if holidays.IsCancelled(date) {
+			exDate, _ := timetable.EventTimes[course.StartTime-1].On(date)
+			event.AddExceptionDate(exDate)
+		}
 	}
-	return &event
+	return event
 }
 
 func CalPage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, errMethodNotAllowed)
 		return
 	}
 	_, err := w.Write(CalBytes)
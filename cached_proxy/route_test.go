@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"cached_proxy/feign"
 	"cached_proxy/icalendar"
+	"log/slog"
+	"strings"
 	"testing"
 )
 
@@ -45,7 +48,7 @@ func TestCoursesConvertCalendar(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := CoursesConvertCalendar(tt.args.list, tt.args.calendar); !tt.judge(got) {
+			if got := CoursesConvertCalendar("student", tt.args.list, tt.args.calendar, &CalendarOptions{}); !tt.judge(got) {
 				t.Errorf("CoursesConvertCalendar() = %v", got)
 			}
 		})
@@ -85,9 +88,111 @@ func TestExamsConvertCalendar(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ExamsConvertCalendar(tt.args.exams, tt.args.calendar); !tt.judge(got) {
+			if got := ExamsConvertCalendar("student", tt.args.exams, tt.args.calendar, &CalendarOptions{}); !tt.judge(got) {
 				t.Errorf("ExamsConvertCalendar() = %v", got)
 			}
 		})
 	}
 }
+
+func TestCoursesConvertCalendar_Holidays(t *testing.T) {
+	list := &feign.CourseList{
+		Courses: []feign.Course{
+			{Name: "Monday", Teacher: "Test", Classroom: "Test", Weeks: "1-16", StartTime: 1, Duration: 2, Day: "Monday"},
+			{Name: "Sunday", Teacher: "Test", Classroom: "Test", Weeks: "10", StartTime: 3, Duration: 2, Day: "Sunday"},
+		},
+	}
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	holidays := &feign.HolidayCalendar{
+		TermId:      "2024-2025-2",
+		Holidays:    []feign.Holiday{{Date: "2025-05-05", Name: "劳动节"}},
+		Adjustments: []feign.Adjustment{{Date: "2025-04-27", Follow: "2025-05-05", Name: "劳动节调休"}},
+	}
+	ics := coursesConvertCalendar("student", list, calendar, holidays, &CalendarOptions{}).ToIcs(nil)
+	for _, want := range []string{
+		"EXDATE;TZID=Asia/Shanghai:20250505T080000",
+		"DTSTART;TZID=Asia/Shanghai:20250427T080000",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("CoursesConvertCalendar() missing %q in\n%s", want, ics)
+		}
+	}
+	// 唯一一次上课被调休取消的课程不再生成事件
+	if strings.Contains(ics, "DTSTART;TZID=Asia/Shanghai:20250427T101000") {
+		t.Errorf("CoursesConvertCalendar() should skip courses without any occurrence:\n%s", ics)
+	}
+}
+
+func TestCoursesConvertCalendar_StableUID(t *testing.T) {
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	course := feign.Course{Name: "Test", Teacher: "Test", Classroom: "A101", Weeks: "1-8", StartTime: 1, Duration: 2, Day: "Monday"}
+	moved := course
+	moved.Classroom = "B202"
+	moved.StartTime = 3
+	moved.Day = "Tuesday"
+	uidOf := func(studentID string, course feign.Course) string {
+		list := &feign.CourseList{Courses: []feign.Course{course}}
+		return coursesConvertCalendar(studentID, list, calendar, nil, &CalendarOptions{}).GetEvents()[0].GetUID()
+	}
+	uid := uidOf("student", course)
+	if !strings.HasSuffix(uid, "@"+CalendarDomain) {
+		t.Errorf("UID should end with the calendar domain: %s", uid)
+	}
+	if uidOf("student", moved) != uid {
+		t.Errorf("UID should not change when the course moves rooms or times")
+	}
+	if uidOf("another", course) == uid {
+		t.Errorf("UID should be different for different students")
+	}
+}
+
+func TestCoursesConvertCalendar_OddWeeks(t *testing.T) {
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	list := &feign.CourseList{Courses: []feign.Course{
+		{Name: "Odd", Teacher: "Test", Classroom: "Test", Weeks: "1-15单", StartTime: 1, Duration: 2, Day: "Monday"},
+	}}
+	events := coursesConvertCalendar("student", list, calendar, nil, &CalendarOptions{}).GetEvents()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event for odd weeks, got %d", len(events))
+	}
+	ics := events[0].ToIcs(nil)
+	if !strings.Contains(ics, "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=8") {
+		t.Errorf("expected a bi-weekly rule, got\n%s", ics)
+	}
+}
+
+func TestWeekErrors(t *testing.T) {
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	list := &feign.CourseList{Courses: []feign.Course{
+		{Name: "Valid", Weeks: "1-16周", StartTime: 1, Duration: 2, Day: "Monday"},
+		{Name: "Broken", Weeks: "1-8,九", StartTime: 1, Duration: 2, Day: "Tuesday"},
+	}}
+	errs := weekErrors(list, calendar)
+	if len(errs) != 1 || errs[0].Item != "九" {
+		t.Errorf("weekErrors() = %+v, want one error for %q", errs, "九")
+	}
+	// 能够解析的部分仍然生成事件
+	if events := coursesConvertCalendar("student", list, calendar, nil, &CalendarOptions{}).GetEvents(); len(events) != 2 {
+		t.Errorf("expected 2 events, got %d", len(events))
+	}
+}
+
+func TestCourseWeeks_OutOfRange(t *testing.T) {
+	defer func(logger *slog.Logger) { slog.SetDefault(logger) }(slog.Default())
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	course := feign.Course{Name: "Overflow", Weeks: "1-20", StartTime: 1, Duration: 2, Day: "Monday"}
+
+	// 超出学期的区间截断到最后一周，仍然作为无法解析的周次提示
+	if weeks := courseWeeks(course, calendar); len(weeks) != 18 || weeks[17] != 18 {
+		t.Errorf("courseWeeks() = %v, want weeks 1-18", weeks)
+	}
+	if errs := weekErrors(&feign.CourseList{Courses: []feign.Course{course}}, calendar); len(errs) != 1 || errs[0].Item != "1-20" {
+		t.Errorf("weekErrors() = %+v, want one error for %q", errs, "1-20")
+	}
+	courseWeeks(course, calendar)
+	if n := strings.Count(buf.String(), "failed to parse weeks"); n != 1 {
+		t.Errorf("expected one warning per expression, got %d:\n%s", n, buf.String())
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cached_proxy/cache"
+	"cached_proxy/feign"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ScheduleItem 是课表中某一天的一次课程
+type ScheduleItem struct {
+	feign.Course
+	Date   string `json:"date"`    // 上课日期
+	Week   int    `json:"week"`    // 上课周次
+	Begin  string `json:"begin"`   // 上课时间
+	End    string `json:"end"`     // 下课时间
+	MakeUp bool   `json:"make_up"` // 是否为调休补课
+}
+
+// Schedule 是某一周的实际课表，已经应用了节假日和调休安排
+type Schedule struct {
+	Week        int                `json:"week"`
+	Courses     []ScheduleItem     `json:"courses"`
+	Holidays    []feign.Holiday    `json:"holidays"`
+	Adjustments []feign.Adjustment `json:"adjustments"`
+	WeekErrors  []*feign.WeekError `json:"week_errors"` // 无法解析的课程周次，这些周次的课程没有出现在课表中
+}
+
+// CoursesSchedule 计算指定周次的实际课表
+func CoursesSchedule(list *feign.CourseList, calendar *feign.TeachingCalendar, holidays *feign.HolidayCalendar, week int) *Schedule {
+	if list == nil || calendar == nil {
+		return nil
+	}
+	schedule := &Schedule{
+		Week:        week,
+		Courses:     []ScheduleItem{},
+		Holidays:    []feign.Holiday{},
+		Adjustments: []feign.Adjustment{},
+		WeekErrors:  weekErrors(list, calendar),
+	}
+	timetable := calendar.GetTermTimeTable().TimeTableOf(week)
+	for day := 1; day <= 7; day++ {
+		date := calendar.DateOf(week, day)
+		if holidays != nil {
+			for _, holiday := range holidays.Holidays {
+				if holiday.Date == date.Format(feign.DateLayout) {
+					schedule.Holidays = append(schedule.Holidays, holiday)
+				}
+			}
+			for _, adjustment := range holidays.Adjustments {
+				if adjustment.Date != date.Format(feign.DateLayout) {
+					continue
+				}
+				schedule.Adjustments = append(schedule.Adjustments, adjustment)
+				follow, _ := time.Parse(feign.DateLayout, adjustment.Follow)
+				for _, course := range makeUpCourses(list, calendar, follow) {
+					schedule.Courses = append(schedule.Courses, newScheduleItem(course, date, week, timetable, true))
+				}
+			}
+		}
+	}
+	// 通过重复规则展开本周的课程，放假日和调休日的课程已经作为 EXDATE 排除
+	from := calendar.DateOf(week, 1)
+	to := calendar.DateOf(week+1, 1)
+	for _, course := range list.Courses {
+		for _, event := range courseEvents("", course, "", calendar, holidays, &CalendarOptions{}) {
+			for _, occurrence := range event.Occurrences(from, to) {
+				schedule.Courses = append(schedule.Courses, newScheduleItem(course, occurrence, week, timetable, false))
+			}
+		}
+	}
+	sort.SliceStable(schedule.Courses, func(i, j int) bool {
+		if schedule.Courses[i].Date != schedule.Courses[j].Date {
+			return schedule.Courses[i].Date < schedule.Courses[j].Date
+		}
+		return schedule.Courses[i].StartTime < schedule.Courses[j].StartTime
+	})
+	return schedule
+}
+
+func newScheduleItem(course feign.Course, date time.Time, week int, timetable feign.TimeTable, makeUp bool) ScheduleItem {
+	tb := timetable.EventTimes
+	begin, _ := tb[course.StartTime-1].On(date)
+	_, end := tb[course.StartTime+course.Duration-2].On(date)
+	return ScheduleItem{
+		Course: course,
+		Date:   date.Format(feign.DateLayout),
+		Week:   week,
+		Begin:  begin.Format("15:04"),
+		End:    end.Format("15:04"),
+		MakeUp: makeUp,
+	}
+}
+
+// ScheduleGetter 获取某一周的实际课表
+type ScheduleGetter struct {
+	TokenService
+	courseService   cache.InformationService[feign.CourseList]
+	calendarService cache.InformationService[feign.TeachingCalendar]
+	holidayService  feign.HolidayService
+}
+
+var (
+	ScheduleHandler = &ScheduleGetter{
+		courseService:   StudentCourseService,
+		calendarService: CalendarService,
+		holidayService:  HolidayService,
+	}
+)
+
+func (s *ScheduleGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+	account := s.checkToken(w, r)
+	if account == nil {
+		return
+	}
+	status := http.StatusOK
+	courses, err := s.courseService.GetInfoContext(r.Context(), account.AccountID())
+	if err != nil {
+		status = http.StatusNonAuthoritativeInfo
+	}
+	calendar, err := s.calendarService.GetInfoContext(r.Context(), account.AccountID())
+	if err != nil {
+		status = http.StatusNonAuthoritativeInfo
+	}
+	if calendar == nil || courses == nil {
+		writeError(w, r, errDataUpdating)
+		return
+	}
+	week, _ := calendar.WeekOf(time.Now())
+	if week < 1 {
+		week = 1
+	}
+	if value := r.URL.Query().Get("week"); value != "" {
+		if week, err = strconv.Atoi(value); err != nil || week < 1 {
+			writeError(w, r, badRequest("Invalid week"))
+			return
+		}
+	}
+	schedule := CoursesSchedule(courses, calendar, s.holidayService.GetHolidayCalendar(calendar.TermId), week)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	resp := feign.CommonResponse[any]{
+		Code:    1,
+		Message: "success",
+		Data:    schedule,
+	}
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode schedule", "error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"cached_proxy/feign"
+	"testing"
+)
+
+func TestCoursesSchedule(t *testing.T) {
+	list := &feign.CourseList{
+		Courses: []feign.Course{
+			{Name: "Monday", Weeks: "1-16", StartTime: 1, Duration: 2, Day: "Monday"},
+			{Name: "Tuesday", Weeks: "1-8", StartTime: 5, Duration: 2, Day: "Tuesday"},
+			{Name: "Sunday", Weeks: "10", StartTime: 3, Duration: 2, Day: "Sunday"},
+			{Name: "Even", Weeks: "2-8双", StartTime: 3, Duration: 2, Day: "Wednesday"},
+		},
+	}
+	calendar := &feign.TeachingCalendar{Start: "2025-02-17", Weeks: 18, TermId: "2024-2025-2"}
+	holidays := &feign.HolidayCalendar{
+		TermId:      "2024-2025-2",
+		Holidays:    []feign.Holiday{{Date: "2025-05-05", Name: "劳动节"}},
+		Adjustments: []feign.Adjustment{{Date: "2025-04-27", Follow: "2025-05-05", Name: "劳动节调休"}},
+	}
+
+	t.Run("Normal week", func(t *testing.T) {
+		schedule := CoursesSchedule(list, calendar, holidays, 1)
+		if len(schedule.Courses) != 2 {
+			t.Fatalf("expected 2 courses, got %+v", schedule.Courses)
+		}
+		if schedule.Courses[1].Date != "2025-02-18" || schedule.Courses[1].Begin != "14:00" {
+			t.Errorf("unexpected course: %+v", schedule.Courses[1])
+		}
+	})
+
+	t.Run("Make-up day", func(t *testing.T) {
+		schedule := CoursesSchedule(list, calendar, holidays, 10)
+		if len(schedule.Courses) != 2 {
+			t.Fatalf("expected 2 courses, got %+v", schedule.Courses)
+		}
+		makeUp := schedule.Courses[1]
+		if !makeUp.MakeUp || makeUp.Name != "Monday" || makeUp.Date != "2025-04-27" {
+			t.Errorf("expected make-up course on 2025-04-27, got %+v", makeUp)
+		}
+		if len(schedule.Adjustments) != 1 {
+			t.Errorf("expected 1 adjustment, got %+v", schedule.Adjustments)
+		}
+	})
+
+	t.Run("Holiday", func(t *testing.T) {
+		schedule := CoursesSchedule(list, calendar, holidays, 12)
+		if len(schedule.Courses) != 0 {
+			t.Errorf("expected no course, got %+v", schedule.Courses)
+		}
+		if len(schedule.Holidays) != 1 {
+			t.Errorf("expected 1 holiday, got %+v", schedule.Holidays)
+		}
+	})
+
+	t.Run("Even week", func(t *testing.T) {
+		schedule := CoursesSchedule(list, calendar, holidays, 2)
+		if len(schedule.Courses) != 3 || schedule.Courses[2].Name != "Even" || schedule.Courses[2].Date != "2025-02-26" {
+			t.Errorf("expected the even-week course on 2025-02-26, got %+v", schedule.Courses)
+		}
+		if schedule := CoursesSchedule(list, calendar, holidays, 3); len(schedule.Courses) != 2 {
+			t.Errorf("expected no even-week course in week 3, got %+v", schedule.Courses)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"cached_proxy/cache"
+	"cached_proxy/feign"
+	"cached_proxy/transcript"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// transcriptFormats 是支持的导出格式对应的 Content-Type
+var transcriptFormats = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"pdf":  "application/pdf",
+}
+
+// ScoreExportGetter 将成绩导出为成绩单文件
+type ScoreExportGetter struct {
+	TokenService
+	majorService cache.InformationService[feign.ScoreBoard]
+	minorService cache.InformationService[feign.ScoreBoard]
+	infoService  cache.InformationService[feign.StudentInfo]
+	fontPath     string
+	fontOnce     sync.Once
+	font         *transcript.Font
+}
+
+var (
+	ScoreExportHandler = &ScoreExportGetter{
+		majorService: StudentMajorScoreService,
+		minorService: StudentMinorScoreService,
+		infoService:  StudentInfoService,
+		fontPath:     TranscriptFont,
+	}
+)
+
+// loadFont 在第一次导出 PDF 时读取字体，没有配置字体或者配置的字体无法读取时使用随服务发布的字体
+func (s *ScoreExportGetter) loadFont() *transcript.Font {
+	s.fontOnce.Do(func() {
+		if s.fontPath != "" {
+			font, err := transcript.LoadFont(s.fontPath)
+			if err == nil {
+				s.font = font
+				return
+			}
+			slog.Warn("failed to load transcript font, falling back to the bundled font", "path", s.fontPath, "error", err)
+		}
+		font, err := transcript.DefaultFont()
+		if err != nil {
+			slog.Error("failed to load the bundled transcript font, falling back to STSong-Light", "error", err)
+			return
+		}
+		s.font = font
+	})
+	return s.font
+}
+
+// GetInfo 导出成绩单，format 为 csv、xlsx 或 pdf，minor=1 时导出辅修成绩
+func (s *ScoreExportGetter) GetInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	contentType, found := transcriptFormats[format]
+	if !found {
+		writeError(w, r, badRequest("Invalid format"))
+		return
+	}
+	account := s.checkToken(w, r)
+	if account == nil {
+		return
+	}
+	service := s.majorService
+	if r.URL.Query().Get("minor") == "1" {
+		service = s.minorService
+	}
+	status := http.StatusOK
+	board, err := service.GetInfoContext(r.Context(), account.AccountID())
+	if err != nil {
+		status = http.StatusNonAuthoritativeInfo
+	}
+	if board == nil {
+		writeError(w, r, errDataUpdating)
+		return
+	}
+	// 学生信息只用于成绩单的表头，缺失时仍然导出
+	info, err := s.infoService.GetInfoContext(r.Context(), account.AccountID())
+	if err != nil {
+		status = http.StatusNonAuthoritativeInfo
+	}
+
+	t := transcript.New(board, info, time.Now().In(feign.Zone))
+	buf := bytes.Buffer{}
+	switch format {
+	case "csv":
+		err = t.WriteCSV(&buf)
+	case "xlsx":
+		err = t.WriteXLSX(&buf)
+	case "pdf":
+		err = t.WritePDF(&buf, s.loadFont())
+	}
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to export transcript as %s: %w", format, err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	filename := "transcript"
+	if t.StudentID != "" {
+		filename += "-" + url.PathEscape(t.StudentID)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", filename, format))
+	w.WriteHeader(status)
+	if _, err = buf.WriteTo(w); err != nil {
+		slog.WarnContext(r.Context(), "failed to write transcript", "error", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"cached_proxy/transcript"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScoreExportGetter_GetInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{"InvalidFormat", http.MethodGet, "/scores/export?format=docx", http.StatusBadRequest},
+		{"MethodNotAllowed", http.MethodPost, "/scores/export?format=pdf", http.StatusMethodNotAllowed},
+		{"MissingToken", http.MethodGet, "/scores/export?format=csv", http.StatusUnauthorized},
+	}
+	handler := &ScoreExportGetter{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.GetInfo(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestScoreExportGetter_LoadFont(t *testing.T) {
+	bundled, err := transcript.DefaultFont()
+	if err != nil {
+		t.Fatalf("bundled font should parse: %v", err)
+	}
+	for _, path := range []string{"", "./testdata/missing.ttf"} {
+		handler := &ScoreExportGetter{fontPath: path}
+		if font := handler.loadFont(); font != bundled {
+			t.Errorf("font path %q should fall back to the bundled font, got %p", path, font)
+		}
+	}
+}
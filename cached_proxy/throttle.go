@@ -0,0 +1,69 @@
+package main
+
+import (
+	"cached_proxy/ratelimit"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errTooManyRequests 返回入站请求被限流的错误
+func errTooManyRequests(wait time.Duration) error {
+	return &HTTPError{Status: http.StatusTooManyRequests, Code: "rate_limited", Message: "Too Many Requests", RetryAfter: wait}
+}
+
+// Throttle 限制入站请求的频率，所有请求都按 IP 限流，令牌有效的请求再按令牌限流
+//
+// 只有确认有效的令牌才会创建令牌的桶，随意伪造的 Authorization 不能绕过 IP 的限制，也不会占用内存。
+type Throttle struct {
+	tokens *ratelimit.Limiter
+	ips    *ratelimit.Limiter
+	valid  func(token string) bool // 判断令牌是否有效
+	exempt map[string]bool         // 不限流的路径
+}
+
+// NewThrottle 创建入站请求的限流，valid 用于确认令牌有效
+func NewThrottle(tokenLimit ratelimit.Limit, ipLimit ratelimit.Limit, valid func(token string) bool, exempt ...string) *Throttle {
+	t := &Throttle{tokens: ratelimit.NewLimiter(tokenLimit), ips: ratelimit.NewLimiter(ipLimit), valid: valid, exempt: map[string]bool{}}
+	for _, path := range exempt {
+		t.exempt[path] = true
+	}
+	return t
+}
+
+// Wrap 为 next 添加限流，被限流的请求返回 429 和 Retry-After
+func (t *Throttle) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.exempt[r.URL.Path] {
+			if ok, wait := t.allow(r); !ok {
+				writeError(w, r, errTooManyRequests(wait))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *Throttle) allow(r *http.Request) (bool, time.Duration) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ok, wait := t.ips.Allow(host); !ok {
+		return false, wait
+	}
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found && token != "" && t.valid(token) {
+		return t.tokens.Allow(token)
+	}
+	return true, 0
+}
+
+// validToken 判断令牌是否属于某个账户
+func validToken(token string) bool {
+	account, err := AccountService.GetAccountByToken(token)
+	return err == nil && account != nil
+}
+
+// ApiThrottle 是 API 服务的入站限流，健康检查和监控指标不限流
+var ApiThrottle = NewThrottle(TokenRateLimit, IPRateLimit, validToken, "/health", "/metrics")
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"cached_proxy/ratelimit"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestThrottle(t *testing.T) {
+	// 速率很低，测试期间不会补充令牌
+	limit := ratelimit.Limit{Rate: 0.001, Burst: 2}
+	valid := func(token string) bool { return token == "a" || token == "b" }
+	handler := NewThrottle(limit, limit, valid, "/health").Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := func(path string, remoteAddr string, authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = remoteAddr
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		remoteAddr    string
+		authorization string
+		status        int
+	}{
+		{"FirstIP", "/info", "10.0.0.1:1234", "", http.StatusNoContent},
+		{"SameIPOtherPort", "/feeds/abc", "10.0.0.1:5678", "", http.StatusNoContent},
+		{"IPExhausted", "/info", "10.0.0.1:1234", "", http.StatusTooManyRequests},
+		{"OtherIP", "/info", "10.0.0.2:1234", "", http.StatusNoContent},
+		{"TokenFromExhaustedIP", "/info", "10.0.0.1:1234", "Bearer a", http.StatusTooManyRequests},
+		{"Token", "/info", "10.0.0.3:1234", "Bearer a", http.StatusNoContent},
+		{"TokenOtherIP", "/info", "10.0.0.4:1234", "Bearer a", http.StatusNoContent},
+		{"TokenExhausted", "/scores", "10.0.0.5:1234", "Bearer a", http.StatusTooManyRequests},
+		{"OtherToken", "/info", "10.0.0.6:1234", "Bearer b", http.StatusNoContent},
+		{"Exempt", "/health", "10.0.0.1:1234", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		w := request(tt.path, tt.remoteAddr, tt.authorization)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+		if tt.status == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: missing Retry-After", tt.name)
+		}
+	}
+}
+
+func TestThrottle_BogusTokens(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 0.001, Burst: 2}
+	validated := 0
+	valid := func(token string) bool {
+		validated++
+		return false
+	}
+	handler := NewThrottle(limit, limit, valid).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// 每次换一个伪造的令牌，仍然按 IP 限流
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = "10.0.0.9:1234"
+		r.Header.Set("Authorization", fmt.Sprintf("Bearer x%d", i))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("request %d: expected status %d, got %d", i, want, w.Code)
+		}
+	}
+	// 被 IP 限流的请求不再查询令牌
+	if validated != 2 {
+		t.Errorf("expected 2 token lookups, got %d", validated)
+	}
+}
@@ -0,0 +1,276 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter 将结束的 span 发送到追踪后端
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// BatchProcessor 在后台批量导出 span，队列满时丢弃新的 span，不阻塞请求
+type BatchProcessor struct {
+	exporter  Exporter
+	queue     chan SpanData
+	batchSize int
+	interval  time.Duration
+	flush     chan chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBatchProcessor 创建并启动 BatchProcessor，每 interval 或者攒够 batchSize 个 span 时导出一次
+func NewBatchProcessor(exporter Exporter, queueSize, batchSize int, interval time.Duration) *BatchProcessor {
+	p := &BatchProcessor{
+		exporter:  exporter,
+		queue:     make(chan SpanData, queueSize),
+		batchSize: batchSize,
+		interval:  interval,
+		flush:     make(chan chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// OnEnd 将 span 放入队列
+func (p *BatchProcessor) OnEnd(span SpanData) {
+	select {
+	case p.queue <- span:
+	default:
+		slog.Debug("trace queue is full, dropping span", "span", span.Name)
+	}
+}
+
+// Shutdown 导出队列中剩余的 span 并停止后台任务，ctx 结束时放弃等待
+func (p *BatchProcessor) Shutdown(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case p.flush <- flushed:
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *BatchProcessor) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	batch := make([]SpanData, 0, p.batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := p.exporter.Export(ctx, batch); err != nil {
+			slog.Warn("failed to export spans", "count", len(batch), "error", err)
+		}
+		batch = make([]SpanData, 0, p.batchSize)
+	}
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case flushed := <-p.flush:
+			// 停止前取出队列中已有的 span
+			for len(p.queue) > 0 {
+				if batch = append(batch, <-p.queue); len(batch) >= p.batchSize {
+					export()
+				}
+			}
+			export()
+			close(p.done)
+			close(flushed)
+			return
+		}
+	}
+}
+
+// otlpRequest 是 OTLP/HTTP 的 JSON 请求，也用于标准输出
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue 是 OTLP 的 AnyValue，64 位整数按照 protobuf 的 JSON 映射使用字符串
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func toOTLPValue(value any) otlpValue {
+	switch v := value.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return otlpValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpValue{IntValue: &s}
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return otlpValue{StringValue: &s}
+	}
+}
+
+func toOTLPAttributes(attrs []Attribute) []otlpAttribute {
+	result := make([]otlpAttribute, 0, len(attrs))
+	for _, attr := range attrs {
+		result = append(result, otlpAttribute{Key: attr.Key, Value: toOTLPValue(attr.Value)})
+	}
+	return result
+}
+
+// encodeOTLP 将 span 转换为 OTLP 的请求，service 为 service.name 资源属性
+func encodeOTLP(service string, spans []SpanData) otlpRequest {
+	converted := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.SpanContext.TraceID.String(),
+			SpanID:            span.SpanContext.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        toOTLPAttributes(span.Attributes),
+			Status:            otlpStatus{Code: span.Status, Message: span.StatusMessage},
+		}
+		if span.Parent.IsValid() {
+			s.ParentSpanID = span.Parent.String()
+		}
+		converted = append(converted, s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: toOTLPAttributes([]Attribute{{Key: "service.name", Value: service}})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: converted}},
+	}}}
+}
+
+// scopeName 是产生 span 的库的名字
+const scopeName = "cached_proxy/tracing"
+
+// StdoutExporter 每批 span 输出一行 OTLP JSON，用于调试或者由日志收集器转发
+type StdoutExporter struct {
+	service string
+	mu      sync.Mutex
+	w       io.Writer
+}
+
+// NewStdoutExporter 创建输出到 w 的 StdoutExporter
+func NewStdoutExporter(service string, w io.Writer) *StdoutExporter {
+	return &StdoutExporter{service: service, w: w}
+}
+
+func (e *StdoutExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return json.NewEncoder(e.w).Encode(encodeOTLP(e.service, spans))
+}
+
+// OTLPExporter 通过 OTLP/HTTP 的 JSON 编码发送 span，例如发送到本地的 OpenTelemetry Collector
+type OTLPExporter struct {
+	service  string
+	endpoint string
+	client   *http.Client
+}
+
+// NewOTLPExporter 创建 OTLPExporter，endpoint 为 Collector 的地址，例如 http://localhost:4318，span 发送到 /v1/traces
+func NewOTLPExporter(service, endpoint string, client *http.Client) *OTLPExporter {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	return &OTLPExporter{service: service, endpoint: endpoint, client: client}
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(encodeOTLP(e.service, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testSpans() []SpanData {
+	root := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}, Sampled: true}
+	child := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{3}, Sampled: true}
+	start := time.Unix(1700000000, 0)
+	return []SpanData{
+		{Name: "GET /info", Kind: KindServer, SpanContext: root, Start: start, End: start.Add(time.Second),
+			Attributes: []Attribute{{"http.response.status_code", 200}, {"ok", true}, {"ratio", 0.5}}},
+		{Name: "cache.lookup", Kind: KindInternal, SpanContext: child, Parent: root.SpanID, Start: start, End: start.Add(time.Millisecond),
+			Status: StatusError, StatusMessage: "boom"},
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]any
+	var path, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter("proxy", server.URL+"/", server.Client())
+	if err := exporter.Export(context.Background(), testSpans()); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if path != "/v1/traces" || contentType != "application/json" {
+		t.Errorf("unexpected request %s %s", path, contentType)
+	}
+	resource := body["resourceSpans"].([]any)[0].(map[string]any)
+	service := resource["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	if service["key"] != "service.name" || service["value"].(map[string]any)["stringValue"] != "proxy" {
+		t.Errorf("unexpected resource %v", service)
+	}
+	spans := resource["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	root, child := spans[0].(map[string]any), spans[1].(map[string]any)
+	tests := []struct {
+		got  any
+		want any
+	}{
+		{root["traceId"], "01000000000000000000000000000000"},
+		{root["spanId"], "0200000000000000"},
+		{root["parentSpanId"], nil},
+		{root["kind"], float64(KindServer)},
+		{root["startTimeUnixNano"], "1700000000000000000"},
+		{root["attributes"].([]any)[0].(map[string]any)["value"].(map[string]any)["intValue"], "200"},
+		{root["attributes"].([]any)[1].(map[string]any)["value"].(map[string]any)["boolValue"], true},
+		{root["attributes"].([]any)[2].(map[string]any)["value"].(map[string]any)["doubleValue"], 0.5},
+		{child["parentSpanId"], "0200000000000000"},
+		{child["status"].(map[string]any)["code"], float64(StatusError)},
+		{child["status"].(map[string]any)["message"], "boom"},
+	}
+	for i, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%d: got %v, want %v", i, tt.got, tt.want)
+		}
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := NewOTLPExporter("proxy", failing.URL+"/v1/traces", failing.Client()).Export(context.Background(), testSpans()); err == nil {
+		t.Error("non-2xx response should be an error")
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewStdoutExporter("proxy", &buf).Export(context.Background(), testSpans()); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	var request otlpRequest
+	if err := json.Unmarshal(buf.Bytes(), &request); err != nil || len(request.ResourceSpans[0].ScopeSpans[0].Spans) != 2 {
+		t.Errorf("expected one JSON line with 2 spans, got %s %v", buf.String(), err)
+	}
+}
+
+// exporterFunc 将函数作为 Exporter
+type exporterFunc func(ctx context.Context, spans []SpanData) error
+
+func (f exporterFunc) Export(ctx context.Context, spans []SpanData) error {
+	return f(ctx, spans)
+}
+
+func TestBatchProcessor(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]SpanData
+	exporter := exporterFunc(func(_ context.Context, spans []SpanData) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, spans)
+		return errors.New("ignored")
+	})
+	processor := NewBatchProcessor(exporter, 10, 2, time.Hour)
+	for _, span := range append(testSpans(), testSpans()[0]) {
+		processor.OnEnd(span)
+	}
+	if err := processor.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if err := processor.Shutdown(context.Background()); err != nil {
+		t.Errorf("second shutdown should be a no-op, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Errorf("expected a full batch and the rest flushed on shutdown, got %v", batches)
+	}
+}
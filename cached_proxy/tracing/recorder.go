@@ -0,0 +1,32 @@
+package tracing
+
+import "sync"
+
+// Recorder 是在内存中保存结束的 span 的 Processor，用于测试
+type Recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *Recorder) OnEnd(span SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// Spans 返回按结束顺序排列的 span
+func (r *Recorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SpanData(nil), r.spans...)
+}
+
+// Attribute 返回 span 中名为 key 的属性，没有时返回 nil
+func (d SpanData) Attribute(key string) any {
+	for i := len(d.Attributes) - 1; i >= 0; i-- {
+		if d.Attributes[i].Key == key {
+			return d.Attributes[i].Value
+		}
+	}
+	return nil
+}
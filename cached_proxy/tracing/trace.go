@@ -0,0 +1,298 @@
+// Package tracing 实现分布式追踪，使用 W3C Trace Context 传播，可以通过 OTLP/HTTP 导出到 OpenTelemetry Collector
+//
+// 只实现了服务需要的部分：span 的父子关系、属性、状态和按父 span 采样，没有依赖 OpenTelemetry 的 SDK。
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID 是追踪的 ID
+type TraceID [16]byte
+
+// SpanID 是 span 的 ID
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid 全为 0 的 ID 无效
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// IsValid 全为 0 的 ID 无效
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext 是需要跨进程传播的 span 信息
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid 判断 TraceID 和 SpanID 是否都有效
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// TraceparentHeader 是 W3C Trace Context 的请求头
+const TraceparentHeader = "traceparent"
+
+// Traceparent 返回 traceparent 请求头的值
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent 解析 traceparent 请求头，格式错误或 ID 无效时返回 false
+func ParseTraceparent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	// 版本 00 只有四个部分，未来的版本可能在后面追加字段
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+	var sc SpanContext
+	var flags [1]byte
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, false
+	}
+	if _, err := hex.DecodeString(parts[0]); err != nil || !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// decodeHex 将小写十六进制字符串解码到 dst，长度必须与 dst 一致
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// SpanKind 是 span 的类型，取值与 OTLP 相同
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// StatusCode 是 span 的状态，取值与 OTLP 相同
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute 是 span 的属性，Value 可以是 string、bool、int、int64 或 float64
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// SpanData 是结束后的 span，交给 Exporter 导出
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Span 是正在进行的操作，结束时调用 End
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SpanContext 返回需要传播的 span 信息，span 为 nil 时返回无效的 SpanContext
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttributes 设置属性，结束后的设置被忽略
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Attributes = append(s.data.Attributes, attrs...)
+	}
+}
+
+// SetAttribute 设置一个属性
+func (s *Span) SetAttribute(key string, value any) {
+	s.SetAttributes(Attribute{Key: key, Value: value})
+}
+
+// SetStatus 设置 span 的状态
+func (s *Span) SetStatus(code StatusCode, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Status, s.data.StatusMessage = code, message
+	}
+}
+
+// RecordError 在 err 不为 nil 时将 span 标记为出错
+func (s *Span) RecordError(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	}
+}
+
+// End 结束 span，被采样的 span 交给 Tracer 导出，重复调用没有效果
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt 在指定的时间结束 span
+func (s *Span) EndAt(end time.Time) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = end
+	data := s.data
+	s.mu.Unlock()
+	if data.SpanContext.Sampled && s.tracer.processor != nil {
+		s.tracer.processor.OnEnd(data)
+	}
+}
+
+// Processor 接收结束的 span
+type Processor interface {
+	OnEnd(span SpanData)
+}
+
+// Tracer 创建 span，没有 Processor 时仍然生成 ID 并传播，但不采样
+type Tracer struct {
+	processor Processor
+}
+
+// NewTracer 创建 Tracer，processor 为 nil 时不导出任何 span
+func NewTracer(processor Processor) *Tracer {
+	return &Tracer{processor: processor}
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultTracer = NewTracer(nil)
+)
+
+// SetDefault 设置 Start 使用的 Tracer
+func SetDefault(t *Tracer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTracer = t
+}
+
+// Default 返回 Start 使用的 Tracer
+func Default() *Tracer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultTracer
+}
+
+// Start 使用默认的 Tracer 创建 span
+func Start(ctx context.Context, name string, options ...Option) (context.Context, *Span) {
+	return Default().Start(ctx, name, options...)
+}
+
+// Option 是创建 span 的选项
+type Option func(*SpanData)
+
+// WithKind 设置 span 的类型，默认为 KindInternal
+func WithKind(kind SpanKind) Option {
+	return func(d *SpanData) { d.Kind = kind }
+}
+
+// WithStartTime 设置 span 的开始时间，默认为现在，用于记录已经开始的等待
+func WithStartTime(start time.Time) Option {
+	return func(d *SpanData) { d.Start = start }
+}
+
+// WithAttributes 设置 span 的初始属性
+func WithAttributes(attrs ...Attribute) Option {
+	return func(d *SpanData) { d.Attributes = append(d.Attributes, attrs...) }
+}
+
+// Start 创建 ctx 中的 span 的子 span，没有父 span 时开始新的追踪，返回携带新 span 的上下文
+//
+// 有父 span 时沿用父 span 的采样决定，否则在有 Processor 时采样。
+func (t *Tracer) Start(ctx context.Context, name string, options ...Option) (context.Context, *Span) {
+	data := SpanData{Name: name, Kind: KindInternal, Start: time.Now()}
+	for _, option := range options {
+		option(&data)
+	}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		data.SpanContext.TraceID, data.Parent, data.SpanContext.Sampled = parent.TraceID, parent.SpanID, parent.Sampled
+	} else {
+		data.SpanContext.TraceID, data.SpanContext.Sampled = newTraceID(), t.processor != nil
+	}
+	data.SpanContext.SpanID = newSpanID()
+	span := &Span{tracer: t, data: data}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// SpanFromContext 返回 ctx 中的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext 返回以其他进程的 span 为父 span 的上下文，用于接收 traceparent
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext 返回 ctx 中的 span 的信息，没有本地 span 时返回远程的父 span
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header  string
+		ok      bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		// 未来的版本可以在后面追加字段
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		sc, ok := ParseTraceparent(tt.header)
+		if ok != tt.ok || sc.Sampled != tt.sampled {
+			t.Errorf("ParseTraceparent(%q) = %+v %v, want ok %v sampled %v", tt.header, sc, ok, tt.ok, tt.sampled)
+		}
+	}
+
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	if sc, _ := ParseTraceparent(header); sc.Traceparent() != header {
+		t.Errorf("Traceparent() = %s, want %s", sc.Traceparent(), header)
+	}
+}
+
+func TestTracer_Start(t *testing.T) {
+	recorder := &Recorder{}
+	tracer := NewTracer(recorder)
+
+	ctx, root := tracer.Start(context.Background(), "root", WithKind(KindServer))
+	_, child := tracer.Start(ctx, "child", WithAttributes(Attribute{Key: "k", Value: "v"}))
+	child.SetAttribute("n", 1)
+	child.End()
+	child.SetAttribute("late", true)
+	child.End()
+	root.End()
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.SpanContext.TraceID != r.SpanContext.TraceID || c.Parent != r.SpanContext.SpanID || r.Parent.IsValid() {
+		t.Errorf("child should belong to the root, got %+v %+v", c, r)
+	}
+	if c.Attribute("k") != "v" || c.Attribute("n") != 1 || c.Attribute("late") != nil {
+		t.Errorf("unexpected attributes %v", c.Attributes)
+	}
+	if r.Kind != KindServer || c.Kind != KindInternal || !r.SpanContext.Sampled {
+		t.Errorf("unexpected span %+v", r)
+	}
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	recorder := &Recorder{}
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	// 上游没有采样时不导出，但仍然沿用追踪 ID
+	_, span := NewTracer(recorder).Start(ContextWithRemoteSpanContext(context.Background(), remote), "unsampled")
+	span.End()
+	if span.SpanContext().TraceID != remote.TraceID || span.SpanContext().Sampled || len(recorder.Spans()) != 0 {
+		t.Errorf("unsampled parent should not be exported, got %+v", span.SpanContext())
+	}
+
+	// 没有 Processor 时仍然生成 ID 用于传播
+	_, span = NewTracer(nil).Start(context.Background(), "disabled")
+	span.End()
+	if !span.SpanContext().IsValid() || span.SpanContext().Sampled {
+		t.Errorf("disabled tracer should create unsampled spans, got %+v", span.SpanContext())
+	}
+
+	start := time.Now().Add(-time.Second)
+	_, span = NewTracer(recorder).Start(context.Background(), "waited", WithStartTime(start))
+	span.RecordError(nil)
+	span.End()
+	if got := recorder.Spans(); len(got) != 1 || !got[0].Start.Equal(start) || got[0].Status != StatusUnset {
+		t.Errorf("unexpected span %+v", got)
+	}
+}
+
+func TestSpanContextFromContext(t *testing.T) {
+	if sc := SpanContextFromContext(context.Background()); sc.IsValid() {
+		t.Errorf("empty context should not have a span, got %+v", sc)
+	}
+	if SpanFromContext(context.Background()).SpanContext().IsValid() {
+		t.Error("nil span should have an invalid span context")
+	}
+	ctx, span := NewTracer(nil).Start(context.Background(), "span")
+	if SpanFromContext(ctx) != span || SpanContextFromContext(ctx) != span.SpanContext() {
+		t.Error("span should be stored in the context")
+	}
+}
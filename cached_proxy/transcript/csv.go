@@ -0,0 +1,28 @@
+package transcript
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// utf8BOM 让 Excel 以 UTF-8 打开 CSV 文件
+const utf8BOM = "\ufeff"
+
+// WriteCSV 将成绩单导出为 CSV
+func (t *Transcript) WriteCSV(w io.Writer) error {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	for _, row := range t.table() {
+		record := make([]string, len(row))
+		for i, c := range row {
+			record[i] = c.text
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,193 @@
+package transcript
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Font 是嵌入 PDF 的 TrueType 字体，只支持 glyf 轮廓的 .ttf 文件，不支持 CFF 轮廓的 .otf 和字体集合 .ttc
+type Font struct {
+	name       string
+	tables     map[string][]byte // 字体中的表，按标签索引，用于生成子集
+	numGlyphs  int
+	longLoca   bool // loca 表是否使用 32 位偏移
+	unitsPerEm int
+	bbox       [4]int
+	ascent     int
+	descent    int
+	glyphs     map[rune]uint16
+	advances   []uint16
+}
+
+// errMalformedFont 表示字体文件的结构不完整
+var errMalformedFont = errors.New("malformed font")
+
+// defaultFontData 是随服务发布的文泉驿微米黑，Apache License 2.0，见 fonts/README.md
+//
+//go:embed fonts/wqy-microhei.ttf
+var defaultFontData []byte
+
+// DefaultFont 返回随服务发布的中文字体，只在第一次调用时解析
+var DefaultFont = sync.OnceValues(func() (*Font, error) {
+	return ParseFont("WenQuanYiMicroHei", defaultFontData)
+})
+
+// LoadFont 读取 TrueType 字体文件
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return ParseFont(name, data)
+}
+
+// ParseFont 解析 TrueType 字体，读取 PDF 需要的度量和字符到字形的映射
+func ParseFont(name string, data []byte) (font *Font, err error) {
+	// 字体中的偏移量不可信，越界时作为格式错误返回
+	defer func() {
+		if recover() != nil {
+			font, err = nil, errMalformedFont
+		}
+	}()
+	switch string(data[:4]) {
+	case "OTTO":
+		return nil, fmt.Errorf("CFF based OpenType fonts are not supported")
+	case "ttcf":
+		return nil, fmt.Errorf("font collections are not supported")
+	}
+	tables := map[string][]byte{}
+	for i, n := 0, u16(data, 4); i < n; i++ {
+		record := data[12+16*i:]
+		offset, length := int(binary.BigEndian.Uint32(record[8:])), int(binary.BigEndian.Uint32(record[12:]))
+		tables[string(record[:4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "cmap", "glyf", "loca", "maxp"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("missing %s table", tag)
+		}
+	}
+	head, hhea, hmtx := tables["head"], tables["hhea"], tables["hmtx"]
+	font = &Font{
+		name:       sanitizeFontName(name),
+		tables:     tables,
+		numGlyphs:  u16(tables["maxp"], 4),
+		longLoca:   s16(head, 50) == 1,
+		unitsPerEm: u16(head, 18),
+		bbox:       [4]int{s16(head, 36), s16(head, 38), s16(head, 40), s16(head, 42)},
+		ascent:     s16(hhea, 4),
+		descent:    s16(hhea, 6),
+	}
+	if font.unitsPerEm == 0 || len(tables["loca"]) < font.locaSize() {
+		return nil, errMalformedFont
+	}
+	for i, n := 0, u16(hhea, 34); i < n; i++ {
+		font.advances = append(font.advances, uint16(u16(hmtx, 4*i)))
+	}
+	if font.glyphs, err = parseCmap(tables["cmap"]); err != nil {
+		return nil, err
+	}
+	return font, nil
+}
+
+// parseCmap 读取 Unicode 的 cmap 子表，优先使用支持完整 Unicode 的格式 12
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	var format4, format12 []byte
+	for i, n := 0, u16(cmap, 2); i < n; i++ {
+		record := cmap[4+8*i:]
+		platform, encoding := u16(record, 0), u16(record, 2)
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		table := cmap[binary.BigEndian.Uint32(record[4:]):]
+		switch u16(table, 0) {
+		case 4:
+			format4 = table
+		case 12:
+			format12 = table
+		}
+	}
+	glyphs := map[rune]uint16{}
+	switch {
+	case format12 != nil:
+		for i, n := 0, int(binary.BigEndian.Uint32(format12[12:])); i < n; i++ {
+			group := format12[16+12*i:]
+			start, end := binary.BigEndian.Uint32(group), binary.BigEndian.Uint32(group[4:])
+			glyph := binary.BigEndian.Uint32(group[8:])
+			if end < start || end-start > 0x10FFFF {
+				return nil, errMalformedFont
+			}
+			for c := start; c <= end; c++ {
+				glyphs[rune(c)] = uint16(glyph + c - start)
+			}
+		}
+	case format4 != nil:
+		segments := u16(format4, 6) / 2
+		ends, starts := 14, 16+2*segments
+		deltas, ranges := starts+2*segments, starts+4*segments
+		for i := 0; i < segments; i++ {
+			start, end := u16(format4, starts+2*i), u16(format4, ends+2*i)
+			delta, rangeOffset := u16(format4, deltas+2*i), u16(format4, ranges+2*i)
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				glyph := c + delta
+				if rangeOffset != 0 {
+					if glyph = u16(format4, ranges+2*i+rangeOffset+2*(c-start)); glyph == 0 {
+						continue
+					}
+					glyph += delta
+				}
+				if glyph&0xFFFF != 0 {
+					glyphs[rune(c)] = uint16(glyph)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("no unicode cmap")
+	}
+	return glyphs, nil
+}
+
+// width 返回字形的宽度，单位为 1/1000 字号
+func (f *Font) width(glyph uint16) int {
+	if len(f.advances) == 0 {
+		return 1000
+	}
+	advance := f.advances[len(f.advances)-1]
+	if int(glyph) < len(f.advances) {
+		advance = f.advances[glyph]
+	}
+	return int(advance) * 1000 / f.unitsPerEm
+}
+
+// scale 将字体单位换算为 1/1000 字号
+func (f *Font) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// sanitizeFontName 生成 PDF 中可以使用的字体名称
+func sanitizeFontName(name string) string {
+	b := strings.Builder{}
+	for _, r := range name {
+		if r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "EmbeddedFont"
+	}
+	return b.String()
+}
+
+func u16(b []byte, offset int) int {
+	return int(binary.BigEndian.Uint16(b[offset:]))
+}
+
+func s16(b []byte, offset int) int {
+	return int(int16(binary.BigEndian.Uint16(b[offset:])))
+}
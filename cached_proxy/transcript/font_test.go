@@ -0,0 +1,92 @@
+package transcript
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// testFont 生成一个最小的 TrueType 字体，只有 "成" 一个字符，对应字形 1
+//
+// 字形 1 是引用字形 2 的复合字形，字形 3 没有被任何字符使用。
+func testFont() []byte {
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[18:], 2048)
+	for i, v := range []int16{-100, -400, 2000, 1800} {
+		binary.BigEndian.PutUint16(head[36+2*i:], uint16(v))
+	}
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint16(hhea[4:], 1800)
+	binary.BigEndian.PutUint16(hhea[6:], uint16(0xFFFF-400+1))
+	binary.BigEndian.PutUint16(hhea[34:], 2)
+	hmtx := make([]byte, 12)
+	binary.BigEndian.PutUint16(hmtx[0:], 2048)
+	binary.BigEndian.PutUint16(hmtx[4:], 1024)
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	binary.BigEndian.PutUint16(maxp[4:], 4)
+	// 字形 0、2、3 是 12 字节的简单字形，字形 1 的组件没有缩放，参数为两个字节
+	simple := func(b byte) []byte { return []byte{0, 1, 0, 0, 0, 0, 0, b, 0, b, 0, 0} }
+	composite := []byte{0xFF, 0xFF, 0, 0, 0, 0, 0, 9, 0, 9, 0, 0, 0, 2, 0, 0}
+	glyf := append(append(append(simple(1), composite...), simple(2)...), simple(3)...)
+	loca := make([]byte, 10)
+	for i, v := range []uint16{0, 6, 14, 20, 26} {
+		binary.BigEndian.PutUint16(loca[2*i:], v)
+	}
+	// cmap 格式 4，两个分段：0x6210 和结束标记 0xFFFF
+	cmap := make([]byte, 12+32)
+	binary.BigEndian.PutUint16(cmap[2:], 1)
+	binary.BigEndian.PutUint16(cmap[4:], 3)
+	binary.BigEndian.PutUint16(cmap[6:], 1)
+	binary.BigEndian.PutUint32(cmap[8:], 12)
+	sub := cmap[12:]
+	for i, v := range []uint16{4, 32, 0, 4, 4, 1, 0, 0x6210, 0xFFFF, 0, 0x6210, 0xFFFF, 0x10001 - 0x6210, 1, 0, 0} {
+		binary.BigEndian.PutUint16(sub[2*i:], v)
+	}
+	return writeSfnt(map[string][]byte{"cmap": cmap, "glyf": glyf, "head": head, "hhea": hhea, "hmtx": hmtx, "loca": loca, "maxp": maxp})
+}
+
+func TestParseFont(t *testing.T) {
+	font, err := ParseFont("思源宋体 Bold", testFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if font.name != "Bold" {
+		t.Errorf("unexpected name %s", font.name)
+	}
+	if font.glyphs['成'] != 1 || len(font.glyphs) != 1 {
+		t.Errorf("unexpected glyphs %v", font.glyphs)
+	}
+	if font.width(0) != 1000 || font.width(1) != 500 || font.width(5) != 500 {
+		t.Errorf("unexpected widths %d %d %d", font.width(0), font.width(1), font.width(5))
+	}
+	if font.scale(font.descent) != -195 || font.scale(font.bbox[2]) != 976 {
+		t.Errorf("unexpected metrics %d %d", font.scale(font.descent), font.scale(font.bbox[2]))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", nil},
+		{"Truncated", testFont()[:40]},
+		{"OpenType", append([]byte("OTTO"), testFont()[4:]...)},
+		{"Collection", append([]byte("ttcf"), testFont()[4:]...)},
+		{"MissingTable", func() []byte {
+			data := testFont()
+			copy(data[12+16:], "xxxx")
+			return data
+		}()},
+		{"TruncatedLoca", func() []byte {
+			font, _ := ParseFont("test", testFont())
+			font.tables["loca"] = font.tables["loca"][:4]
+			return writeSfnt(font.tables)
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if font, err := ParseFont("test", tt.data); err == nil {
+				t.Errorf("expected error, got %+v", font)
+			}
+		})
+	}
+}
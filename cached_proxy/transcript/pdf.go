@@ -0,0 +1,295 @@
+package transcript
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 纸张的尺寸和页面布局，单位为 pt
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 50
+	lineHeight   = 16
+	bodySize     = 10
+	maxNameRunes = 24
+)
+
+// pdfColumns 是成绩表格每一列的横坐标
+var pdfColumns = []int{pageMargin, pageMargin + 20, 330, 410, 460, 510}
+
+// pdfFont 将文本编码为 PDF 字符串，并生成字体对象
+type pdfFont interface {
+	// encode 将文本编码为十六进制字符串
+	encode(s string) string
+	// objects 添加字体需要的对象，返回 Type0 字体的引用
+	objects(d *pdfDocument) int
+}
+
+// standardFont 是 Adobe 预置的宋体，不嵌入字体文件，需要阅读器安装亚洲语言字体包
+type standardFont struct{}
+
+func (standardFont) encode(s string) string {
+	b := strings.Builder{}
+	for _, r := range s {
+		// UCS-2 编码只支持基本多文种平面
+		if r > 0xFFFF {
+			r = '?'
+		}
+		b.WriteString(utf16Hex(r))
+	}
+	return "<" + b.String() + ">"
+}
+
+// utf16Hex 返回字符的 UTF-16BE 编码的十六进制表示
+func utf16Hex(r rune) string {
+	b := strings.Builder{}
+	for _, unit := range utf16.Encode([]rune{r}) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	return b.String()
+}
+
+func (standardFont) objects(d *pdfDocument) int {
+	descriptor := d.add("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	cid := d.add(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor %d 0 R /DW 1000 >>", descriptor))
+	return d.add(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [%d 0 R] >>", cid))
+}
+
+// embeddedFont 使用 Identity-H 编码嵌入 TrueType 字体的子集，并记录用到的字形用于生成子集、宽度和 ToUnicode
+type embeddedFont struct {
+	*Font
+	used map[uint16]rune
+}
+
+func (f *embeddedFont) encode(s string) string {
+	b := strings.Builder{}
+	for _, r := range s {
+		glyph := f.glyphs[r]
+		if _, found := f.used[glyph]; !found && glyph != 0 {
+			f.used[glyph] = r
+		}
+		fmt.Fprintf(&b, "%04X", glyph)
+	}
+	return "<" + b.String() + ">"
+}
+
+func (f *embeddedFont) objects(d *pdfDocument) int {
+	glyphs := make([]int, 0, len(f.used))
+	for glyph := range f.used {
+		glyphs = append(glyphs, int(glyph))
+	}
+	sort.Ints(glyphs)
+	widths, toUnicode := strings.Builder{}, strings.Builder{}
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", glyph, f.width(uint16(glyph)))
+		fmt.Fprintf(&toUnicode, "<%04X> <%s>\n", glyph, utf16Hex(f.used[uint16(glyph)]))
+	}
+	data := f.subset(f.used)
+	file := d.addStream(fmt.Sprintf("/Length1 %d", len(data)), data)
+	// 子集字体的名称需要加上 6 个大写字母的前缀
+	name := subsetTag(glyphs) + "+" + f.name
+	descriptor := d.add(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.ascent), file))
+	cid := d.add(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R "+
+		"/CIDToGIDMap /Identity /DW 1000 /W [%s] >>", name, descriptor, widths.String()))
+	cmap := d.addStream("", []byte("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n"+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n"+
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n"+bfChars(toUnicode.String())+
+		"endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n"))
+	return d.add(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, cid, cmap))
+}
+
+// subsetTag 根据子集包含的字形生成 6 个大写字母的前缀，相同的子集使用相同的前缀
+func subsetTag(glyphs []int) string {
+	hash := fnv.New32a()
+	for _, glyph := range glyphs {
+		_, _ = fmt.Fprintf(hash, "%d,", glyph)
+	}
+	sum := hash.Sum32()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = byte('A' + sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
+
+// bfChars 将映射按照每组最多 100 项分为多个 beginbfchar
+func bfChars(mappings string) string {
+	lines := strings.Split(strings.TrimSuffix(mappings, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return ""
+	}
+	b := strings.Builder{}
+	for i := 0; i < len(lines); i += 100 {
+		end := i + 100
+		if end > len(lines) {
+			end = len(lines)
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n%s\nendbfchar\n", end-i, strings.Join(lines[i:end], "\n"))
+	}
+	return b.String()
+}
+
+// pdfDocument 按顺序保存 PDF 的间接对象，对象编号从 1 开始
+type pdfDocument struct {
+	objects [][]byte
+}
+
+// reserve 预留一个对象编号，用于需要先被引用的对象
+func (d *pdfDocument) reserve() int {
+	d.objects = append(d.objects, nil)
+	return len(d.objects)
+}
+
+func (d *pdfDocument) set(n int, body string) {
+	d.objects[n-1] = []byte(body)
+}
+
+func (d *pdfDocument) add(body string) int {
+	n := d.reserve()
+	d.set(n, body)
+	return n
+}
+
+// addStream 添加使用 FlateDecode 压缩的流对象
+func (d *pdfDocument) addStream(dict string, data []byte) int {
+	compressed := bytes.Buffer{}
+	writer := zlib.NewWriter(&compressed)
+	_, _ = writer.Write(data)
+	_ = writer.Close()
+	n := d.reserve()
+	body := fmt.Sprintf("<< /Length %d /Filter /FlateDecode %s >>\nstream\n", compressed.Len(), dict)
+	d.objects[n-1] = append(append([]byte(body), compressed.Bytes()...), "\nendstream"...)
+	return n
+}
+
+// writeTo 输出 PDF 文件，包括交叉引用表和文件尾
+func (d *pdfDocument) writeTo(w io.Writer, root int) error {
+	out := bytes.Buffer{}
+	out.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(d.objects))
+	for i, object := range d.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(object)
+		out.WriteString("\nendobj\n")
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, root, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// pdfLayout 按行排版成绩单，空间不足时换页
+type pdfLayout struct {
+	font  pdfFont
+	pages []*strings.Builder
+	y     int
+}
+
+func (l *pdfLayout) newPage() {
+	l.pages = append(l.pages, &strings.Builder{})
+	l.y = pageHeight - pageMargin
+}
+
+// line 换到下一行，剩余空间小于 space 时换页
+func (l *pdfLayout) line(space int) {
+	if len(l.pages) == 0 || l.y-space < pageMargin {
+		l.newPage()
+	}
+	l.y -= lineHeight
+}
+
+func (l *pdfLayout) text(x int, size int, s string) {
+	fmt.Fprintf(l.pages[len(l.pages)-1], "BT /F1 %d Tf %d %d Td %s Tj ET\n", size, x, l.y, l.font.encode(s))
+}
+
+func (l *pdfLayout) rule() {
+	fmt.Fprintf(l.pages[len(l.pages)-1], "0.5 w %d %d m %d %d l S\n", pageMargin, l.y-4, pageWidth-pageMargin, l.y-4)
+}
+
+// WritePDF 将成绩单导出为 A4 的 PDF，嵌入 font 中用到的字形，font 为 nil 时使用不嵌入的 STSong-Light
+func (t *Transcript) WritePDF(w io.Writer, font *Font) error {
+	layout := &pdfLayout{font: standardFont{}}
+	if font != nil {
+		layout.font = &embeddedFont{Font: font, used: map[uint16]rune{}}
+	}
+	layout.line(0)
+	layout.text(pageWidth/2-36, 18, "成 绩 单")
+	layout.line(lineHeight)
+	for i, field := range t.header() {
+		if i%2 == 0 {
+			layout.line(lineHeight)
+		}
+		layout.text(pageMargin+(i%2)*250, bodySize, field[0]+"："+field[1])
+	}
+	for _, term := range t.Terms {
+		// 空一行，学期标题至少和表头、一门课程在同一页
+		layout.line(0)
+		layout.line(3 * lineHeight)
+		layout.text(pageMargin, 12, fmt.Sprintf("%s    已获学分 %s    绩点 %s", TermName(term.Term), formatNumber(term.Credit), formatNumber(term.GPA)))
+		layout.line(2 * lineHeight)
+		for i, name := range scoreHeader[1:] {
+			layout.text(pdfColumns[i+1], bodySize, name)
+		}
+		layout.rule()
+		for _, row := range term.Scores {
+			layout.line(lineHeight)
+			point := ""
+			if row.Counted {
+				point = formatNumber(row.Point)
+			}
+			for i, value := range []string{truncate(row.Name, maxNameRunes), row.Type, formatNumber(row.Credit), row.Score.Score, point} {
+				layout.text(pdfColumns[i+1], bodySize, value)
+			}
+		}
+	}
+	layout.line(3 * lineHeight)
+	for _, field := range t.summary() {
+		layout.line(lineHeight)
+		layout.text(pageMargin, bodySize, field[0]+"："+field[1])
+	}
+
+	d := &pdfDocument{}
+	catalog, pages := d.reserve(), d.reserve()
+	var kids []string
+	for i, ops := range layout.pages {
+		// 页码在排版结束后才能确定总页数
+		fmt.Fprintf(ops, "BT /F1 9 Tf %d %d Td %s Tj ET\n", pageWidth/2-20, pageMargin/2, layout.font.encode(fmt.Sprintf("%d / %d", i+1, len(layout.pages))))
+		content := d.addStream("", []byte(ops.String()))
+		page := d.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Contents %d 0 R >>", pages, content))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	fontRef := layout.font.objects(d)
+	d.set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R >> >> >>",
+		strings.Join(kids, " "), len(kids), pageWidth, pageHeight, fontRef))
+	d.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	return d.writeTo(w, catalog)
+}
+
+// truncate 截断过长的文本
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
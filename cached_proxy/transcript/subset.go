@@ -0,0 +1,202 @@
+package transcript
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// subsetTables 是子集中保留的表，PDF 中的 CIDFontType2 通过 /CIDToGIDMap /Identity 直接使用字形编号，不需要 cmap
+var subsetTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+// locaSize 返回 loca 表需要的长度
+func (f *Font) locaSize() int {
+	if f.longLoca {
+		return 4 * (f.numGlyphs + 1)
+	}
+	return 2 * (f.numGlyphs + 1)
+}
+
+// glyph 返回字形在 glyf 表中的数据，空字形或越界时返回 nil
+func (f *Font) glyph(glyph int) []byte {
+	if glyph >= f.numGlyphs {
+		return nil
+	}
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	var start, end int
+	if f.longLoca {
+		start, end = int(binary.BigEndian.Uint32(loca[4*glyph:])), int(binary.BigEndian.Uint32(loca[4*glyph+4:]))
+	} else {
+		start, end = 2*u16(loca, 2*glyph), 2*u16(loca, 2*glyph+2)
+	}
+	if start >= end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// 复合字形中每个组件的标志
+const (
+	argsAreWords    = 0x0001
+	haveScale       = 0x0008
+	moreComponents  = 0x0020
+	haveXYScale     = 0x0040
+	haveTwoByTwo    = 0x0080
+	compositeHeader = 10
+)
+
+// components 返回复合字形引用的字形，简单字形返回 nil
+func components(data []byte) []uint16 {
+	if len(data) < compositeHeader || s16(data, 0) >= 0 {
+		return nil
+	}
+	var glyphs []uint16
+	for p := compositeHeader; p+4 <= len(data); {
+		flags := u16(data, p)
+		glyphs = append(glyphs, uint16(u16(data, p+2)))
+		p += 4
+		if flags&argsAreWords != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			p += 2
+		case flags&haveXYScale != 0:
+			p += 4
+		case flags&haveTwoByTwo != 0:
+			p += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return glyphs
+}
+
+// subset 生成只包含 used 中字形的字体文件，字形编号保持不变，其余字形为空
+//
+// 字形 0 和复合字形引用的字形同样保留。完整的中文字体有几 MB，子集通常只有几十 KB。
+func (f *Font) subset(used map[uint16]rune) []byte {
+	keep := map[int]bool{0: true}
+	pending := []int{0}
+	for glyph := range used {
+		if !keep[int(glyph)] {
+			keep[int(glyph)] = true
+			pending = append(pending, int(glyph))
+		}
+	}
+	for len(pending) > 0 {
+		glyph := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, component := range components(f.glyph(glyph)) {
+			if !keep[int(component)] {
+				keep[int(component)] = true
+				pending = append(pending, int(component))
+			}
+		}
+	}
+
+	// 编号大于所有保留字形的字形直接去掉，子集统一使用 32 位的 loca，每个字形按 4 字节对齐
+	numGlyphs := 0
+	for glyph := range keep {
+		numGlyphs = max(numGlyphs, glyph+1)
+	}
+	loca := make([]byte, 4*(numGlyphs+1))
+	var glyf []byte
+	for glyph := 0; glyph < numGlyphs; glyph++ {
+		binary.BigEndian.PutUint32(loca[4*glyph:], uint32(len(glyf)))
+		if keep[glyph] {
+			glyf = append(glyf, f.glyph(glyph)...)
+			glyf = append(glyf, make([]byte, pad4(len(glyf)))...)
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*numGlyphs:], uint32(len(glyf)))
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint16(head[50:], 1)
+	maxp := append([]byte(nil), f.tables["maxp"]...)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(numGlyphs))
+
+	// 空字形的左侧空白没有意义，置为 0 后 hmtx 表压缩后很小
+	hhea := append([]byte(nil), f.tables["hhea"]...)
+	metrics := min(u16(hhea, 34), numGlyphs)
+	binary.BigEndian.PutUint16(hhea[34:], uint16(metrics))
+	hmtx := make([]byte, 2*(metrics+numGlyphs))
+	copy(hmtx, f.tables["hmtx"])
+	for glyph := 0; glyph < numGlyphs; glyph++ {
+		lsb := 4*metrics + 2*(glyph-metrics)
+		if glyph < metrics {
+			lsb = 4*glyph + 2
+		}
+		if !keep[glyph] {
+			binary.BigEndian.PutUint16(hmtx[lsb:], 0)
+		}
+	}
+
+	tables := map[string][]byte{}
+	for _, tag := range subsetTables {
+		if data, found := f.tables[tag]; found {
+			tables[tag] = data
+		}
+	}
+	tables["glyf"], tables["loca"], tables["head"] = glyf, loca, head
+	tables["maxp"], tables["hhea"], tables["hmtx"] = maxp, hhea, hmtx
+	return writeSfnt(tables)
+}
+
+// writeSfnt 按照 OpenType 的格式写出字体文件，并计算各个表和整个文件的校验和
+func writeSfnt(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= len(tags) {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+	out := make([]byte, 12+16*len(tags))
+	binary.BigEndian.PutUint32(out, 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(16*len(tags)-searchRange))
+	headOffset := 0
+	for i, tag := range tags {
+		data := tables[tag]
+		if tag == "head" {
+			// 计算整个文件的校验和之前 checkSumAdjustment 需要为 0
+			data = append([]byte(nil), data...)
+			binary.BigEndian.PutUint32(data[8:], 0)
+			headOffset = len(out)
+		}
+		record := out[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], checksum(data))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(data)))
+		out = append(out, data...)
+		out = append(out, make([]byte, pad4(len(data)))...)
+	}
+	if headOffset > 0 {
+		binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-checksum(out))
+	}
+	return out
+}
+
+// checksum 按照 32 位大端整数求和，不足 4 字节的部分补 0
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		word := [4]byte{}
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// pad4 返回对齐到 4 字节需要补充的长度
+func pad4(n int) int {
+	return (4 - n%4) % 4
+}
@@ -0,0 +1,80 @@
+package transcript
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+)
+
+// readTables 读取字体文件的表，并校验每个表和整个文件的校验和
+func readTables(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	if sum := checksum(data); sum != 0xB1B0AFBA {
+		t.Errorf("file checksum = %#x, want 0xB1B0AFBA", sum)
+	}
+	tables := map[string][]byte{}
+	for i, n := 0, u16(data, 4); i < n; i++ {
+		record := data[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(record[8:]), binary.BigEndian.Uint32(record[12:])
+		table := data[offset : offset+length]
+		tag := string(record[:4])
+		if sum := checksum(table); tag != "head" && sum != binary.BigEndian.Uint32(record[4:]) {
+			t.Errorf("%s checksum = %#x, want %#x", tag, sum, binary.BigEndian.Uint32(record[4:]))
+		}
+		tables[tag] = table
+	}
+	return tables
+}
+
+func TestFont_Subset(t *testing.T) {
+	font, err := ParseFont("test", testFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := readTables(t, font.subset(map[uint16]rune{1: '成'}))
+	if tables["cmap"] != nil || tables["loca"] == nil || s16(tables["head"], 50) != 1 {
+		t.Fatalf("unexpected tables in subset")
+	}
+	subset := &Font{tables: tables, numGlyphs: u16(tables["maxp"], 4), longLoca: true}
+	// 字形 1 引用的字形 2 和 .notdef 同样保留，没有使用的字形 3 为空
+	for glyph, keep := range []bool{true, true, true, false} {
+		want := font.glyph(glyph)
+		if !keep {
+			want = nil
+		}
+		if got := subset.glyph(glyph); !bytes.Equal(got, want) {
+			t.Errorf("glyph %d = %v, want %v", glyph, got, want)
+		}
+	}
+}
+
+func TestDefaultFont_Subset(t *testing.T) {
+	font, err := DefaultFont()
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := map[uint16]rune{}
+	for _, r := range "成绩单 学号：2021 高等数学 Ａ" {
+		if glyph := font.glyphs[r]; glyph != 0 {
+			used[glyph] = r
+		} else {
+			t.Errorf("bundled font has no glyph for %q", r)
+		}
+	}
+	data := font.subset(used)
+	compressed := bytes.Buffer{}
+	writer := zlib.NewWriter(&compressed)
+	_, _ = writer.Write(data)
+	_ = writer.Close()
+	if compressed.Len() > 64*1024 {
+		t.Errorf("compressed subset should be small, got %d bytes", compressed.Len())
+	}
+	tables := readTables(t, data)
+	subset := &Font{tables: tables, numGlyphs: u16(tables["maxp"], 4), longLoca: true}
+	for glyph := range used {
+		if !bytes.Equal(subset.glyph(int(glyph)), font.glyph(int(glyph))) {
+			t.Errorf("glyph %d differs in subset", glyph)
+		}
+	}
+}
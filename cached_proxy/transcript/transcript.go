@@ -0,0 +1,240 @@
+// Package transcript 将成绩导出为 CSV、XLSX 和可打印的 PDF 成绩单
+package transcript
+
+import (
+	"cached_proxy/feign"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transcript 是用于导出的成绩单，成绩按照学期分组
+type Transcript struct {
+	StudentID    string
+	Name         string
+	College      string
+	Major        string
+	Class        string
+	EntranceDay  string
+	Terms        []Term
+	Credit       float64 // 已获得的学分
+	GPA          float64 // 按照 GradePoint 计算的学分加权绩点
+	OfficialGPA  string  // 教务系统给出的绩点
+	AverageScore string  // 教务系统给出的平均成绩
+	GeneratedAt  time.Time
+}
+
+// Term 是一个学期的成绩
+type Term struct {
+	Term   int
+	Scores []Row
+	Credit float64 // 本学期已获得的学分
+	GPA    float64 // 本学期的学分加权绩点
+}
+
+// Row 是一门课程的成绩
+type Row struct {
+	feign.Score
+	Credit  float64
+	Point   float64 // 绩点
+	Counted bool    // 是否计入绩点，合格制等无法换算的成绩不计入
+	Passed  bool    // 是否获得学分
+}
+
+// textScores 是等级制成绩对应的百分制成绩
+var textScores = map[string]float64{
+	"优秀": 95, "优": 95, "良好": 85, "良": 85, "中等": 75, "中": 75, "及格": 65, "不及格": 0, "不合格": 0,
+}
+
+// passScores 是只区分是否通过的成绩，获得学分但不计入绩点
+var passScores = map[string]bool{"合格": true, "通过": true}
+
+// GradePoint 按照常用的 4.0 标准将百分制成绩换算为绩点
+func GradePoint(score float64) float64 {
+	switch {
+	case score >= 90:
+		return 4.0
+	case score >= 85:
+		return 3.7
+	case score >= 82:
+		return 3.3
+	case score >= 78:
+		return 3.0
+	case score >= 75:
+		return 2.7
+	case score >= 72:
+		return 2.3
+	case score >= 68:
+		return 2.0
+	case score >= 64:
+		return 1.5
+	case score >= 60:
+		return 1.0
+	}
+	return 0
+}
+
+// newRow 解析一门课程的学分和成绩
+func newRow(score feign.Score) Row {
+	row := Row{Score: score}
+	row.Credit, _ = strconv.ParseFloat(strings.TrimSpace(score.Credit), 64)
+	text := strings.TrimSpace(score.Score)
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		if passScores[text] {
+			row.Passed = true
+			return row
+		}
+		var found bool
+		if value, found = textScores[text]; !found {
+			return row
+		}
+	}
+	row.Point = GradePoint(value)
+	row.Counted = true
+	row.Passed = value >= 60
+	return row
+}
+
+// New 根据成绩和学生信息生成成绩单，info 可以为 nil
+func New(board *feign.ScoreBoard, info *feign.StudentInfo, now time.Time) *Transcript {
+	t := &Transcript{
+		StudentID:    board.StudentId,
+		Name:         board.Name,
+		College:      board.College,
+		Major:        board.Major,
+		OfficialGPA:  board.Gpa,
+		AverageScore: board.AverageScore,
+		GeneratedAt:  now,
+	}
+	if info != nil {
+		t.Class = info.Class
+		t.EntranceDay = info.EntranceDay
+		if t.StudentID == "" {
+			t.StudentID = info.StudentId
+		}
+		if t.Name == "" {
+			t.Name = info.Name
+		}
+		if t.College == "" {
+			t.College = info.College
+		}
+		if t.Major == "" {
+			t.Major = info.Major
+		}
+	}
+	terms := map[int]*Term{}
+	for _, score := range board.Scores {
+		term, found := terms[score.Term]
+		if !found {
+			term = &Term{Term: score.Term}
+			terms[score.Term] = term
+		}
+		term.Scores = append(term.Scores, newRow(score))
+	}
+	var all []Row
+	for _, term := range terms {
+		term.Credit, term.GPA = summarize(term.Scores)
+		t.Terms = append(t.Terms, *term)
+		all = append(all, term.Scores...)
+	}
+	sort.Slice(t.Terms, func(i, j int) bool {
+		return t.Terms[i].Term < t.Terms[j].Term
+	})
+	t.Credit, t.GPA = summarize(all)
+	return t
+}
+
+// summarize 计算已获得的学分和学分加权绩点
+func summarize(rows []Row) (credit float64, gpa float64) {
+	var points, weights float64
+	for _, row := range rows {
+		if row.Passed {
+			credit += row.Credit
+		}
+		if row.Counted {
+			points += row.Point * row.Credit
+			weights += row.Credit
+		}
+	}
+	if weights > 0 {
+		gpa = points / weights
+	}
+	return credit, gpa
+}
+
+// TermName 返回学期的名称，例如 "第1学期"
+func TermName(term int) string {
+	return fmt.Sprintf("第%d学期", term)
+}
+
+// cell 是表格中的一个单元格
+type cell struct {
+	text     string
+	number   float64
+	isNumber bool
+}
+
+func text(s string) cell {
+	return cell{text: s}
+}
+
+func number(n float64) cell {
+	return cell{number: n, isNumber: true, text: formatNumber(n)}
+}
+
+// formatNumber 格式化学分和绩点，最多保留两位小数
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(float64(int64(n*100+0.5))/100, 'f', -1, 64)
+}
+
+// scoreHeader 是成绩表格的表头
+var scoreHeader = []string{"学期", "课程名称", "课程类型", "学分", "成绩", "绩点"}
+
+// header 返回成绩单开头的学生信息
+func (t *Transcript) header() [][2]string {
+	return [][2]string{
+		{"学号", t.StudentID}, {"姓名", t.Name}, {"学院", t.College}, {"专业", t.Major},
+		{"班级", t.Class}, {"入学日期", t.EntranceDay},
+	}
+}
+
+// summary 返回成绩单末尾的汇总
+func (t *Transcript) summary() [][2]string {
+	return [][2]string{
+		{"已获学分", formatNumber(t.Credit)}, {"学分加权绩点", formatNumber(t.GPA)},
+		{"教务系统绩点", t.OfficialGPA}, {"教务系统平均成绩", t.AverageScore},
+		{"生成时间", t.GeneratedAt.Format("2006-01-02 15:04")},
+	}
+}
+
+// table 将成绩单转换为表格，CSV 和 XLSX 使用相同的布局
+func (t *Transcript) table() [][]cell {
+	var rows [][]cell
+	for _, field := range t.header() {
+		rows = append(rows, []cell{text(field[0]), text(field[1])})
+	}
+	rows = append(rows, nil)
+	header := make([]cell, len(scoreHeader))
+	for i, name := range scoreHeader {
+		header[i] = text(name)
+	}
+	rows = append(rows, header)
+	for _, term := range t.Terms {
+		for _, row := range term.Scores {
+			point := text("")
+			if row.Counted {
+				point = number(row.Point)
+			}
+			rows = append(rows, []cell{text(TermName(term.Term)), text(row.Name), text(row.Type), number(row.Credit), text(row.Score.Score), point})
+		}
+		rows = append(rows, []cell{text(TermName(term.Term) + "小计"), text(""), text(""), number(term.Credit), text(""), number(term.GPA)})
+	}
+	rows = append(rows, nil)
+	for _, field := range t.summary() {
+		rows = append(rows, []cell{text(field[0]), text(field[1])})
+	}
+	return rows
+}
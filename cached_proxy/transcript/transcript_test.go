@@ -0,0 +1,202 @@
+package transcript
+
+import (
+	"archive/zip"
+	"bytes"
+	"cached_proxy/feign"
+	"encoding/csv"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBoard() *feign.ScoreBoard {
+	return &feign.ScoreBoard{
+		StudentId: "202305550001",
+		Name:      "张三",
+		Major:     "软件工程",
+		Gpa:       "3.50",
+		Scores: []feign.Score{
+			{Name: "大学物理", Score: "78", Credit: "4", Type: "必修", Term: 2},
+			{Name: "高等数学", Score: "92", Credit: "5", Type: "必修", Term: 1},
+			{Name: "体育", Score: "良好", Credit: "1", Type: "必修", Term: 1},
+			{Name: "劳动教育", Score: "合格", Credit: "0.5", Type: "必修", Term: 1},
+			{Name: "线性代数", Score: "55", Credit: "3", Type: "必修", Term: 2},
+		},
+	}
+}
+
+func TestGradePoint(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  float64
+	}{
+		{100, 4.0}, {90, 4.0}, {89.5, 3.7}, {83, 3.3}, {78, 3.0}, {75, 2.7},
+		{72, 2.3}, {68, 2.0}, {64, 1.5}, {60, 1.0}, {59.9, 0}, {0, 0},
+	}
+	for _, tt := range tests {
+		if got := GradePoint(tt.score); got != tt.want {
+			t.Errorf("GradePoint(%v) = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	info := &feign.StudentInfo{StudentId: "ignored", Class: "软件2301", EntranceDay: "2023-09-01", College: "计算机学院"}
+	got := New(testBoard(), info, time.Date(2025, 7, 1, 8, 0, 0, 0, feign.Zone))
+	if got.StudentID != "202305550001" || got.College != "计算机学院" || got.Class != "软件2301" {
+		t.Errorf("unexpected header %+v", got)
+	}
+	if len(got.Terms) != 2 || got.Terms[0].Term != 1 || got.Terms[1].Term != 2 {
+		t.Fatalf("terms should be sorted, got %+v", got.Terms)
+	}
+	first := got.Terms[0]
+	// 合格制成绩获得学分但不计入绩点
+	if first.Credit != 6.5 {
+		t.Errorf("expected 6.5 credits in term 1, got %v", first.Credit)
+	}
+	if want := (4.0*5 + 3.7*1) / 6; math.Abs(first.GPA-want) > 1e-9 {
+		t.Errorf("expected GPA %v in term 1, got %v", want, first.GPA)
+	}
+	second := got.Terms[1]
+	if second.Credit != 4 {
+		t.Errorf("failed course should not earn credits, got %v", second.Credit)
+	}
+	if got.Credit != 10.5 {
+		t.Errorf("expected 10.5 credits in total, got %v", got.Credit)
+	}
+	if want := (4.0*5 + 3.7*1 + 3.0*4) / 13; math.Abs(got.GPA-want) > 1e-9 {
+		t.Errorf("expected GPA %v in total, got %v", want, got.GPA)
+	}
+
+	empty := New(&feign.ScoreBoard{}, nil, time.Time{})
+	if len(empty.Terms) != 0 || empty.GPA != 0 {
+		t.Errorf("unexpected transcript without scores: %+v", empty)
+	}
+}
+
+func TestTranscript_WriteCSV(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := New(testBoard(), nil, time.Date(2025, 7, 1, 8, 0, 0, 0, feign.Zone)).WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), utf8BOM) {
+		t.Error("CSV should start with BOM")
+	}
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), utf8BOM)))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"第1学期", "高等数学", "必修", "5", "92", "4"},
+		{"第1学期", "劳动教育", "必修", "0.5", "合格", ""},
+		{"第1学期小计", "", "", "6.5", "", "3.95"},
+		{"第2学期", "线性代数", "必修", "3", "55", "0"},
+		{"已获学分", "10.5"},
+		{"生成时间", "2025-07-01 08:00"},
+	}
+	for _, row := range want {
+		found := false
+		for _, record := range records {
+			if strings.Join(record, ",") == strings.Join(row, ",") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("CSV should contain %v, got %v", row, records)
+		}
+	}
+}
+
+func TestTranscript_WriteXLSX(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := New(testBoard(), nil, time.Now()).WriteXLSX(&buf); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(f)
+		_ = f.Close()
+		files[file.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml"} {
+		if _, found := files[name]; !found {
+			t.Errorf("missing %s", name)
+		}
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, s := range []string{
+		`<c r="B9" t="inlineStr"><is><t>高等数学</t></is></c>`,
+		`<c r="D9"><v>5</v></c>`,
+		`<c r="A8" t="inlineStr" s="1"><is><t>学期</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, s) {
+			t.Errorf("sheet should contain %s, got %s", s, sheet)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 5: "F", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestTranscript_WritePDF(t *testing.T) {
+	board := testBoard()
+	// 足够多的课程使成绩单分页
+	for i := 0; i < 60; i++ {
+		board.Scores = append(board.Scores, feign.Score{Name: "一门名字非常非常非常非常非常非常非常非常长的选修课程", Score: "80", Credit: "2", Type: "选修", Term: 3})
+	}
+	transcript := New(board, nil, time.Now())
+
+	buf := bytes.Buffer{}
+	if err := transcript.WritePDF(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.String()
+	for _, s := range []string{"%PDF-1.7", "/BaseFont /STSong-Light", "/Encoding /UniGB-UCS2-H", "/Count 2 ", "xref\n", "%%EOF\n"} {
+		if !strings.Contains(pdf, s) {
+			t.Errorf("PDF should contain %q", s)
+		}
+	}
+
+	font, err := ParseFont("Test Font", testFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := transcript.WritePDF(&buf, font); err != nil {
+		t.Fatal(err)
+	}
+	pdf = buf.String()
+	for _, s := range []string{"+TestFont", "/Encoding /Identity-H", "/FontFile2", "/ToUnicode", "/W [1 [500] ]"} {
+		if !strings.Contains(pdf, s) {
+			t.Errorf("PDF should contain %q", s)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("高等数学", 4); got != "高等数学" {
+		t.Errorf("unexpected %s", got)
+	}
+	if got := truncate("高等数学实验", 4); got != "高等数…" {
+		t.Errorf("unexpected %s", got)
+	}
+}
@@ -0,0 +1,112 @@
+package transcript
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xlsxFiles 是 XLSX 中除工作表以外的固定文件，参见 ECMA-376 Part 1
+var xlsxFiles = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="成绩单" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	// 样式 0 为默认样式，样式 1 为加粗，用于表头和小计
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="等线"/></font><font><b/><sz val="11"/><name val="等线"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`},
+}
+
+// WriteXLSX 将成绩单导出为只有一个工作表的 XLSX，文本使用内联字符串
+func (t *Transcript) WriteXLSX(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	for _, file := range xlsxFiles {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, file.content); err != nil {
+			return err
+		}
+	}
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(f, t.sheet()); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// sheet 生成工作表的内容
+func (t *Transcript) sheet() string {
+	b := strings.Builder{}
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<cols><col min="1" max="1" width="16" customWidth="1"/><col min="2" max="2" width="36" customWidth="1"/>` +
+		`<col min="3" max="3" width="14" customWidth="1"/></cols>`)
+	b.WriteString("<sheetData>")
+	for i, row := range t.table() {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		bold := len(row) > 0 && (row[0].text == scoreHeader[0] || strings.HasSuffix(row[0].text, "小计"))
+		for j, c := range row {
+			ref := columnName(j) + fmt.Sprint(i+1)
+			style := ""
+			if bold {
+				style = ` s="1"`
+			}
+			if c.isNumber {
+				fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, style, c.text)
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"%s><is><t>%s</t></is></c>`, ref, style, xmlText(c.text))
+		}
+		b.WriteString("</row>")
+	}
+	b.WriteString("</sheetData></worksheet>")
+	return b.String()
+}
+
+// columnName 返回从 0 开始的列号对应的列名，例如 0 为 A，26 为 AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func xmlText(s string) string {
+	b := strings.Builder{}
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}